@@ -0,0 +1,110 @@
+// Command migrate manages the database schema:
+//
+//	migrate up                apply every pending migration
+//	migrate down [-steps N]   revert the last N migrations (default 1)
+//	migrate status            list migrations and when they were applied
+//	migrate create <name>     write a new empty migration
+//
+// The database is configured with the same DB_* variables as the server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gotempl/database"
+	"gotempl/database/migrate"
+	"os"
+	"time"
+	// DB_LEGACY_TIME_ZONE, without depending on the system's time zone database
+	_ "time/tzdata"
+
+	"github.com/joho/godotenv"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	dir := flags.String("dir", "database/migrations", "directory of the migrations package, for create")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: migrate [flags] up|down|status|create <name>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *steps < 1 {
+		fmt.Fprintln(flags.Output(), "-steps must be at least 1")
+		os.Exit(2)
+	}
+
+	if err := run(flags.Arg(0), flags.Args()[1:], *steps, *dir); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(command string, args []string, steps int, dir string) error {
+	if command == "create" {
+		if len(args) != 1 {
+			return fmt.Errorf("usage: migrate create <name>")
+		}
+		path, err := migrate.Create(dir, args[0], time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Println("Created", path)
+		return nil
+	}
+
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	cfg, err := database.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	migrator, err := database.NewMigrator(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		fmt.Printf("Applied %d migration(s)\n", applied)
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Missing {
+				state += " (missing from this binary)"
+			}
+			fmt.Printf("%s  %-30s  %s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, expected up, down, status or create", command)
+	}
+}
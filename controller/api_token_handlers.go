@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"gotempl/middleware"
+	"gotempl/model"
+	"gotempl/service"
+	"gotempl/views/crud"
+	"gotempl/views/layout"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type APITokenHandler struct {
+	Service *service.APITokenService
+	Users   *service.UserService
+}
+
+func NewAPITokenHandler(service *service.APITokenService, users *service.UserService) *APITokenHandler {
+	return &APITokenHandler{Service: service, Users: users}
+}
+
+// scoped returns the token service limited to the tokens of the caller's organization
+func (h *APITokenHandler) scoped(c *gin.Context) *service.APITokenService {
+	tenant := middleware.CurrentTenant(c)
+	if tenant == middleware.AllTenants {
+		return h.Service
+	}
+	return h.Service.ForOrg(tenant)
+}
+
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	OwnerUid  string     `json:"owner_uid"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateAPITokenResponse struct {
+	Token  model.APIToken `json:"token"`
+	Secret string         `json:"secret"`
+}
+
+// CreateToken godoc
+// @Summary      Mint a personal access token
+// @Description  Create an API token for machine clients. The secret is only returned once. The owner defaults to the caller, it can be another member of the caller's organization whose role isn't above the caller's. The token is limited to the caller's organization. Callers authenticated with a token can only grant its scopes, and the new token expires no later than it (by default when it does).
+// @Tags         Token
+// @Accept       json
+// @Produce      json
+// @Param        token  body      CreateAPITokenRequest  true  "Token information"
+// @Success      201   {object}  CreateAPITokenResponse
+// @Failure      400   {object}  object
+// @Failure      403   {object}  object
+// @Failure      500   {object}  object
+// @Security     BearerAuth
+// @Router       /token [post]
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	var req CreateAPITokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.OwnerUid == "" {
+		req.OwnerUid = c.GetString(middleware.SubjectKey)
+	}
+	if req.OwnerUid != c.GetString(middleware.SubjectKey) {
+		// Tokens act as their owner, who must be someone the caller manages
+		owner, err := tenantUsers(c, h.Users).Get(c.Request.Context(), req.OwnerUid)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Owner is not a member of your organization"})
+			} else {
+				log.Error("Error:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve owner"})
+			}
+			return
+		}
+		if middleware.RoleRank(owner.Role) > middleware.RoleRank(middleware.CurrentRole(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: the owner's role is above yours"})
+			return
+		}
+	}
+
+	if err := limitToCallerToken(middleware.CurrentPrincipal(c), &req); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := model.APIToken{
+		Name:      req.Name,
+		OwnerUid:  req.OwnerUid,
+		ExpiresAt: req.ExpiresAt,
+	}
+	// Tokens reach the organization the caller is working in
+	secret, err := h.scoped(c).CreateToken(c.Request.Context(), &token, req.Scopes)
+	if err != nil {
+		log.Error("Error:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPITokenResponse{Token: token, Secret: secret})
+}
+
+// limitToCallerToken keeps tokens minted with a token within its reach: they
+// have none of the scopes it lacks and expire no later than it, by default
+// when it does. Sessions are limited by role alone.
+func limitToCallerToken(principal *middleware.Principal, req *CreateAPITokenRequest) error {
+	if principal == nil || principal.TokenID == 0 {
+		return nil
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return fmt.Errorf("%w: your token doesn't have the %s scope", errForbidden, scope)
+		}
+	}
+	if principal.ExpiresAt.IsZero() {
+		return nil
+	}
+	if req.ExpiresAt == nil {
+		req.ExpiresAt = &principal.ExpiresAt
+	} else if req.ExpiresAt.After(principal.ExpiresAt) {
+		return fmt.Errorf("%w: the token can't expire after yours", errForbidden)
+	}
+	return nil
+}
+
+// GetAllTokens godoc
+// @Summary      Get all API tokens
+// @Description  Retrieve the API tokens of the caller's organization, without their secrets
+// @Tags         Token
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   model.APIToken
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /token [get]
+func (h *APITokenHandler) GetAllTokens(c *gin.Context) {
+	tokens, err := h.scoped(c).GetAllTokens(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeToken godoc
+// @Summary      Revoke an API token
+// @Description  Revoke an API token of the caller's organization, it stops working immediately
+// @Tags         Token
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Token ID"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /token/{id} [delete]
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := h.scoped(c).RevokeToken(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found or already revoked"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TokenCRUDHandler godoc
+// @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
+// @Description  Fetches the API tokens and members of the caller's organization and renders an HTML page to mint and revoke them (non-REST endpoint)
+// @Tags         Token
+// @Produce      html
+// @Success      200  {string}  string  "HTML page content"
+// @Router       /admin/token [get]
+// @Notes
+func (h *APITokenHandler) TokenCRUDHandler(c *gin.Context) {
+	tokens, err := h.scoped(c).GetAllTokens(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	users, err := tenantUsers(c, h.Users).GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	layout.Render(c, 200, crud.TokenForm(tokens, users, c.GetString(middleware.SubjectKey)))
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotempl/middleware"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCreateTokenOwner(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Organization{}, &model.Membership{}, &model.APIToken{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	db.Create(&model.User{Uid: "a1", Username: "alice", Role: middleware.RoleAdmin})
+	db.Create(&model.User{Uid: "a2", Username: "amy", Role: middleware.RoleUser})
+	db.Create(&model.User{Uid: "s1", Username: "sam", Role: middleware.RoleSuperAdmin})
+	db.Create(&model.User{Uid: "b1", Username: "bob", Role: middleware.RoleUser})
+	db.Create(&model.Organization{ID: "org_a", Name: "Acme"})
+	db.Create(&model.Organization{ID: "org_b", Name: "Beta"})
+	for _, membership := range []model.Membership{{UserUid: "a1", OrgID: "org_a"}, {UserUid: "a2", OrgID: "org_a"}, {UserUid: "s1", OrgID: "org_a"}, {UserUid: "b1", OrgID: "org_b"}} {
+		db.Create(&membership)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	handler := NewAPITokenHandler(service.NewAPITokenService(repository.NewAPITokenRepository(db), userRepo), service.NewUserService(userRepo))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/token", withOrgPrincipal("a1", "org_a", middleware.RoleAdmin), middleware.ResolveTenant(), handler.CreateToken)
+
+	create := func(owner string) *httptest.ResponseRecorder {
+		body := `{"name":"ci","scopes":["events:read"],"owner_uid":"` + owner + `"}`
+		req, _ := http.NewRequest("POST", "/token", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, create("").Code)
+	assert.Equal(t, http.StatusCreated, create("a2").Code)
+	// The owner can't outrank the caller or belong to another organization
+	assert.Equal(t, http.StatusForbidden, create("s1").Code)
+	assert.Equal(t, http.StatusBadRequest, create("b1").Code)
+
+	var tokens []model.APIToken
+	assert.NoError(t, db.Order("id").Find(&tokens).Error)
+	if assert.Len(t, tokens, 2) {
+		assert.Equal(t, "a1", tokens[0].OwnerUid)
+		assert.Equal(t, "a2", tokens[1].OwnerUid)
+		assert.Equal(t, "org_a", tokens[1].OrgID)
+	}
+}
+
+func TestCreateTokenWithToken(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.APIToken{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	db.Create(&model.User{Uid: "a1", Username: "alice", Role: middleware.RoleAdmin})
+
+	userRepo := repository.NewUserRepository(db)
+	handler := NewAPITokenHandler(service.NewAPITokenService(repository.NewAPITokenRepository(db), userRepo), service.NewUserService(userRepo))
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/token", func(c *gin.Context) {
+		c.Set(middleware.SubjectKey, "a1")
+		c.Set(middleware.RoleKey, middleware.RoleAdmin)
+		c.Set(middleware.PrincipalKey, &middleware.Principal{
+			Subject:   "a1",
+			Role:      middleware.RoleAdmin,
+			Scopes:    []string{model.ScopeUsersAdmin, model.ScopeEventsRead},
+			TokenID:   1,
+			ExpiresAt: expiresAt,
+		})
+		c.Next()
+	}, middleware.ResolveTenant(), handler.CreateToken)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/token", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The token can't grant more than it has, nor outlive it
+	w := create(`{"name":"ci","scopes":["events:read","events:write"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "events:write")
+	w = create(`{"name":"ci","scopes":["events:read"],"expires_at":"` + expiresAt.Add(time.Minute).Format(time.RFC3339) + `"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = create(`{"name":"ci","scopes":["events:read"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var response CreateAPITokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.NotNil(t, response.Token.ExpiresAt) {
+		assert.True(t, response.Token.ExpiresAt.Equal(expiresAt))
+	}
+}
+
+func TestTokensAreScopedToOrganization(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Organization{}, &model.Membership{}, &model.APIToken{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	db.Create(&model.User{Uid: "a1", Username: "alice", Role: middleware.RoleAdmin})
+	db.Create(&model.User{Uid: "b1", Username: "bob", Role: middleware.RoleAdmin})
+	db.Create(&model.APIToken{Name: "a", OwnerUid: "a1", OrgID: "org_a", Prefix: "gtp_a", TokenHash: "a", Scopes: "events:read"})
+	db.Create(&model.APIToken{Name: "b", OwnerUid: "b1", OrgID: "org_b", Prefix: "gtp_b", TokenHash: "b", Scopes: "events:read"})
+
+	userRepo := repository.NewUserRepository(db)
+	handler := NewAPITokenHandler(service.NewAPITokenService(repository.NewAPITokenRepository(db), userRepo), service.NewUserService(userRepo))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/token", withOrgPrincipal("a1", "org_a", middleware.RoleAdmin), middleware.ResolveTenant())
+	group.GET("/", handler.GetAllTokens)
+	group.DELETE("/:id", handler.RevokeToken)
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "/token/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens []model.APIToken
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	if assert.Len(t, tokens, 1) {
+		assert.Equal(t, "a", tokens[0].Name)
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/token/2").Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/token/1").Code)
+
+	var other model.APIToken
+	assert.NoError(t, db.First(&other, "name = ?", "b").Error)
+	assert.Nil(t, other.RevokedAt)
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"errors"
+	"gotempl/middleware"
+	"gotempl/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type AttendanceHandler struct {
+	Service *service.AttendanceService
+}
+
+func NewAttendanceHandler(attendance *service.AttendanceService) *AttendanceHandler {
+	return &AttendanceHandler{Service: attendance}
+}
+
+// Register adds the routes of registrations to the event group: registering
+// and cancelling change the event, they are guarded by routes.Write
+func (h *AttendanceHandler) Register(group gin.IRouter, routes Routes) {
+	group.POST("/:id/rsvp", with(routes.Write, h.RSVP)...)
+	group.DELETE("/:id/rsvp", with(routes.Write, h.CancelRSVP)...)
+	group.GET("/:id/attendees", with(routes.Read, h.GetAttendees)...)
+}
+
+// scoped returns the attendance service limited to the caller's organization
+func (h *AttendanceHandler) scoped(c *gin.Context) *service.AttendanceService {
+	tenant := middleware.CurrentTenant(c)
+	if tenant == middleware.AllTenants {
+		return h.Service
+	}
+	return h.Service.ForOrg(tenant)
+}
+
+// eventID parses the id path parameter, it answers 400 when it is malformed
+func eventID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// RSVP godoc
+// @Summary      Register to an event
+// @Description  Register the caller to a published event. They get a seat while attendees_count is below max_attendees (0 doesn't limit it), otherwise they are put on the waitlist.
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Event ID"
+// @Success      201  {object}  model.Attendance
+// @Failure      400  {object}  object
+// @Failure      401  {object}  object
+// @Failure      404  {object}  object
+// @Failure      409  {object}  object  "The caller is already registered, or the event isn't published"
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/rsvp [post]
+func (h *AttendanceHandler) RSVP(c *gin.Context) {
+	id, ok := eventID(c)
+	if !ok {
+		return
+	}
+	principal := middleware.CurrentPrincipal(c)
+	if principal == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errNoPrincipal.Error()})
+		return
+	}
+
+	attendance, err := h.scoped(c).Register(c.Request.Context(), id, principal.Subject)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else if errors.Is(err, service.ErrAlreadyRegistered) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Already registered to the event"})
+		} else if errors.Is(err, service.ErrRegistrationClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The event isn't open for registrations"})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register to the event"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, attendance)
+}
+
+// CancelRSVP godoc
+// @Summary      Cancel a registration
+// @Description  Cancel the caller's registration to an event. A seat it frees goes to the first user of the waitlist.
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Event ID"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  object
+// @Failure      401  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/rsvp [delete]
+func (h *AttendanceHandler) CancelRSVP(c *gin.Context) {
+	id, ok := eventID(c)
+	if !ok {
+		return
+	}
+	principal := middleware.CurrentPrincipal(c)
+	if principal == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errNoPrincipal.Error()})
+		return
+	}
+
+	promoted, err := h.scoped(c).Cancel(c.Request.Context(), id, principal.Subject)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel the registration"})
+		}
+		return
+	}
+	if promoted != nil {
+		log.Info("Promoted ", promoted.UserUid, " from the waitlist of event ", id)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetAttendees godoc
+// @Summary      List the attendees of an event
+// @Description  Retrieve the registrations to an event, those with a seat first, then the waitlist in order
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Event ID"
+// @Success      200  {array}   model.Attendance
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/attendees [get]
+func (h *AttendanceHandler) GetAttendees(c *gin.Context) {
+	id, ok := eventID(c)
+	if !ok {
+		return
+	}
+
+	attendances, err := h.scoped(c).ListAttendees(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attendees"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, attendances)
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotempl/middleware"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRSVP(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Event{}, &model.Attendance{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	assert.NoError(t, db.Create(&model.Event{Title: "Workshop", CreatedBy: "author-uid", MaxAttendees: 2, Status: model.EventPublished}).Error)
+
+	events := repository.NewEventRepository(db)
+	eventHandler := NewEventHandler(service.NewEventService(events, repository.NewSearchRepository(db)))
+	handler := NewAttendanceHandler(service.NewAttendanceService(events, repository.NewAttendanceRepository(db)))
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	as := func(subject string) *gin.RouterGroup {
+		return router.Group("/"+subject, withPrincipal(subject))
+	}
+	for _, subject := range []string{"alice", "bob", "carol", "author-uid"} {
+		group := as(subject)
+		group.POST("/event/:id/rsvp", handler.RSVP)
+		group.DELETE("/event/:id/rsvp", handler.CancelRSVP)
+		group.GET("/event/:id/attendees", handler.GetAttendees)
+		group.PUT("/event/:id", eventHandler.UpdateEvent)
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	event := func() model.Event {
+		var event model.Event
+		assert.NoError(t, db.First(&event, 1).Error)
+		return event
+	}
+	attendees := func() []model.Attendance {
+		w := do("GET", "/alice/event/1/attendees", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var attendances []model.Attendance
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &attendances))
+		return attendances
+	}
+
+	for _, subject := range []string{"alice", "bob", "carol"} {
+		w := do("POST", "/"+subject+"/event/1/rsvp", "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	assert.Equal(t, http.StatusConflict, do("POST", "/alice/event/1/rsvp", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/alice/event/2/rsvp", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/alice/event/x/rsvp", "").Code)
+	assert.Equal(t, uint(2), event().AttendeesCount)
+	assert.Equal(t, uint64(3), event().Version)
+
+	list := attendees()
+	if assert.Len(t, list, 3) {
+		assert.Equal(t, "alice", list[0].UserUid)
+		assert.Equal(t, model.AttendanceGoing, list[0].Status)
+		assert.Equal(t, "carol", list[2].UserUid)
+		assert.Equal(t, model.AttendanceWaitlisted, list[2].Status)
+	}
+
+	// The count can't be written by clients
+	w := do("PUT", "/author-uid/event/1", `{"title":"Workshop","max_attendees":2,"attendees_count":0}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(2), event().AttendeesCount)
+
+	// Cancelling a seat promotes the waitlist
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/alice/event/1/rsvp", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/alice/event/1/rsvp", "").Code)
+	list = attendees()
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "bob", list[0].UserUid)
+		assert.Equal(t, "carol", list[1].UserUid)
+		assert.Equal(t, model.AttendanceGoing, list[1].Status)
+	}
+	assert.Equal(t, uint(2), event().AttendeesCount)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/carol/event/1/rsvp", "").Code)
+	assert.Equal(t, uint(1), event().AttendeesCount)
+}
+
+func TestRSVPRequiresWriteScope(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Event{}, &model.Attendance{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	assert.NoError(t, db.Create(&model.Event{Title: "Workshop", CreatedBy: "author-uid", Status: model.EventPublished}).Error)
+
+	handler := NewAttendanceHandler(service.NewAttendanceService(repository.NewEventRepository(db), repository.NewAttendanceRepository(db)))
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	routes := Routes{
+		Read:  []gin.HandlerFunc{middleware.RequireScope(model.ScopeEventsRead)},
+		Write: []gin.HandlerFunc{middleware.RequireScope(model.ScopeEventsWrite)},
+	}
+	for name, scopes := range map[string][]string{
+		"reader": {model.ScopeEventsRead},
+		"writer": {model.ScopeEventsRead, model.ScopeEventsWrite},
+	} {
+		group := router.Group("/"+name, func(c *gin.Context) {
+			c.Set(middleware.SubjectKey, name)
+			c.Set(middleware.PrincipalKey, &middleware.Principal{Subject: name, TokenID: 1, Scopes: scopes})
+			c.Next()
+		})
+		handler.Register(group.Group("/event"), routes)
+	}
+	do := func(method, path string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, do("POST", "/reader/event/1/rsvp"))
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/reader/event/1/rsvp"))
+	assert.Equal(t, http.StatusOK, do("GET", "/reader/event/1/attendees"))
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/writer/event/1/rsvp"))
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/writer/event/1/rsvp"))
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"gotempl/middleware"
+	"gotempl/repository"
+	"gotempl/service"
+	"gotempl/views/crud"
+	"gotempl/views/layout"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	Service *service.AuditService
+}
+
+func NewAuditHandler(service *service.AuditService) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+// scoped returns the audit log limited to the records of the caller's organization
+func (h *AuditHandler) scoped(c *gin.Context) *service.AuditService {
+	tenant := middleware.CurrentTenant(c)
+	if tenant == middleware.AllTenants {
+		return h.Service
+	}
+	return h.Service.ForOrg(tenant)
+}
+
+// GetAuditLog godoc
+// @Summary      List audit entries
+// @Description  Retrieve a page of the audit log of events and users, by default the latest change first. Each entry has the actor, the request ID, the client IP and the changed fields with their value before and after.
+// @Tags         Audit
+// @Accept       json
+// @Produce      json
+// @Param        limit       query     int     false  "Page size, at most 100"  default(20)
+// @Param        cursor      query     string  false  "next_cursor of the previous page"
+// @Param        sort        query     string  false  "id or created_at, prefixed with - for descending order"  default(-id)
+// @Param        entity      query     string  false  "Only changes of this kind of record"  Enums(event, user)
+// @Param        id          query     string  false  "Only changes of the record with this ID"
+// @Param        action      query     string  false  "Only this kind of change"  Enums(create, update, delete, restore, purge)
+// @Param        actor       query     string  false  "Only changes made by this user ID"
+// @Param        request_id  query     string  false  "Only changes made by this request"
+// @Param        since       query     string  false  "Only changes made at or after this RFC 3339 time"
+// @Param        until       query     string  false  "Only changes made before this RFC 3339 time"
+// @Success      200  {object}  repository.Page[model.AuditEntry]
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /audit [get]
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	var filter repository.AuditFilter
+	opts, err := bindListQuery(c, &filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.scoped(c).ListEntries(c.Request.Context(), filter, opts)
+	if err != nil {
+		listError(c, err, "Failed to fetch the audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// AuditLogHandler godoc
+// @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
+// @Description  Fetches a page of the audit log and renders an HTML page to browse and filter it (non-REST endpoint)
+// @Tags         Audit
+// @Produce      html
+// @Success      200  {string}  string  "HTML page content"
+// @Router       /admin/audit [get]
+func (h *AuditHandler) AuditLogHandler(c *gin.Context) {
+	var filter repository.AuditFilter
+	opts, err := bindListQuery(c, &filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.scoped(c).ListEntries(c.Request.Context(), filter, opts)
+	if err != nil {
+		listError(c, err, "Failed to fetch the audit log")
+		return
+	}
+
+	layout.Render(c, 200, crud.AuditLog(page.Items, pagination(c, page), c.Request.URL.Query()))
+}
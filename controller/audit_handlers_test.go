@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotempl/middleware"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Organization{}, &model.Membership{}, &model.Event{}, &model.AuditEntry{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	audit := service.NewAuditService(repository.NewAuditRepository(db))
+	events := service.NewEventService(repository.NewEventRepository(db), repository.NewSearchRepository(db))
+	events.Audit = audit
+	users := service.NewUserService(repository.NewUserRepository(db))
+	users.Audit = audit
+	eventHandler := NewEventHandler(events)
+	userHandler := NewUserHandler(users)
+	auditHandler := NewAuditHandler(audit)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	admin := router.Group("/", withOrgPrincipal("admin-uid", "org_a", middleware.RoleAdmin), middleware.ResolveTenant())
+	admin.POST("/event", eventHandler.CreateEvent)
+	admin.PUT("/event/:id", eventHandler.UpdateEvent)
+	admin.DELETE("/event/:id", eventHandler.DeleteEvent)
+	admin.PUT("/user/:id", userHandler.UpdateUser)
+	admin.GET("/audit", auditHandler.GetAuditLog)
+	other := router.Group("/other", withOrgPrincipal("other-uid", "org_b", middleware.RoleAdmin), middleware.ResolveTenant())
+	other.PUT("/user/:id", userHandler.UpdateUser)
+	other.GET("/audit", auditHandler.GetAuditLog)
+	router.GET("/root/audit", withOrgPrincipal("root", "", middleware.RoleSuperAdmin), middleware.ResolveTenant(), auditHandler.GetAuditLog)
+
+	do := func(method, url, body, requestID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.RequestIDHeader, requestID)
+		req.RemoteAddr = "192.0.2.10:4321"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(url string) []model.AuditEntry {
+		w := do("GET", url, "", "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page repository.Page[model.AuditEntry]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page.Items
+	}
+
+	w := do("POST", "/event", `{"title":"Launch","status":"draft"}`, "req-create")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = do("PUT", "/event/1", `{"title":"Launch","location":"Berlin","is_public":true}`, "req-update")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	// Saving without changes is not recorded
+	w = do("PUT", "/event/1", `{"title":"Launch","location":"Berlin","status":"draft","is_public":true}`, "req-noop")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do("DELETE", "/event/1", "", "req-delete")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	entries := list("/audit?entity=event&id=1")
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, []string{"delete", "update", "create"}, []string{entries[0].Action, entries[1].Action, entries[2].Action})
+
+		update := entries[1]
+		assert.Equal(t, "admin-uid", update.Actor)
+		assert.Equal(t, "req-update", update.RequestID)
+		assert.Equal(t, "192.0.2.10", update.IP)
+		assert.Equal(t, "org_a", update.OrgID)
+		assert.Equal(t, model.AuditChanges{"location": {Before: "", After: "Berlin"}}, update.Changes)
+
+		assert.Nil(t, entries[2].Changes["title"].Before)
+		assert.Equal(t, "Launch", entries[2].Changes["title"].After)
+		assert.Equal(t, "admin-uid", entries[0].Changes["deleted_by"].After)
+		assert.Contains(t, entries[0].Changes, "deleted_at")
+	}
+
+	assert.Len(t, list("/audit?action=update"), 1)
+	assert.Len(t, list("/audit?request_id=req-delete"), 1)
+	assert.Empty(t, list("/audit?actor=someone-else"))
+
+	// User changes are recorded under the organizations of the user
+	db.Create(&model.User{Uid: "u1", Username: "jane", Role: "user"})
+	db.Create(&model.User{Uid: "u2", Username: "john", Role: "user"})
+	db.Create(&model.Organization{ID: "org_a", Name: "Acme"})
+	db.Create(&model.Organization{ID: "org_b", Name: "Beta"})
+	db.Create(&model.Membership{UserUid: "u1", OrgID: "org_a"})
+	db.Create(&model.Membership{UserUid: "u2", OrgID: "org_b"})
+	w = do("PUT", "/user/u1", `{"username":"jane","role":"admin"}`, "req-role")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = do("PUT", "/other/user/u2", `{"username":"johnny","role":"user"}`, "req-rename")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	entries = list("/audit?entity=user")
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "u1", entries[0].EntityID)
+		assert.Equal(t, "org_a", entries[0].OrgID)
+		assert.Equal(t, model.AuditChanges{"role": {Before: "user", After: "admin"}}, entries[0].Changes)
+	}
+	entries = list("/other/audit?entity=user")
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "u2", entries[0].EntityID)
+		assert.Equal(t, "org_b", entries[0].OrgID)
+	}
+
+	// Other organizations don't see the changes
+	assert.Empty(t, list("/other/audit?entity=event"))
+	assert.Len(t, list("/root/audit"), 5)
+
+	w = do("GET", "/audit?since=yesterday", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"gotempl/middleware"
+	"gotempl/views"
+	"gotempl/views/layout"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const sessionCookie = "__session"
+
+type DevLoginHandler struct {
+	Auth *middleware.DevAuthenticator
+}
+
+func NewDevLoginHandler(auth *middleware.DevAuthenticator) *DevLoginHandler {
+	return &DevLoginHandler{Auth: auth}
+}
+
+// Handler for the development login page
+func (h *DevLoginHandler) LoginForm(c *gin.Context) {
+	layout.Render(c, http.StatusOK, views.DevLogin(h.Auth.Username, ""))
+}
+
+// Login checks the submitted credentials and stores the session token in the
+// same cookie Clerk uses, so the rest of the app doesn't know the difference
+func (h *DevLoginHandler) Login(c *gin.Context) {
+	username := c.PostForm("username")
+
+	token, err := h.Auth.SignIn(username, c.PostForm("password"))
+	if err != nil {
+		layout.Render(c, http.StatusUnauthorized, views.DevLogin(username, err.Error()))
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, token, 0, "/", "", false, true)
+	c.Redirect(http.StatusSeeOther, "/admin/")
+}
+
+// Logout clears the session cookie
+func (h *DevLoginHandler) Logout(c *gin.Context) {
+	c.SetCookie(sessionCookie, "", -1, "/", "", false, true)
+	c.Redirect(http.StatusSeeOther, "/sign-in")
+}
@@ -0,0 +1,348 @@
+package controller
+
+import (
+	"errors"
+	"gotempl/middleware"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// errNoPrincipal is returned by Prepare hooks that need to know the caller
+var errNoPrincipal = errors.New("Access denied: authentication is needed")
+
+// errForbidden is wrapped by Prepare hooks that don't let the caller make the change
+var errForbidden = errors.New("Access denied")
+
+// Routes are the middlewares of the routes registered by CRUDHandler.Register
+type Routes struct {
+	// Read guards the list and the lookups
+	Read []gin.HandlerFunc
+	// Write guards creations and updates
+	Write []gin.HandlerFunc
+	// Delete guards deletions
+	Delete []gin.HandlerFunc
+	// List replaces CRUDHandler.List as the list handler, optional
+	List gin.HandlerFunc
+}
+
+// CRUDHandler serves the REST API of the records of a CRUDService, F is the
+// filter bound from the query string of lists
+type CRUDHandler[T any, ID comparable, F repository.Filter] struct {
+	// Name is the name of the records in messages, e.g. "Event"
+	Name string
+	// Service returns the service acting for the request
+	Service func(c *gin.Context) *service.CRUDService[T, ID]
+	// ParseID parses the id path parameter
+	ParseID func(param string) (ID, error)
+	// Prepare sets the fields of a record clients can't choose before it is
+	// created (existing is nil) or replaces existing, optional. It returns
+	// errNoPrincipal when the request needs an authenticated caller and wraps
+	// errForbidden when the caller isn't allowed to make the change.
+	Prepare func(c *gin.Context, record, existing *T) error
+}
+
+// with returns the middlewares followed by the handler
+func with(middlewares []gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	return append(append([]gin.HandlerFunc{}, middlewares...), handler)
+}
+
+// Register adds the list, create, get, replace, patch and delete routes to group
+func (h *CRUDHandler[T, ID, F]) Register(group gin.IRouter, routes Routes) {
+	list := h.List
+	if routes.List != nil {
+		list = routes.List
+	}
+	group.POST("/", with(routes.Write, h.Create)...)
+	group.GET("/", with(routes.Read, list)...)
+	group.GET("/:id", with(routes.Read, h.Get)...)
+	group.PUT("/:id", with(routes.Write, h.Update)...)
+	group.PATCH("/:id", with(routes.Write, h.Patch)...)
+	group.DELETE("/:id", with(routes.Delete, h.Delete)...)
+}
+
+func (h *CRUDHandler[T, ID, F]) lower() string {
+	return strings.ToLower(h.Name)
+}
+
+// id parses the id path parameter, it answers 400 when it is malformed
+func (h *CRUDHandler[T, ID, F]) id(c *gin.Context) (ID, bool) {
+	id, err := h.ParseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return id, false
+	}
+	return id, true
+}
+
+// load returns the live record, it answers 404 when there is none
+func (h *CRUDHandler[T, ID, F]) load(c *gin.Context, id ID) (*T, bool) {
+	record, err := h.Service(c).Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": h.Name + " not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve " + h.lower()})
+		}
+		return nil, false
+	}
+	return record, true
+}
+
+func (h *CRUDHandler[T, ID, F]) prepare(c *gin.Context, record, existing *T) bool {
+	if h.Prepare == nil {
+		return true
+	}
+	if err := h.Prepare(c, record, existing); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errNoPrincipal) {
+			status = http.StatusUnauthorized
+		} else if errors.Is(err, errForbidden) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// conflict answers 412 with the current version of a record that was changed
+// while the request was writing it
+func (h *CRUDHandler[T, ID, F]) conflict(c *gin.Context, id ID) {
+	current, err := h.Service(c).Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": h.Name + " not found"})
+		return
+	}
+	preconditionFailed(c, current, h.Service(c).Repo().Version(current))
+}
+
+func (h *CRUDHandler[T, ID, F]) List(c *gin.Context) {
+	var filter F
+	opts, err := bindListQuery(c, &filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.Service(c).List(c.Request.Context(), filter, opts)
+	if err != nil {
+		listError(c, err, "Failed to fetch "+h.lower()+"s")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *CRUDHandler[T, ID, F]) Get(c *gin.Context) {
+	id, ok := h.id(c)
+	if !ok {
+		return
+	}
+	record, ok := h.load(c, id)
+	if !ok {
+		return
+	}
+
+	version := h.Service(c).Repo().Version(record)
+	if notModified(c, version) {
+		return
+	}
+	setETag(c, version)
+	c.JSON(http.StatusOK, record)
+}
+
+func (h *CRUDHandler[T, ID, F]) Create(c *gin.Context) {
+	var record T
+	if err := c.ShouldBindJSON(&record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.prepare(c, &record, nil) {
+		return
+	}
+
+	svc := h.Service(c)
+	if err := svc.Create(c.Request.Context(), &record); err != nil {
+		log.Error("Error:", err)
+		if errors.Is(err, service.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create " + h.lower()})
+		return
+	}
+
+	setETag(c, svc.Repo().Version(&record))
+	c.JSON(http.StatusCreated, record)
+}
+
+// Update replaces the record with the body
+func (h *CRUDHandler[T, ID, F]) Update(c *gin.Context) {
+	h.replace(c, func(existing, record *T) error {
+		return c.ShouldBindJSON(record)
+	}, func(c *gin.Context, err error) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	})
+}
+
+// Patch applies the merge patch or JSON Patch of the body to the record
+func (h *CRUDHandler[T, ID, F]) Patch(c *gin.Context) {
+	h.replace(c, func(existing, record *T) error {
+		return patchRecord(c, existing, record)
+	}, patchError)
+}
+
+// replace saves the record decoded from the request over the existing one,
+// decodeError reports the requests decode can't read
+func (h *CRUDHandler[T, ID, F]) replace(c *gin.Context, decode func(existing, record *T) error, decodeError func(c *gin.Context, err error)) {
+	id, ok := h.id(c)
+	if !ok {
+		return
+	}
+	existing, ok := h.load(c, id)
+	if !ok {
+		return
+	}
+
+	svc := h.Service(c)
+	if version := svc.Repo().Version(existing); !ifMatch(c, version) {
+		preconditionFailed(c, existing, version)
+		return
+	}
+
+	var record T
+	if err := decode(existing, &record); err != nil {
+		decodeError(c, err)
+		return
+	}
+	svc.Repo().Keep(&record, existing)
+	if !h.prepare(c, &record, existing) {
+		return
+	}
+
+	if err := svc.Update(c.Request.Context(), &record); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, id)
+			return
+		}
+		if errors.Is(err, service.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update " + h.lower()})
+		return
+	}
+
+	setETag(c, svc.Repo().Version(&record))
+	c.JSON(http.StatusOK, record)
+}
+
+// Delete moves the record to the trash
+func (h *CRUDHandler[T, ID, F]) Delete(c *gin.Context) {
+	id, ok := h.id(c)
+	if !ok {
+		return
+	}
+	existing, ok := h.load(c, id)
+	if !ok {
+		return
+	}
+
+	svc := h.Service(c)
+	version := svc.Repo().Version(existing)
+	if !ifMatch(c, version) {
+		preconditionFailed(c, existing, version)
+		return
+	}
+
+	if err := svc.Delete(c.Request.Context(), id, version, c.GetString(middleware.SubjectKey)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": h.Name + " not found"})
+		} else if errors.Is(err, repository.ErrVersionConflict) {
+			h.conflict(c, id)
+		} else if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete " + h.lower()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Trash lists the trashed records
+func (h *CRUDHandler[T, ID, F]) Trash(c *gin.Context) {
+	var opts repository.ListOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.Service(c).ListTrash(c.Request.Context(), opts)
+	if err != nil {
+		listError(c, err, "Failed to fetch "+h.lower()+"s")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Restore takes the record out of the trash
+func (h *CRUDHandler[T, ID, F]) Restore(c *gin.Context) {
+	id, ok := h.id(c)
+	if !ok {
+		return
+	}
+
+	svc := h.Service(c)
+	record, err := svc.Restore(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": h.Name + " not found in the trash"})
+		} else if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore " + h.lower()})
+		}
+		return
+	}
+
+	setETag(c, svc.Repo().Version(record))
+	c.JSON(http.StatusOK, record)
+}
+
+// Purge permanently deletes a trashed record
+func (h *CRUDHandler[T, ID, F]) Purge(c *gin.Context) {
+	id, ok := h.id(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service(c).Purge(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": h.Name + " not found in the trash"})
+		} else if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge " + h.lower() + ", other records may still refer to it"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
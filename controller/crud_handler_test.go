@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// note is a resource served only by the generic handler
+type note struct {
+	ID        uint64         `json:"id" gorm:"primaryKey"`
+	Text      string         `json:"text" validate:"required"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	DeletedBy string         `json:"deleted_by"`
+	Version   uint64         `json:"version" gorm:"not null;default:1"`
+}
+
+func TestCRUDHandler(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&note{}))
+
+	notes := service.NewCRUDService(repository.NewRepository[note, uint64](db, map[string]repository.SortColumn{"id": {Field: "ID"}}, "id"), "note")
+	handler := &CRUDHandler[note, uint64, repository.NoFilter]{
+		Name:    "Note",
+		Service: func(c *gin.Context) *service.CRUDService[note, uint64] { return notes },
+		ParseID: func(param string) (uint64, error) { return strconv.ParseUint(param, 10, 64) },
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.Register(router.Group("/note"), Routes{})
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/note/", `{"text":"first","version":5,"deleted_by":"x"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/note/", `{}`).Code)
+
+	w = do("PUT", "/note/1", `{"id":9,"text":"second"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/note/1", `{"text":""}`).Code)
+
+	w = do("PATCH", "/note/1", `{"text":"third"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do("GET", "/note/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got note
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, note{ID: 1, Text: "third", Version: 3}, got)
+
+	w = do("GET", "/note/", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/note/abc", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/note/2", "").Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/note/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/note/1", "").Code)
+
+	var trashed note
+	assert.NoError(t, db.Unscoped().First(&trashed, 1).Error)
+	assert.True(t, trashed.DeletedAt.Valid)
+	assert.Equal(t, uint64(4), trashed.Version)
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"gotempl/model"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag tags the response with the version of the record
+func setETag(c *gin.Context, version uint64) {
+	c.Header("ETag", model.ETag(version))
+}
+
+// notModified answers 304 when the If-None-Match header has the current version
+func notModified(c *gin.Context, version uint64) bool {
+	if !matchesETag(c.GetHeader("If-None-Match"), version) {
+		return false
+	}
+	setETag(c, version)
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// ifMatch tells whether the If-Match header, if any, allows changing the
+// current version of the record
+func ifMatch(c *gin.Context, version uint64) bool {
+	header := c.GetHeader("If-Match")
+	return header == "" || matchesETag(header, version)
+}
+
+// matchesETag tells whether a list of entity tags has the version, or is *
+func matchesETag(header string, version uint64) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == model.ETag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionFailed answers 412 with the current record, so the client can
+// show what changed and retry with its ETag
+func preconditionFailed(c *gin.Context, current any, version uint64) {
+	setETag(c, version)
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "The record was changed by someone else, review its current values and retry",
+		"current": current,
+	})
+}
@@ -2,12 +2,17 @@ package controller
 
 import (
 	"errors"
+	"gotempl/middleware"
 	"gotempl/model"
+	"gotempl/repository"
 	"gotempl/service"
 	"gotempl/views/crud"
 	"gotempl/views/layout"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -16,15 +21,72 @@ import (
 
 type EventHandler struct {
 	Service *service.EventService
+	// CRUD serves the REST routes of events, the methods below document them
+	CRUD *CRUDHandler[model.Event, uint64, repository.EventFilter]
 }
 
-func NewEventHandler(service *service.EventService) *EventHandler {
-	return &EventHandler{Service: service}
+func NewEventHandler(events *service.EventService) *EventHandler {
+	h := &EventHandler{Service: events}
+	h.CRUD = &CRUDHandler[model.Event, uint64, repository.EventFilter]{
+		Name: "Event",
+		Service: func(c *gin.Context) *service.CRUDService[model.Event, uint64] {
+			return h.scoped(c).CRUDService
+		},
+		ParseID: func(param string) (uint64, error) {
+			return strconv.ParseUint(param, 10, 64)
+		},
+		Prepare: prepareEvent,
+	}
+	return h
+}
+
+// Register adds the CRUD routes of events to group, their list expands the
+// occurrences of recurring events on request
+func (h *EventHandler) Register(group gin.IRouter, routes Routes) {
+	routes.List = h.GetAllEvents
+	h.CRUD.Register(group, routes)
+}
+
+// scoped returns the event service limited to the caller's organization, whose
+// changes are audited as made by the caller
+func (h *EventHandler) scoped(c *gin.Context) *service.EventService {
+	events := h.Service.As(middleware.CurrentActor(c))
+	tenant := middleware.CurrentTenant(c)
+	if tenant == middleware.AllTenants {
+		return events
+	}
+	return events.ForOrg(tenant)
+}
+
+// prepareEvent makes the caller the author of new events, never what the body
+// claims, and the last editor of changed ones, which keep their author,
+// organization and the occurrence they override. The attendees count is only
+// changed by registrations.
+func prepareEvent(c *gin.Context, event, existing *model.Event) error {
+	principal := middleware.CurrentPrincipal(c)
+	if principal == nil {
+		return errNoPrincipal
+	}
+
+	event.UpdatedBy = principal.Subject
+	if existing == nil {
+		event.CreatedBy = principal.Subject
+		event.AttendeesCount = 0
+		// Occurrences are overridden through EditOccurrence
+		event.SeriesID, event.RecurrenceID = nil, nil
+		return nil
+	}
+	event.AttendeesCount = existing.AttendeesCount
+	event.SeriesID, event.RecurrenceID = existing.SeriesID, existing.RecurrenceID
+	event.CreatedBy = existing.CreatedBy
+	event.CreatedAt = existing.CreatedAt
+	event.OrgID = existing.OrgID
+	return nil
 }
 
 // CreateEvent godoc
 // @Summary      Create a new event
-// @Description  Create a new event with the provided information
+// @Description  Create a new event with the provided information. createdBy and updated_by are set to the caller, attendees_count starts at 0 and the status at draft. start_time and end_time are returned in UTC, time_zone is an IANA name (UTC by default) and all-day events start and end at midnight in it.
 // @Tags         Event
 // @Accept       json
 // @Produce      json
@@ -32,149 +94,582 @@ func NewEventHandler(service *service.EventService) *EventHandler {
 // @Success      201   {object}  model.Event
 // @Failure      400   {object}  object
 // @Failure      500   {object}  object
+// @Security     BearerAuth
 // @Router       /event [post]
 func (h *EventHandler) CreateEvent(c *gin.Context) {
-	var event model.Event
+	h.CRUD.Create(c)
+}
+
+// GetAllEvents godoc
+// @Summary      List events
+// @Description  Retrieve a page of events. Pass next_cursor back as cursor, with the same sort and filters, to read the next page.
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        limit         query     int     false  "Page size, at most 100"  default(20)
+// @Param        cursor        query     string  false  "next_cursor of the previous page"
+// @Param        sort          query     string  false  "id, start_time, end_time, created_at, updated_at or title, prefixed with - for descending order"  default(id)
+// @Param        status        query     string  false  "Only events with this status"
+// @Param        event_type    query     string  false  "Only events of this type"
+// @Param        created_by    query     string  false  "Only events created by this user ID"
+// @Param        is_public     query     bool    false  "Only public or private events"
+// @Param        is_featured   query     bool    false  "Only featured or not featured events"
+// @Param        start_after   query     string  false  "Only events starting at or after this RFC 3339 time"
+// @Param        start_before  query     string  false  "Only events starting before this RFC 3339 time"
+// @Param        tag           query     string  false  "Only events with this tag"
+// @Param        recurring     query     bool    false  "Only recurring or not recurring events"
+// @Param        expand        query     bool    false  "List the occurrences starting from from to to instead, in order, without pagination"
+// @Param        from          query     string  false  "Start of the expanded window, an RFC 3339 time"
+// @Param        to            query     string  false  "End of the expanded window, an RFC 3339 time"
+// @Success      200  {object}  repository.Page[model.Event]
+// @Success      200  {array}   model.Occurrence  "With expand=true"
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /event [get]
+func (h *EventHandler) GetAllEvents(c *gin.Context) {
+	if c.Query("expand") != "true" {
+		h.CRUD.List(c)
+		return
+	}
 
-	if err := c.ShouldBindJSON(&event); err != nil {
+	var filter repository.EventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	from, to, ok := occurrenceWindow(c)
+	if !ok {
+		return
+	}
 
-	if err := h.Service.CreateEvent(&event); err != nil {
-		log.Error("Error:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create event"})
+	occurrences, err := h.scoped(c).ListOccurrences(c.Request.Context(), filter, from, to)
+	if err != nil {
+		h.occurrenceError(c, 0, err, "Failed to fetch occurrences")
 		return
 	}
 
-	c.JSON(http.StatusCreated, event)
+	c.JSON(http.StatusOK, occurrences)
 }
 
-// GetAllEvents godoc
-// @Summary      Get all events
-// @Description  Retrieve a list of all events
+// SearchEvents godoc
+// @Summary      Search events
+// @Description  Full-text search of the title, description, location, tags and organizer contact info of events. Every word must match, as a prefix. Results are ordered by relevance and carry an HTML snippet with the matches in <mark>.
 // @Tags         Event
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   model.Event
+// @Param        q      query     string  true   "Words to search for"
+// @Param        limit  query     int     false  "Number of results, at most 100"  default(20)
+// @Success      200  {object}  repository.Page[repository.SearchResult]
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
-// @Router       /event [get]
-func (h *EventHandler) GetAllEvents(c *gin.Context) {
-	events, err := h.Service.GetAllEvents()
+// @Security     BearerAuth
+// @Router       /event/search [get]
+func (h *EventHandler) SearchEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	page, err := h.scoped(c).SearchEvents(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptySearch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search events"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetTags godoc
+// @Summary      List tags
+// @Description  Retrieve the tags of the events with how many events have them, the most used first
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   repository.TagCount
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /tags [get]
+func (h *EventHandler) GetTags(c *gin.Context) {
+	tags, err := h.scoped(c).TagCounts(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		log.Error("Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
 		return
 	}
 
-	c.JSON(http.StatusOK, events)
+	c.JSON(http.StatusOK, tags)
 }
 
 // GetEvent godoc
 // @Summary      Get a event by ID
-// @Description  Retrieve a event's information using their ID
+// @Description  Retrieve a event's information using their ID. The ETag header is its version, to send back in If-Match when changing it.
 // @Tags         Event
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "Event ID"
+// @Param        If-None-Match  header  string  false  "ETag of a cached version, answered with 304 while it is current"
 // @Success      200  {object}  model.Event
+// @Success      304  {object}  nil
 // @Failure      400  {object}  object
 // @Failure      404  {object}  object
+// @Security     BearerAuth
 // @Router       /event/{id} [get]
 func (h *EventHandler) GetEvent(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+	h.CRUD.Get(c)
+}
+
+// UpdateEvent godoc
+// @Summary      Update a event
+// @Description  Update a event's information in the system. createdBy, attendees_count and the status are kept, the status only changes through the lifecycle actions. updated_by is set to the caller.
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string     true  "Event ID"
+// @Param        event  body      model.Event true  "Updated event information"
+// @Param        If-Match  header  string  false  "ETag of the version the change is based on"
+// @Success      200   {object}  model.Event
+// @Failure      400   {object}  object
+// @Failure      404   {object}  object
+// @Failure      500   {object}  object
+// @Failure      412  {object}  object  "The event changed, the body has its current version"
+// @Security     BearerAuth
+// @Router       /event/{id} [put]
+func (h *EventHandler) UpdateEvent(c *gin.Context) {
+	h.CRUD.Update(c)
+}
+
+// PatchEvent godoc
+// @Summary      Patch a event
+// @Description  Change some fields of a event. The body is a JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json): its members replace those of the event and null resets them. A JSON Patch (RFC 6902, application/json-patch+json) is accepted too. id, createdBy, created_at, org_id, attendees_count, status and the trash fields can't be changed, updated_by is set to the caller.
+// @Tags         Event
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id     path      string  true  "Event ID"
+// @Param        patch  body      object  true  "Merge patch, e.g. {\"title\": \"New title\"}"
+// @Param        If-Match  header  string  false  "ETag of the version the change is based on"
+// @Success      200   {object}  model.Event
+// @Failure      400   {object}  object
+// @Failure      404   {object}  object
+// @Failure      412   {object}  object  "The event changed, the body has its current version"
+// @Failure      415   {object}  object
+// @Failure      500   {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id} [patch]
+func (h *EventHandler) PatchEvent(c *gin.Context) {
+	h.CRUD.Patch(c)
+}
+
+// DeleteEvent godoc
+// @Summary      Delete a event
+// @Description  Move a event to the trash using their ID. It can be restored until it is purged.
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Event ID"
+// @Param        If-Match  header  string  false  "ETag of the version the deletion is based on"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Failure      412  {object}  object  "The event changed, the body has its current version"
+// @Security     BearerAuth
+// @Router       /event/{id} [delete]
+func (h *EventHandler) DeleteEvent(c *gin.Context) {
+	h.CRUD.Delete(c)
+}
+
+// TransitionRequest is the optional body of the lifecycle actions
+type TransitionRequest struct {
+	// Reason is required to cancel an event
+	Reason string `json:"reason" example:"The venue is unavailable"`
+}
+
+// TransitionEvent godoc
+// @Summary      Change the status of a event
+// @Description  Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string             true   "Event ID"
+// @Param        request  body      TransitionRequest  false  "Why the status changes"
+// @Param        If-Match  header  string  false  "ETag of the version the change is based on"
+// @Success      200  {object}  model.Event
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      409  {object}  object  "The action doesn't apply to the current status"
+// @Failure      412  {object}  object  "The event changed, the body has its current version"
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/schedule [post]
+// @Router       /event/{id}/publish [post]
+// @Router       /event/{id}/start [post]
+// @Router       /event/{id}/complete [post]
+// @Router       /event/{id}/postpone [post]
+// @Router       /event/{id}/cancel [post]
+func (h *EventHandler) TransitionEvent(c *gin.Context) {
+	id, ok := h.CRUD.id(c)
+	if !ok {
+		return
+	}
+	var req TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	existing, ok := h.CRUD.load(c, id)
+	if !ok {
+		return
+	}
+	if !ifMatch(c, existing.Version) {
+		preconditionFailed(c, existing, existing.Version)
 		return
 	}
 
-	event, err := h.Service.GetEventByID(id)
+	event, err := h.scoped(c).Transition(c.Request.Context(), id, existing.Version, path.Base(c.FullPath()), req.Reason, c.GetString(middleware.SubjectKey))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		case errors.Is(err, repository.ErrVersionConflict):
+			h.CRUD.conflict(c, id)
+		case errors.Is(err, service.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change the status of the event"})
 		}
 		return
 	}
 
+	setETag(c, event.Version)
 	c.JSON(http.StatusOK, event)
 }
 
-// UpdateEvent godoc
-// @Summary      Update a event
-// @Description  Update a event's information in the system
+// occurrenceWindow parses the from and to query parameters, it answers 400
+// when they are missing or malformed
+func occurrenceWindow(c *gin.Context) (time.Time, time.Time, bool) {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time"})
+		return from, from, false
+	}
+	to, err := time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time"})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// occurrenceStart parses the start path parameter, it answers 400 when it is malformed
+func occurrenceStart(c *gin.Context) (time.Time, bool) {
+	start, err := time.Parse(time.RFC3339, c.Param("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The occurrence must be its RFC 3339 start time"})
+		return start, false
+	}
+	return start, true
+}
+
+// occurrenceError reports the failures of the occurrences of event id, and
+// unexpected ones with msg
+func (h *EventHandler) occurrenceError(c *gin.Context, id uint64, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	case errors.Is(err, service.ErrNoOccurrence):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Occurrence not found"})
+	case errors.Is(err, repository.ErrVersionConflict):
+		h.CRUD.conflict(c, id)
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, repository.ErrInvalidListOptions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error("Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
+// GetOccurrences godoc
+// @Summary      List the occurrences of a event
+// @Description  Expand the recurrence rule of a event into its occurrences starting from from to to, in order. The exceptions (exdates) are left out and the overridden occurrences are replaced by the events overriding them, wherever they moved. A event that doesn't recur has one occurrence. At most 1000 occurrences are returned, in a window of at most 366 days starting at most 100 years after the series.
 // @Tags         Event
 // @Accept       json
 // @Produce      json
-// @Param        id    path      string     true  "Event ID"
-// @Param        event  body      model.Event true  "Updated event information"
+// @Param        id    path      string  true  "Event ID"
+// @Param        from  query     string  true  "Start of the window, an RFC 3339 time"
+// @Param        to    query     string  true  "End of the window, an RFC 3339 time"
+// @Success      200  {array}   model.Occurrence
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/occurrences [get]
+func (h *EventHandler) GetOccurrences(c *gin.Context) {
+	id, ok := h.CRUD.id(c)
+	if !ok {
+		return
+	}
+	from, to, ok := occurrenceWindow(c)
+	if !ok {
+		return
+	}
+
+	occurrences, err := h.scoped(c).Occurrences(c.Request.Context(), id, from, to)
+	if err != nil {
+		h.occurrenceError(c, id, err, "Failed to fetch occurrences")
+		return
+	}
+
+	c.JSON(http.StatusOK, occurrences)
+}
+
+// EditOccurrence godoc
+// @Summary      Change an occurrence of a recurring event
+// @Description  Patch the occurrence of a recurring event starting at start, as given by its rule. With scope=this (the default) an event overriding the occurrence is created, or changed, with the fields of the series; with scope=following the series ends before the occurrence and a new series, changed by the patch, continues it with the following exceptions and overrides; with scope=all the series itself is changed, its exceptions and overrides move with its start. The body is a patch as for PATCH /event/{id}, of the event changed, which is returned.
+// @Tags         Event
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id     path      string  true   "Event ID of the series"
+// @Param        start  path      string  true   "RFC 3339 start of the occurrence, given by the rule"
+// @Param        scope  query     string  false  "this, following or all"  default(this)
+// @Param        patch  body      object  true   "Merge patch, e.g. {\"title\": \"New title\"}"
+// @Param        If-Match  header  string  false  "ETag of the version of the series the change is based on"
 // @Success      200   {object}  model.Event
 // @Failure      400   {object}  object
+// @Failure      404   {object}  object
+// @Failure      412   {object}  object  "The series changed, the body has its current version"
+// @Failure      415   {object}  object
 // @Failure      500   {object}  object
-// @Router       /event/{id} [put]
-func (h *EventHandler) UpdateEvent(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+// @Security     BearerAuth
+// @Router       /event/{id}/occurrences/{start} [patch]
+func (h *EventHandler) EditOccurrence(c *gin.Context) {
+	id, ok := h.CRUD.id(c)
+	if !ok {
 		return
 	}
-
-	var event model.Event
-	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	start, ok := occurrenceStart(c)
+	if !ok {
+		return
+	}
+	series, ok := h.CRUD.load(c, id)
+	if !ok {
+		return
+	}
+	if !ifMatch(c, series.Version) {
+		preconditionFailed(c, series, series.Version)
 		return
 	}
 
-	event.ID = uint64(id)
-	if err := h.Service.UpdateEvent(&event); err != nil {
-		log.Error(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+	// The patch applies to the event the service changes
+	var decodeErr error
+	events := h.scoped(c)
+	event, err := events.EditOccurrence(c.Request.Context(), id, series.Version, start, c.DefaultQuery("scope", service.EditThis), func(current *model.Event) error {
+		var record model.Event
+		if decodeErr = patchRecord(c, current, &record); decodeErr != nil {
+			return decodeErr
+		}
+		events.Repo().Keep(&record, current)
+		if decodeErr = prepareEvent(c, &record, current); decodeErr != nil {
+			return decodeErr
+		}
+		*current = record
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(decodeErr, errNoPrincipal):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": decodeErr.Error()})
+		case decodeErr != nil:
+			patchError(c, decodeErr)
+		default:
+			h.occurrenceError(c, id, err, "Failed to update the occurrence")
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, event)
 }
 
-// DeleteEvent godoc
-// @Summary      Delete a event
-// @Description  Delete a event from the system using their ID.
+// DeleteOccurrence godoc
+// @Summary      Delete an occurrence of a recurring event
+// @Description  Remove the occurrence of a recurring event starting at start, as given by its rule. With scope=this (the default) it becomes an exception of the series (exdates); with scope=following the series ends before it; with scope=all the series goes to the trash. The events overriding the removed occurrences go to the trash.
 // @Tags         Event
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "Event ID"
+// @Param        id     path      string  true   "Event ID of the series"
+// @Param        start  path      string  true   "RFC 3339 start of the occurrence, given by the rule"
+// @Param        scope  query     string  false  "this, following or all"  default(this)
+// @Param        If-Match  header  string  false  "ETag of the version of the series the deletion is based on"
 // @Success      204  {object}  nil
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      412  {object}  object  "The series changed, the body has its current version"
 // @Failure      500  {object}  object
-// @Router       /event/{id} [delete]
-func (h *EventHandler) DeleteEvent(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+// @Security     BearerAuth
+// @Router       /event/{id}/occurrences/{start} [delete]
+func (h *EventHandler) DeleteOccurrence(c *gin.Context) {
+	id, ok := h.CRUD.id(c)
+	if !ok {
+		return
+	}
+	start, ok := occurrenceStart(c)
+	if !ok {
+		return
+	}
+	series, ok := h.CRUD.load(c, id)
+	if !ok {
+		return
+	}
+	if !ifMatch(c, series.Version) {
+		preconditionFailed(c, series, series.Version)
 		return
 	}
 
-	if err := h.Service.DeleteEvent(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+	err := h.scoped(c).DeleteOccurrence(c.Request.Context(), id, series.Version, start, c.DefaultQuery("scope", service.EditThis), c.GetString(middleware.SubjectKey))
+	if err != nil {
+		h.occurrenceError(c, id, err, "Failed to delete the occurrence")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// GetTrash godoc
+// @Summary      List trashed events
+// @Description  Retrieve a page of the events in the trash, by default the last deleted first
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        limit   query     int     false  "Page size, at most 100"  default(20)
+// @Param        cursor  query     string  false  "next_cursor of the previous page"
+// @Param        sort    query     string  false  "deleted_at or a sort of the event list, prefixed with - for descending order"  default(-deleted_at)
+// @Success      200  {object}  repository.Page[model.Event]
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /event/trash [get]
+func (h *EventHandler) GetTrash(c *gin.Context) {
+	h.CRUD.Trash(c)
+}
+
+// RestoreEvent godoc
+// @Summary      Restore a event
+// @Description  Take a event out of the trash
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Event ID"
+// @Success      200  {object}  model.Event
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/restore [post]
+func (h *EventHandler) RestoreEvent(c *gin.Context) {
+	h.CRUD.Restore(c)
+}
+
+// PurgeEvent godoc
+// @Summary      Purge a event
+// @Description  Permanently delete a event that is in the trash
+// @Tags         Event
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Event ID"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /event/{id}/purge [delete]
+func (h *EventHandler) PurgeEvent(c *gin.Context) {
+	h.CRUD.Purge(c)
+}
+
 // EventCRUDHandler godoc
 // @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
-// @Description  Fetches all events and renders an HTML page with a CRUD form for event management (non-REST endpoint)
+// @Description  Fetches a page of events and renders an HTML page with a CRUD form for event management (non-REST endpoint)
 // @Tags         Event
 // @Produce      html
 // @Success      200  {string}  string  "HTML page content"
 // @Router       /admin/event/ [get]
 // @Notes
 func (h *EventHandler) EventCRUDHandler(c *gin.Context) {
-	events, err := h.Service.GetAllEvents()
+	if query := c.Query("q"); query != "" {
+		h.eventSearchPage(c, query)
+		return
+	}
+
+	var filter repository.EventFilter
+	opts, err := bindListQuery(c, &filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.scoped(c).List(c.Request.Context(), filter, opts)
+	if err != nil {
+		listError(c, err, "Failed to fetch events")
+		return
+	}
+
+	layout.Render(c, 200, crud.EventForm(page.Items, pagination(c, page), crud.Search{}))
+}
+
+// eventSearchPage renders the admin page with the search results in relevance order
+func (h *EventHandler) eventSearchPage(c *gin.Context, query string) {
+	search := crud.Search{Query: query, Snippets: map[uint64]string{}}
+	events := []model.Event{}
+
+	page, err := h.scoped(c).SearchEvents(c.Request.Context(), query, repository.MaxPageSize)
+	if err != nil && !errors.Is(err, repository.ErrEmptySearch) {
+		log.Error("Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search events"})
+		return
+	}
+	if page == nil {
+		page = &repository.Page[repository.SearchResult]{}
+	}
+
+	for _, result := range page.Items {
+		events = append(events, result.Event)
+		search.Snippets[result.Event.ID] = result.Snippet
+	}
+	layout.Render(c, 200, crud.EventForm(events, crud.Pagination{Shown: len(events), Total: page.Total}, search))
+}
+
+// EventTrashHandler godoc
+// @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
+// @Description  Fetches a page of trashed events and renders an HTML page to restore or purge them (non-REST endpoint)
+// @Tags         Event
+// @Produce      html
+// @Success      200  {string}  string  "HTML page content"
+// @Router       /admin/event/trash [get]
+func (h *EventHandler) EventTrashHandler(c *gin.Context) {
+	var opts repository.ListOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.scoped(c).ListTrash(c.Request.Context(), opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		listError(c, err, "Failed to fetch events")
 		return
 	}
 
-	//print(events)
-	layout.Render(c, 200, crud.EventForm(events))
+	layout.Render(c, 200, crud.EventTrash(page.Items, pagination(c, page)))
 }
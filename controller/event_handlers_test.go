@@ -0,0 +1,706 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gotempl/middleware"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupEventTestEnvironment(t *testing.T) (*gorm.DB, *EventHandler, *gin.Engine) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&model.User{}, &model.Event{})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	repo := repository.NewEventRepository(db)
+	service := service.NewEventService(repo, repository.NewSearchRepository(db))
+	handler := NewEventHandler(service)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	return db, handler, router
+}
+
+// withPrincipal stands in for the authentication middleware
+func withPrincipal(subject string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(middleware.SubjectKey, subject)
+		c.Set(middleware.PrincipalKey, &middleware.Principal{Subject: subject})
+		c.Next()
+	}
+}
+
+func TestCreateEventSetsAuthor(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	router.POST("/event", withPrincipal("author-uid"), handler.CreateEvent)
+
+	body := `{"title":"Standup","createdBy":"someone-else","updated_by":"someone-else"}`
+	req, _ := http.NewRequest("POST", "/event", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var responseEvent model.Event
+	err := json.Unmarshal(w.Body.Bytes(), &responseEvent)
+	assert.NoError(t, err)
+	assert.Equal(t, "author-uid", responseEvent.CreatedBy)
+	assert.Equal(t, "author-uid", responseEvent.UpdatedBy)
+
+	var dbEvent model.Event
+	err = db.First(&dbEvent, responseEvent.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, "author-uid", dbEvent.CreatedBy)
+}
+
+func TestCreateEventRequiresPrincipal(t *testing.T) {
+	_, handler, router := setupEventTestEnvironment(t)
+	router.POST("/event", handler.CreateEvent)
+
+	req, _ := http.NewRequest("POST", "/event", bytes.NewBufferString(`{"title":"Standup"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestUpdateEventKeepsAuthor(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	router.PUT("/event/:id", withPrincipal("editor-uid"), handler.UpdateEvent)
+
+	testEvent := model.Event{Title: "Standup", CreatedBy: "author-uid", UpdatedBy: "author-uid"}
+	db.Create(&testEvent)
+
+	body := `{"title":"Daily standup","createdBy":"someone-else"}`
+	req, _ := http.NewRequest("PUT", "/event/1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var dbEvent model.Event
+	err := db.First(&dbEvent, testEvent.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, "Daily standup", dbEvent.Title)
+	assert.Equal(t, "author-uid", dbEvent.CreatedBy)
+	assert.Equal(t, "editor-uid", dbEvent.UpdatedBy)
+	assert.Equal(t, testEvent.CreatedAt.Unix(), dbEvent.CreatedAt.Unix())
+
+	// Updating a missing event
+	req, _ = http.NewRequest("PUT", "/event/42", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// withOrgPrincipal authenticates as a member of an organization with a local role
+func withOrgPrincipal(subject, orgID, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(middleware.SubjectKey, subject)
+		c.Set(middleware.RoleKey, role)
+		c.Set(middleware.PrincipalKey, &middleware.Principal{Subject: subject, OrgID: orgID, Role: role})
+		c.Next()
+	}
+}
+
+func TestEventsAreScopedToOrganization(t *testing.T) {
+	db, handler, _ := setupEventTestEnvironment(t)
+
+	db.Create(&model.Event{Title: "A1", CreatedBy: "u1", OrgID: "org_a"})
+	db.Create(&model.Event{Title: "B1", CreatedBy: "u2", OrgID: "org_b"})
+	db.Create(&model.Event{Title: "A2", CreatedBy: "u1", OrgID: "org_a"})
+
+	listTitles := func(router *gin.Engine, header map[string]string) []string {
+		req, _ := http.NewRequest("GET", "/event", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var page repository.Page[model.Event]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		titles := []string{}
+		for _, event := range page.Items {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+
+	t.Run("Member only sees its organization", func(t *testing.T) {
+		router := gin.New()
+		auth := withOrgPrincipal("u1", "org_a", "user")
+		router.GET("/event", auth, middleware.ResolveTenant(), handler.GetAllEvents)
+		router.GET("/event/:id", auth, middleware.ResolveTenant(), handler.GetEvent)
+		router.POST("/event", auth, middleware.ResolveTenant(), handler.CreateEvent)
+
+		assert.ElementsMatch(t, []string{"A1", "A2"}, listTitles(router, nil))
+
+		req, _ := http.NewRequest("GET", "/event/2", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		req, _ = http.NewRequest("POST", "/event", bytes.NewBufferString(`{"title":"A3","org_id":"org_b"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var created model.Event
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Equal(t, "org_a", created.OrgID)
+	})
+
+	t.Run("Super admin sees every organization or the one picked", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/event", withOrgPrincipal("root", "", middleware.RoleSuperAdmin), middleware.ResolveTenant(), handler.GetAllEvents)
+
+		assert.ElementsMatch(t, []string{"A1", "A2", "A3", "B1"}, listTitles(router, nil))
+		assert.ElementsMatch(t, []string{"B1"}, listTitles(router, map[string]string{"X-Tenant": "org_b"}))
+	})
+}
+
+func TestGetAllEventsPagination(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	router.GET("/event", handler.GetAllEvents)
+
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 10, d, 9, 0, 0, 0, time.UTC)
+		return &t
+	}
+	events := []model.Event{
+		{Title: "E1", CreatedBy: "u1", Status: "published", StartTime: day(3)},
+		{Title: "E2", CreatedBy: "u1", Status: "draft", StartTime: day(1)},
+		{Title: "E3", CreatedBy: "u2", Status: "published", StartTime: day(3)},
+		{Title: "E4", CreatedBy: "u2", Status: "published", StartTime: day(2), IsFeatured: true},
+		{Title: "E5", CreatedBy: "u1", Status: "published"},
+	}
+	for i := range events {
+		assert.NoError(t, db.Create(&events[i]).Error)
+	}
+
+	// list walks every page and returns the titles in order
+	list := func(query string) ([]string, int64) {
+		titles := []string{}
+		var total int64
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			req, _ := http.NewRequest("GET", "/event?"+query+"&cursor="+cursor, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var page repository.Page[model.Event]
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			assert.LessOrEqual(t, len(page.Items), 2)
+			for _, event := range page.Items {
+				titles = append(titles, event.Title)
+			}
+			total = page.Total
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		return titles, total
+	}
+
+	titles, total := list("limit=2")
+	assert.Equal(t, []string{"E1", "E2", "E3", "E4", "E5"}, titles)
+	assert.Equal(t, int64(5), total)
+
+	// Ties on start_time are broken by ID, events without a start time come first
+	titles, _ = list("limit=2&sort=start_time")
+	assert.Equal(t, []string{"E5", "E2", "E4", "E1", "E3"}, titles)
+	titles, _ = list("limit=2&sort=-start_time")
+	assert.Equal(t, []string{"E3", "E1", "E4", "E2", "E5"}, titles)
+
+	titles, total = list("limit=2&status=published&created_by=u2")
+	assert.Equal(t, []string{"E3", "E4"}, titles)
+	assert.Equal(t, int64(2), total)
+
+	titles, _ = list("limit=2&is_featured=true")
+	assert.Equal(t, []string{"E4"}, titles)
+
+	titles, _ = list("limit=2&sort=start_time&start_after=2024-10-02T02:00:00%2B02:00&start_before=2024-10-03T12:00:00Z")
+	assert.Equal(t, []string{"E4", "E1", "E3"}, titles)
+
+	for _, query := range []string{"sort=location", "cursor=garbage", "is_public=maybe", "start_after=yesterday"} {
+		req, _ := http.NewRequest("GET", "/event?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestEventTrash(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	auth := withPrincipal("admin-uid")
+	router.GET("/event", handler.GetAllEvents)
+	router.GET("/event/trash", handler.GetTrash)
+	router.GET("/event/:id", handler.GetEvent)
+	router.PUT("/event/:id", auth, handler.UpdateEvent)
+	router.DELETE("/event/:id", auth, handler.DeleteEvent)
+	router.POST("/event/:id/restore", handler.RestoreEvent)
+	router.DELETE("/event/:id/purge", handler.PurgeEvent)
+
+	events := []model.Event{{Title: "Keep"}, {Title: "Trash"}}
+	for i := range events {
+		assert.NoError(t, db.Create(&events[i]).Error)
+	}
+	trashed := "/event/" + strconv.FormatUint(events[1].ID, 10)
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(`{"title":"Edited"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	titles := func(url string) []string {
+		w := do("GET", url)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page repository.Page[model.Event]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		titles := []string{}
+		for _, event := range page.Items {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+
+	// Restoring or purging a live event does nothing
+	assert.Equal(t, http.StatusNotFound, do("POST", trashed+"/restore").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", trashed+"/purge").Code)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", trashed).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", trashed).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/event/999").Code)
+
+	// Trashed events are hidden and can't be edited
+	assert.Equal(t, []string{"Keep"}, titles("/event"))
+	assert.Equal(t, []string{"Trash"}, titles("/event/trash"))
+	assert.Equal(t, http.StatusNotFound, do("GET", trashed).Code)
+	assert.Equal(t, http.StatusNotFound, do("PUT", trashed).Code)
+
+	var dbEvent model.Event
+	assert.NoError(t, db.Unscoped().First(&dbEvent, events[1].ID).Error)
+	assert.True(t, dbEvent.DeletedAt.Valid)
+	assert.Equal(t, "admin-uid", dbEvent.DeletedBy)
+
+	w := do("POST", trashed+"/restore")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Keep", "Trash"}, titles("/event"))
+	assert.Empty(t, titles("/event/trash"))
+
+	var restored model.Event
+	assert.NoError(t, db.Unscoped().First(&restored, events[1].ID).Error)
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.Empty(t, restored.DeletedBy)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", trashed).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", trashed+"/purge").Code)
+	assert.Empty(t, titles("/event/trash"))
+	assert.ErrorIs(t, db.Unscoped().First(&dbEvent, events[1].ID).Error, gorm.ErrRecordNotFound)
+}
+
+func TestEventOptimisticConcurrency(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	auth := withPrincipal("editor-uid")
+	router.GET("/event/:id", handler.GetEvent)
+	router.PUT("/event/:id", auth, handler.UpdateEvent)
+	router.DELETE("/event/:id", auth, handler.DeleteEvent)
+
+	event := model.Event{Title: "Draft", CreatedBy: "author-uid"}
+	assert.NoError(t, db.Create(&event).Error)
+	assert.Equal(t, uint64(1), event.Version)
+
+	do := func(method, body string, header map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/event/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, do("GET", "", map[string]string{"If-None-Match": `"1"`}).Code)
+
+	// The first admin saves, the version moves on
+	w = do("PUT", `{"title":"First","version":7}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// The second one edited version 1 too and is told about the change
+	w = do("PUT", `{"title":"Second"}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	var conflict struct {
+		Current model.Event `json:"current"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &conflict))
+	assert.Equal(t, "First", conflict.Current.Title)
+	assert.Equal(t, uint64(2), conflict.Current.Version)
+
+	assert.Equal(t, http.StatusPreconditionFailed, do("DELETE", "", map[string]string{"If-Match": `"1"`}).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do("DELETE", "", map[string]string{"If-Match": `W/"2"`}).Code)
+
+	// A write racing with another one between the read and the save
+	repo := repository.NewEventRepository(db)
+	stale, err := repo.GetByID(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&model.Event{}).Where("id = 1").Update("version", gorm.Expr("version + 1")).Error)
+	stale.Title = "Stale"
+	assert.ErrorIs(t, repo.Update(context.Background(), stale), repository.ErrVersionConflict)
+	assert.Equal(t, uint64(2), stale.Version)
+	assert.ErrorIs(t, repo.Delete(context.Background(), 1, 2, "editor-uid"), repository.ErrVersionConflict)
+	assert.ErrorIs(t, repo.Delete(context.Background(), 99, 0, "editor-uid"), gorm.ErrRecordNotFound)
+
+	w = do("DELETE", "", map[string]string{"If-Match": `"3", "4"`})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var dbEvent model.Event
+	assert.NoError(t, db.Unscoped().First(&dbEvent, 1).Error)
+	assert.Equal(t, "First", dbEvent.Title)
+	assert.Equal(t, uint64(4), dbEvent.Version)
+}
+
+func TestPatchEvent(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	router.PATCH("/event/:id", withPrincipal("editor-uid"), handler.PatchEvent)
+
+	event := model.Event{Title: "Draft", Location: "Berlin", Status: "published", MaxAttendees: 50, CreatedBy: "author-uid"}
+	assert.NoError(t, db.Create(&event).Error)
+
+	patch := func(contentType, body, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/event/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only the title changes, immutable fields are ignored
+	w := patch(MergePatchType, `{"title":"Renamed","id":7,"createdBy":"mallory","version":9}`, `"1"`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	var dbEvent model.Event
+	assert.NoError(t, db.First(&dbEvent, 1).Error)
+	assert.Equal(t, "Renamed", dbEvent.Title)
+	assert.Equal(t, "Berlin", dbEvent.Location)
+	assert.Equal(t, "published", dbEvent.Status)
+	assert.Equal(t, uint(50), dbEvent.MaxAttendees)
+	assert.Equal(t, "author-uid", dbEvent.CreatedBy)
+	assert.Equal(t, "editor-uid", dbEvent.UpdatedBy)
+	assert.Equal(t, uint64(2), dbEvent.Version)
+
+	// null resets a field
+	w = patch("application/json", `{"location":null}`, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = patch(JSONPatchType, `[{"op":"test","path":"/title","value":"Renamed"},{"op":"replace","path":"/max_attendees","value":80}]`, `"3"`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var patched model.Event
+	assert.NoError(t, db.First(&patched, 1).Error)
+	assert.Equal(t, "", patched.Location)
+	assert.Equal(t, uint(80), patched.MaxAttendees)
+	assert.Equal(t, uint64(4), patched.Version)
+
+	assert.Equal(t, http.StatusBadRequest, patch(JSONPatchType, `[{"op":"test","path":"/title","value":"Draft"}]`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, patch(MergePatchType, `["title"]`, "").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, patch("text/plain", `title=x`, "").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, patch(MergePatchType, `{"title":"Late"}`, `"1"`).Code)
+
+	req, _ := http.NewRequest("PATCH", "/event/99", bytes.NewBufferString(`{"title":"Nobody"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestEventLifecycle(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	router.Use(withPrincipal("editor-uid"))
+	router.POST("/event", handler.CreateEvent)
+	router.PUT("/event/:id", handler.UpdateEvent)
+	for action := range service.EventTransitions {
+		router.POST("/event/:id/"+action, handler.TransitionEvent)
+	}
+
+	do := func(method, url, body, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	status := func() model.Event {
+		var event model.Event
+		assert.NoError(t, db.First(&event, 1).Error)
+		return event
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/event", `{"title":"Launch","status":"live"}`, "").Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/event", `{"title":"Launch"}`, "").Code)
+	assert.Equal(t, model.EventDraft, status().Status)
+
+	// The status only changes through the lifecycle actions
+	w := do("PUT", "/event/1", `{"title":"Launch","status":"published"}`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do("PUT", "/event/1", `{"title":"Launch","status":"draft","status_reason":"made up"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, status().StatusReason)
+
+	w = do("POST", "/event/1/publish", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "start time")
+	assert.Equal(t, http.StatusConflict, do("POST", "/event/1/complete", "", "").Code)
+
+	w = do("PUT", "/event/1", `{"title":"Launch","start_time":"2024-11-05T18:00:00Z"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, model.EventDraft, status().Status)
+
+	// Lifecycle actions make the caller the last editor
+	assert.NoError(t, db.Model(&model.Event{}).Where("id = ?", 1).UpdateColumn("updated_by", "author-uid").Error)
+	assert.Equal(t, http.StatusPreconditionFailed, do("POST", "/event/1/publish", "", `"1"`).Code)
+	w = do("POST", "/event/1/publish", "", `"3"`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	assert.Equal(t, model.EventPublished, status().Status)
+	assert.Equal(t, "editor-uid", status().UpdatedBy)
+
+	w = do("POST", "/event/1/postpone", `{"reason":"Speaker is ill"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Speaker is ill", status().StatusReason)
+	assert.Equal(t, http.StatusOK, do("POST", "/event/1/schedule", "", "").Code)
+	assert.Empty(t, status().StatusReason)
+	assert.Equal(t, http.StatusOK, do("POST", "/event/1/start", "", "").Code)
+	assert.Equal(t, model.EventLive, status().Status)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/event/1/cancel", `{"reason":" "}`, "").Code)
+	assert.Equal(t, http.StatusOK, do("POST", "/event/1/cancel", `{"reason":"Power outage"}`, "").Code)
+	assert.Equal(t, model.EventCancelled, status().Status)
+	assert.Equal(t, http.StatusConflict, do("POST", "/event/1/publish", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/event/2/publish", "", "").Code)
+}
+
+func TestEventTags(t *testing.T) {
+	db, handler, router := setupEventTestEnvironment(t)
+	router.Use(withPrincipal("editor-uid"))
+	router.POST("/event", handler.CreateEvent)
+	router.GET("/event", handler.GetAllEvents)
+	router.DELETE("/event/:id", handler.DeleteEvent)
+	router.GET("/tags", handler.GetTags)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"title":"Bad image","images":["not a url"]}`,
+		`{"title":"Bad tag","tags":["Go Lang"]}`,
+		`{"title":"Twice","tags":["go","go"]}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do("POST", "/event", body).Code, body)
+	}
+	for _, body := range []string{
+		`{"title":"Go workshop","tags":["workshop","golang"],"images":["https://example.com/go.png"]}`,
+		`{"title":"Rust workshop","tags":["workshop","rust"]}`,
+		`{"title":"Lunch"}`,
+		`{"title":"Old workshop","tags":["workshop"]}`,
+	} {
+		assert.Equal(t, http.StatusCreated, do("POST", "/event", body).Code, body)
+	}
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/event/4", "").Code)
+
+	var event model.Event
+	assert.NoError(t, db.First(&event, 1).Error)
+	assert.Equal(t, model.StringList{"workshop", "golang"}, event.Tags)
+	assert.Equal(t, model.StringList{"https://example.com/go.png"}, event.Images)
+	w := do("GET", "/event?sort=id", "")
+	assert.Contains(t, w.Body.String(), `"title":"Lunch","description":"","location":"","images":[]`)
+
+	titles := func(url string) []string {
+		w := do("GET", url, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page repository.Page[model.Event]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		titles := []string{}
+		for _, event := range page.Items {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Go workshop", "Rust workshop"}, titles("/event?tag=workshop&sort=id"))
+	assert.Equal(t, []string{"Rust workshop"}, titles("/event?tag=rust"))
+	assert.Empty(t, titles("/event?tag=work"))
+	assert.Empty(t, titles("/event?tag=%25"))
+
+	w = do("GET", "/tags", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tags []repository.TagCount
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Equal(t, []repository.TagCount{{Tag: "workshop", Count: 2}, {Tag: "golang", Count: 1}, {Tag: "rust", Count: 1}}, tags)
+}
+
+func TestEventTimes(t *testing.T) {
+	_, handler, router := setupEventTestEnvironment(t)
+	router.POST("/event", withPrincipal("editor-uid"), handler.CreateEvent)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/event", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{"title":"Unscheduled"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"start_time":null,"end_time":null,`)
+	assert.Contains(t, w.Body.String(), `"time_zone":"UTC","all_day":false`)
+
+	w = create(`{"title":"Meetup","time_zone":"Europe/Paris","start_time":"2024-11-05T18:00:00+01:00","end_time":"2024-11-05T20:00:00+01:00"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"start_time":"2024-11-05T17:00:00Z","end_time":"2024-11-05T19:00:00Z"`)
+
+	w = create(`{"title":"Backwards","start_time":"2024-11-05T18:00:00Z","end_time":"2024-11-05T17:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "end_time must not be before start_time")
+}
+
+func TestEventRoutesExpandOccurrences(t *testing.T) {
+	_, handler, router := setupEventTestEnvironment(t)
+	handler.Register(router.Group("/event", withPrincipal("editor-uid")), Routes{})
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/event/", `{"title":"Standup","start_time":"2024-11-04T09:00:00Z","rrule":"FREQ=DAILY;COUNT=3"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = do("GET", "/event/?expand=true&from=2024-11-01T00:00:00Z&to=2024-12-01T00:00:00Z", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var occurrences []model.Occurrence
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &occurrences))
+	assert.Len(t, occurrences, 3)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/event/?expand=true&from=yesterday", "").Code)
+
+	w = do("GET", "/event/", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+}
+
+func TestEventOccurrences(t *testing.T) {
+	_, handler, router := setupEventTestEnvironment(t)
+	router.POST("/event", withPrincipal("editor-uid"), handler.CreateEvent)
+	router.GET("/event", withPrincipal("editor-uid"), handler.GetAllEvents)
+	router.GET("/event/:id/occurrences", withPrincipal("editor-uid"), handler.GetOccurrences)
+	router.PATCH("/event/:id/occurrences/:start", withPrincipal("editor-uid"), handler.EditOccurrence)
+	router.DELETE("/event/:id/occurrences/:start", withPrincipal("editor-uid"), handler.DeleteOccurrence)
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	starts := func(w *httptest.ResponseRecorder) []string {
+		var occurrences []model.Occurrence
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &occurrences))
+		times := []string{}
+		for _, o := range occurrences {
+			times = append(times, o.StartTime.Format(time.RFC3339)+" "+o.Event.Title)
+		}
+		return times
+	}
+	window := "from=2024-11-01T00:00:00Z&to=2024-12-01T00:00:00Z"
+
+	w := do("POST", "/event", `{"title":"Book club","start_time":"2024-11-05T18:00:00Z","end_time":"2024-11-05T19:00:00Z","rrule":"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU","series_id":7}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"rrule":"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU","exdates":[],"series_id":null,"recurrence_id":null`)
+	w = do("POST", "/event", `{"title":"Launch","start_time":"2024-11-12T12:00:00Z"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = do("POST", "/event", `{"title":"Daily","start_time":"2024-11-05T18:00:00Z","rrule":"FREQ=DAILY;BYDAY=MO"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "start_time must be the first occurrence of the rrule")
+
+	w = do("GET", "/event/1/occurrences?"+window, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"2024-11-05T18:00:00Z Book club", "2024-11-19T18:00:00Z Book club"}, starts(w))
+	assert.Contains(t, w.Body.String(), `"series_id":1,"recurrence_id":"2024-11-19T18:00:00Z","start_time":"2024-11-19T18:00:00Z","end_time":"2024-11-19T19:00:00Z"`)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/event/1/occurrences?from=yesterday&to=2024-12-01T00:00:00Z", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/event/1/occurrences?from=2024-12-01T00:00:00Z&to=2024-11-01T00:00:00Z", "").Code)
+	w = do("GET", "/event?expand=true&from=9998-01-01T00:00:00Z&to=9998-02-01T00:00:00Z", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "more than 100 years after the series")
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/event/1/occurrences?from=2024-01-01T00:00:00Z&to=2026-01-01T00:00:00Z", "").Code)
+
+	// This one moves to the Wednesday
+	w = do("PATCH", "/event/1/occurrences/2024-11-19T18:00:00Z", `{"title":"Book club (moved)","start_time":"2024-11-20T18:00:00Z","end_time":"2024-11-20T19:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"series_id":1,"recurrence_id":"2024-11-19T18:00:00Z"`)
+	assert.Equal(t, http.StatusNotFound, do("PATCH", "/event/1/occurrences/2024-11-12T18:00:00Z", `{"title":"Off week"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("PATCH", "/event/1/occurrences/2024-11-19T18:00:00Z?scope=some", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("PATCH", "/event/1/occurrences/2024-11-19T18:00:00Z", `[]`).Code)
+
+	w = do("GET", "/event?expand=true&"+window, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"2024-11-05T18:00:00Z Book club", "2024-11-12T12:00:00Z Launch", "2024-11-20T18:00:00Z Book club (moved)"}, starts(w))
+	w = do("GET", "/event", "")
+	assert.Contains(t, w.Body.String(), `"total":2`)
+
+	// Deleting the series from the first occurrence removes its override
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/event/1/occurrences/2024-11-05T18:00:00Z?scope=following", "").Code)
+	w = do("GET", "/event?expand=true&"+window, "")
+	assert.Equal(t, []string{"2024-11-12T12:00:00Z Launch"}, starts(w))
+}
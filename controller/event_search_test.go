@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gotempl/database"
+	"gotempl/middleware"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+// The search runs on SQLite FTS5 with `go test -tags sqlite_fts5`, and on the
+// LIKE fallback otherwise
+func TestSearchEvents(t *testing.T) {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLiteMemory, LogLevel: logger.Silent})
+	assert.NoError(t, err)
+	_, err = database.Migrate(context.Background(), db, database.Config{})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	search := repository.NewSearchRepository(db)
+	t.Logf("searching with %T", search)
+	handler := NewEventHandler(service.NewEventService(repository.NewEventRepository(db), search))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	orgA := withOrgPrincipal("u1", "org_a", "user")
+	router.GET("/event/search", orgA, middleware.ResolveTenant(), handler.SearchEvents)
+	router.POST("/event", orgA, middleware.ResolveTenant(), handler.CreateEvent)
+	router.PUT("/event/:id", orgA, middleware.ResolveTenant(), handler.UpdateEvent)
+	router.DELETE("/event/:id", orgA, middleware.ResolveTenant(), handler.DeleteEvent)
+	router.GET("/other/search", withOrgPrincipal("u2", "org_b", "user"), middleware.ResolveTenant(), handler.SearchEvents)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	find := func(path string) []repository.SearchResult {
+		w := send("GET", path, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page repository.Page[repository.SearchResult]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page.Items
+	}
+
+	for _, body := range []string{
+		`{"title":"Team lunch","description":"Pizza after the conference talks","location":"Cafeteria"}`,
+		`{"title":"Go conference","description":"Two days of <talks> about Go","location":"Berlin","tags":["conference","golang"]}`,
+		`{"title":"Board meeting","organizer_contact_info":"board@example.com"}`,
+	} {
+		assert.Equal(t, http.StatusCreated, send("POST", "/event", body).Code)
+	}
+
+	results := find("/event/search?q=conference")
+	assert.Len(t, results, 2)
+	assert.Equal(t, "Go conference", results[0].Event.Title, "title matches rank first")
+	assert.Greater(t, results[0].Rank, results[1].Rank)
+	assert.Contains(t, results[1].Snippet, "<mark>conference</mark>")
+
+	// Every word must match, as a prefix
+	results = find("/event/search?q=conf+berl")
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Go conference", results[0].Event.Title)
+	assert.Len(t, find("/event/search?q=board%40example.com"), 1)
+
+	// Snippets are escaped
+	results = find("/event/search?q=days")
+	assert.Len(t, results, 1)
+	assert.Contains(t, results[0].Snippet, "&lt;talks&gt;")
+
+	// Other organizations don't see the events
+	assert.Empty(t, find("/other/search?q=conference"))
+
+	// The index follows updates and deletes
+	assert.Equal(t, http.StatusOK, send("PUT", "/event/1", `{"title":"Team lunch","description":"Pizza"}`).Code)
+	assert.Len(t, find("/event/search?q=conference"), 1)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/event/2", "").Code)
+	assert.Empty(t, find("/event/search?q=conference"))
+	assert.Len(t, find("/event/search?q=pizza"), 1)
+
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/event/search?q=%20*%20", "").Code)
+}
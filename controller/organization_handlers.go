@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"errors"
+	"gotempl/middleware"
+	"gotempl/service"
+	"gotempl/views/crud"
+	"gotempl/views/layout"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OrganizationHandler struct {
+	Service *service.OrganizationService
+}
+
+func NewOrganizationHandler(service *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{Service: service}
+}
+
+// TenantSwitchHandler godoc
+// @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
+// @Description  Lists every organization so a super admin can pick the tenant the admin pages work on (non-REST endpoint)
+// @Tags         Organization
+// @Produce      html
+// @Success      200  {string}  string  "HTML page content"
+// @Router       /admin/tenant [get]
+// @Notes
+func (h *OrganizationHandler) TenantSwitchHandler(c *gin.Context) {
+	orgs, err := h.Service.GetAllOrganizations(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+
+	layout.Render(c, 200, crud.TenantForm(orgs, middleware.CurrentTenant(c)))
+}
+
+// SwitchTenant godoc
+// @Summary      This is a non-REST endpoint that redirects to an HTML page - not JSON data
+// @Description  Stores the tenant picked by a super admin, an empty org shows every organization (non-REST endpoint)
+// @Tags         Organization
+// @Accept       x-www-form-urlencoded
+// @Param        org  formData  string  false  "Organization ID"
+// @Success      303  {string}  string  "Redirect to the event page"
+// @Failure      404  {string}  string  "Unknown organization"
+// @Router       /admin/tenant [post]
+// @Notes
+func (h *OrganizationHandler) SwitchTenant(c *gin.Context) {
+	org := c.PostForm("org")
+
+	if org == "" {
+		c.SetCookie(middleware.TenantCookie, "", -1, "/", "", false, true)
+		c.Redirect(http.StatusSeeOther, "/admin/event")
+		return
+	}
+
+	if _, err := h.Service.GetOrganization(c.Request.Context(), org); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		}
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(middleware.TenantCookie, org, 0, "/", "", false, true)
+	c.Redirect(http.StatusSeeOther, "/admin/event")
+}
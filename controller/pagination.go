@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"errors"
+	"gotempl/repository"
+	"gotempl/views/crud"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// bindListQuery reads the pagination options and the filter from the query string
+func bindListQuery(c *gin.Context, filter any) (repository.ListOptions, error) {
+	var opts repository.ListOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		return opts, err
+	}
+	if err := c.ShouldBindQuery(filter); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// listError reports bad list parameters as such, and other failures with msg
+func listError(c *gin.Context, err error, msg string) {
+	if errors.Is(err, repository.ErrInvalidListOptions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Error("Error:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
+
+// pagination links the admin pages to the first and the next page, keeping
+// the sort and the filters of the current request
+func pagination[T any](c *gin.Context, page *repository.Page[T]) crud.Pagination {
+	p := crud.Pagination{Shown: len(page.Items), Total: page.Total}
+
+	query := c.Request.URL.Query()
+	if query.Get("cursor") != "" {
+		query.Del("cursor")
+		p.FirstURL = c.Request.URL.Path + "?" + query.Encode()
+	}
+	if page.NextCursor != "" {
+		query.Set("cursor", page.NextCursor)
+		p.NextURL = c.Request.URL.Path + "?" + query.Encode()
+	}
+	return p
+}
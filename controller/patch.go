@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MergePatchType is RFC 7396 JSON Merge Patch, the default of PATCH requests
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is RFC 6902 JSON Patch
+	JSONPatchType = "application/json-patch+json"
+)
+
+var errUnsupportedPatch = errors.New("unsupported patch media type, use " + MergePatchType + " or " + JSONPatchType)
+
+// patchRecord applies the patch in the request body to the JSON of current
+// and decodes the result into target, which should be a zero record
+func patchRecord(c *gin.Context, current any, target any) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+
+	switch c.ContentType() {
+	case MergePatchType, "application/json", "":
+		var patch any
+		if err := json.Unmarshal(body, &patch); err != nil {
+			return err
+		}
+		if _, ok := patch.(map[string]any); !ok {
+			return errors.New("a merge patch must be a JSON object")
+		}
+		doc = mergePatch(doc, patch)
+	case JSONPatchType:
+		var ops []map[string]any
+		if err := json.Unmarshal(body, &ops); err != nil {
+			return err
+		}
+		if doc, err = jsonPatch(doc, ops); err != nil {
+			return err
+		}
+	default:
+		return errUnsupportedPatch
+	}
+
+	if raw, err = json.Marshal(doc); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// patchError reports a patch that can't be applied
+func patchError(c *gin.Context, err error) {
+	if errors.Is(err, errUnsupportedPatch) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patch: " + err.Error()})
+}
+
+// mergePatch applies an RFC 7396 merge patch: members of the patch replace
+// those of the target, objects are merged recursively and null removes a member
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+		} else {
+			targetObj[name] = mergePatch(targetObj[name], value)
+		}
+	}
+	return targetObj
+}
+
+// jsonPatch applies the operations of an RFC 6902 JSON Patch in order, it
+// fails as a whole when one of them does
+func jsonPatch(doc any, ops []map[string]any) (any, error) {
+	for i, op := range ops {
+		var err error
+		if doc, err = applyOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc any, op map[string]any) (any, error) {
+	name, _ := op["op"].(string)
+	path, err := pointerOf(op, "path")
+	if err != nil {
+		return nil, err
+	}
+	value, hasValue := op["value"]
+
+	switch name {
+	case "add", "replace", "test":
+		if !hasValue {
+			return nil, fmt.Errorf("%s needs a value", name)
+		}
+	}
+
+	switch name {
+	case "add":
+		return addValue(doc, path, value)
+	case "remove":
+		return removeValue(doc, path)
+	case "replace":
+		if doc, err = removeValue(doc, path); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	case "test":
+		current, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := pointerOf(op, "from")
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if name == "move" {
+			if isPrefix(from, path) {
+				return nil, errors.New("can't move a value into itself")
+			}
+			if doc, err = removeValue(doc, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = deepCopy(value); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	}
+	return nil, fmt.Errorf("unknown op %q", name)
+}
+
+// pointerOf parses the JSON Pointer of an operation member into its tokens
+func pointerOf(op map[string]any, member string) ([]string, error) {
+	pointer, ok := op[member].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing %s", member)
+	}
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	return len(prefix) < len(path) && reflect.DeepEqual(prefix, path[:len(prefix)])
+}
+
+func getValue(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("no member %q", token)
+		}
+	}
+	return doc, nil
+}
+
+// atParent calls change with the container holding the last token of path,
+// and stores the container it returns in place of the old one
+func atParent(doc any, path []string, change func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+	child, err := getValue(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = atParent(child, path[1:], change)
+	if err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case []any:
+		i, _ := arrayIndex(path[0], len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+func addValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return atParent(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], append([]any{value}, node[i:]...)...), nil
+		}
+		return nil, fmt.Errorf("can't add %q to a scalar", token)
+	})
+}
+
+func removeValue(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("can't remove the whole record")
+	}
+	return atParent(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("no member %q", token)
+	})
+}
+
+// arrayIndex parses an array index token, which must be at most last
+func arrayIndex(token string, last int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > last || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func deepCopy(value any) (any, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var clone any
+	return clone, json.Unmarshal(raw, &clone)
+}
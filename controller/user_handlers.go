@@ -1,164 +1,287 @@
 package controller
 
 import (
-	"errors"
+	"fmt"
+	"gotempl/middleware"
 	"gotempl/model"
+	"gotempl/repository"
 	"gotempl/service"
 	"gotempl/views/crud"
 	"gotempl/views/layout"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type UserHandler struct {
 	Service *service.UserService
+	// CRUD serves the REST routes of users, the methods below document them
+	CRUD *CRUDHandler[model.User, string, repository.UserFilter]
 }
 
-func NewUserHandler(service *service.UserService) *UserHandler {
-	return &UserHandler{Service: service}
+func NewUserHandler(users *service.UserService) *UserHandler {
+	h := &UserHandler{Service: users}
+	h.CRUD = &CRUDHandler[model.User, string, repository.UserFilter]{
+		Name: "User",
+		Service: func(c *gin.Context) *service.CRUDService[model.User, string] {
+			return h.scoped(c).CRUDService
+		},
+		ParseID: func(param string) (string, error) {
+			return param, nil
+		},
+		Prepare: checkRole,
+	}
+	return h
+}
+
+// checkRole stops callers from changing users whose role is above their own,
+// before or after the change: only super admins grant or remove superadmin
+func checkRole(c *gin.Context, user, existing *model.User) error {
+	rank := middleware.RoleRank(middleware.CurrentRole(c))
+	if existing != nil && middleware.RoleRank(existing.Role) > rank {
+		return fmt.Errorf("%w: the user's role is above yours", errForbidden)
+	}
+	if middleware.RoleRank(user.Role) > rank {
+		return fmt.Errorf("%w: the role is above yours", errForbidden)
+	}
+	return nil
+}
+
+// scoped returns the user service limited to the members of the caller's
+// organization, whose changes are audited as made by the caller. Only super
+// admins change the members other organizations share.
+func (h *UserHandler) scoped(c *gin.Context) *service.UserService {
+	users := tenantUsers(c, h.Service.As(middleware.CurrentActor(c)))
+	tenant := middleware.CurrentTenant(c)
+	if tenant == middleware.AllTenants || middleware.CurrentRole(c) == middleware.RoleSuperAdmin {
+		return users
+	}
+	return users.ExclusiveTo(tenant)
+}
+
+// tenantUsers limits users to the members of the caller's organization
+func tenantUsers(c *gin.Context, users *service.UserService) *service.UserService {
+	tenant := middleware.CurrentTenant(c)
+	if tenant == middleware.AllTenants {
+		return users
+	}
+	return users.ForOrg(tenant)
 }
 
 // CreateUser godoc
 // @Summary      Create a new user
-// @Description  Create a new user with the provided information
+// @Description  Create a new user with the provided information. Callers can't grant a role above their own or change users whose role is above theirs.
 // @Tags         User
 // @Accept       json
 // @Produce      json
 // @Param        user  body      model.User  true  "User information"
 // @Success      201   {object}  model.User
 // @Failure      400   {object}  object
+// @Failure      403   {object}  object
 // @Failure      500   {object}  object
+// @Security     BearerAuth
 // @Router       /user [post]
 func (h *UserHandler) CreateUser(c *gin.Context) {
-	var user model.User
-
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := h.Service.CreateUser(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create user"})
-		return
-	}
-
-	c.JSON(http.StatusCreated, user)
+	h.CRUD.Create(c)
 }
 
 // GetAllUsers godoc
-// @Summary      Get all users
-// @Description  Retrieve a list of all users
+// @Summary      List users
+// @Description  Retrieve a page of users. Pass next_cursor back as cursor, with the same sort and filters, to read the next page.
 // @Tags         User
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   model.User
+// @Param        limit   query     int     false  "Page size, at most 100"  default(20)
+// @Param        cursor  query     string  false  "next_cursor of the previous page"
+// @Param        sort    query     string  false  "uid, username or role, prefixed with - for descending order"  default(username)
+// @Param        role    query     string  false  "Only users with this role"
+// @Success      200  {object}  repository.Page[model.User]
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
 // @Router       /user [get]
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.Service.GetAllUsers()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
-		return
-	}
-
-	c.JSON(http.StatusOK, users)
+	h.CRUD.List(c)
 }
 
 // GetUser godoc
 // @Summary      Get a user by ID
-// @Description  Retrieve a user's information using their ID
+// @Description  Retrieve a user's information using their ID. The ETag header is its version, to send back in If-Match when changing it.
 // @Tags         User
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "User ID"
+// @Param        If-None-Match  header  string  false  "ETag of a cached version, answered with 304 while it is current"
 // @Success      200  {object}  model.User
+// @Success      304  {object}  nil
 // @Failure      400  {object}  object
 // @Failure      404  {object}  object
+// @Security     BearerAuth
 // @Router       /user/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
-	id := c.Param("id")
-
-	user, err := h.Service.GetUserByID(id)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
-		}
-		return
-	}
-
-	c.JSON(http.StatusOK, user)
+	h.CRUD.Get(c)
 }
 
 // UpdateUser godoc
 // @Summary      Update a user
-// @Description  Update a user's information in the system
+// @Description  Update a user's information in the system. Callers can't grant a role above their own or change users whose role is above theirs. Only super admins change users who also belong to other organizations.
 // @Tags         User
 // @Accept       json
 // @Produce      json
 // @Param        id    path      string     true  "User ID"
 // @Param        user  body      model.User true  "Updated user information"
+// @Param        If-Match  header  string  false  "ETag of the version the change is based on"
 // @Success      200   {object}  model.User
 // @Failure      400   {object}  object
+// @Failure      403   {object}  object
+// @Failure      404   {object}  object
+// @Failure      412   {object}  object  "The user changed, the body has its current version"
 // @Failure      500   {object}  object
+// @Security     BearerAuth
 // @Router       /user/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
-	id := c.Param("id")
-
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	user.Uid = string(id)
-	if err := h.Service.UpdateUser(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
-		return
-	}
+	h.CRUD.Update(c)
+}
 
-	c.JSON(http.StatusOK, user)
+// PatchUser godoc
+// @Summary      Patch a user
+// @Description  Change some fields of a user. The body is a JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json): its members replace those of the user and null resets them. A JSON Patch (RFC 6902, application/json-patch+json) is accepted too. uid and the trash fields can't be changed. Callers can't grant a role above their own or change users whose role is above theirs. Only super admins change users who also belong to other organizations.
+// @Tags         User
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id     path      string  true  "User ID"
+// @Param        patch  body      object  true  "Merge patch, e.g. {\"role\": \"admin\"}"
+// @Param        If-Match  header  string  false  "ETag of the version the change is based on"
+// @Success      200   {object}  model.User
+// @Failure      400   {object}  object
+// @Failure      403   {object}  object
+// @Failure      404   {object}  object
+// @Failure      412   {object}  object  "The user changed, the body has its current version"
+// @Failure      415   {object}  object
+// @Failure      500   {object}  object
+// @Security     BearerAuth
+// @Router       /user/{id} [patch]
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	h.CRUD.Patch(c)
 }
 
 // DeleteUser godoc
 // @Summary      Delete a user
-// @Description  Delete a user from the system using their ID.
+// @Description  Move a user to the trash using their ID, which revokes their access. It can be restored until it is purged. Only super admins change users who also belong to other organizations.
 // @Tags         User
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "User ID"
+// @Param        If-Match  header  string  false  "ETag of the version the deletion is based on"
 // @Success      204  {object}  nil
+// @Failure      403  {object}  object
+// @Failure      404  {object}  object
+// @Failure      412  {object}  object  "The user changed, the body has its current version"
 // @Failure      500  {object}  object
+// @Security     BearerAuth
 // @Router       /user/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
-	id := c.Param("id")
+	h.CRUD.Delete(c)
+}
 
-	if err := h.Service.DeleteUser(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
-		return
-	}
+// GetTrash godoc
+// @Summary      List trashed users
+// @Description  Retrieve a page of the users in the trash, by default the last deleted first
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Param        limit   query     int     false  "Page size, at most 100"  default(20)
+// @Param        cursor  query     string  false  "next_cursor of the previous page"
+// @Param        sort    query     string  false  "deleted_at or a sort of the user list, prefixed with - for descending order"  default(-deleted_at)
+// @Success      200  {object}  repository.Page[model.User]
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /user/trash [get]
+func (h *UserHandler) GetTrash(c *gin.Context) {
+	h.CRUD.Trash(c)
+}
 
-	c.Status(http.StatusNoContent)
+// RestoreUser godoc
+// @Summary      Restore a user
+// @Description  Take a user out of the trash, which gives their access back. Only super admins change users who also belong to other organizations.
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  model.User
+// @Failure      403  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /user/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	h.CRUD.Restore(c)
+}
+
+// PurgeUser godoc
+// @Summary      Purge a user
+// @Description  Permanently delete a user that is in the trash. Only super admins change users who also belong to other organizations.
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      204  {object}  nil
+// @Failure      403  {object}  object
+// @Failure      404  {object}  object
+// @Failure      500  {object}  object
+// @Security     BearerAuth
+// @Router       /user/{id}/purge [delete]
+func (h *UserHandler) PurgeUser(c *gin.Context) {
+	h.CRUD.Purge(c)
 }
 
 // UserCRUDHandler godoc
 // @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
-// @Description  Fetches all users and renders an HTML page with a CRUD form for user management (non-REST endpoint)
+// @Description  Fetches a page of users and renders an HTML page with a CRUD form for user management (non-REST endpoint)
 // @Tags         User
 // @Produce      html
 // @Success      200  {string}  string  "HTML page content"
 // @Router       /admin/user [get]
 // @Notes
 func (h *UserHandler) UserCRUDHandler(c *gin.Context) {
-	users, err := h.Service.GetAllUsers()
+	var filter repository.UserFilter
+	opts, err := bindListQuery(c, &filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.scoped(c).List(c.Request.Context(), filter, opts)
+	if err != nil {
+		listError(c, err, "Failed to fetch users")
+		return
+	}
+
+	layout.Render(c, 200, crud.UserForm(page.Items, pagination(c, page)))
+}
+
+// UserTrashHandler godoc
+// @Summary      This is a non-REST endpoint that returns an HTML page - not JSON data
+// @Description  Fetches a page of trashed users and renders an HTML page to restore or purge them (non-REST endpoint)
+// @Tags         User
+// @Produce      html
+// @Success      200  {string}  string  "HTML page content"
+// @Router       /admin/user/trash [get]
+func (h *UserHandler) UserTrashHandler(c *gin.Context) {
+	var opts repository.ListOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.scoped(c).ListTrash(c.Request.Context(), opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		listError(c, err, "Failed to fetch users")
 		return
 	}
 
-	//print(users)
-	layout.Render(c, 200, crud.UserForm(users))
+	layout.Render(c, 200, crud.UserTrash(page.Items, pagination(c, page)))
 }
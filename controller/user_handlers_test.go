@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"gotempl/middleware"
 	"gotempl/model"
 	"gotempl/repository"
 	"gotempl/service"
@@ -21,7 +22,7 @@ func setupTestEnvironment(t *testing.T) (*gorm.DB, *UserHandler, *gin.Engine) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	err = db.AutoMigrate(&model.User{})
+	err = db.AutoMigrate(&model.User{}, &model.Organization{}, &model.Membership{})
 	assert.NoError(t, err)
 
 	repo := repository.NewUserRepository(db)
@@ -30,6 +31,7 @@ func setupTestEnvironment(t *testing.T) (*gorm.DB, *UserHandler, *gin.Engine) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(withOrgPrincipal("admin-uid", "", middleware.RoleAdmin))
 	return db, handler, router
 }
 
@@ -115,10 +117,13 @@ func TestGetAllUsers(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var responseUsers []model.User
-	err := json.Unmarshal(w.Body.Bytes(), &responseUsers)
+	var page repository.Page[model.User]
+	err := json.Unmarshal(w.Body.Bytes(), &page)
 	assert.NoError(t, err)
-	assert.Len(t, responseUsers, len(testUsers))
+	assert.Len(t, page.Items, len(testUsers))
+	assert.Equal(t, int64(len(testUsers)), page.Total)
+	assert.Empty(t, page.NextCursor)
+	responseUsers := page.Items
 
 	// Check if the response contains all test users
 	for _, testUser := range testUsers {
@@ -209,6 +214,48 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, updatedUser.Role, dbUser.Role)
 }
 
+func TestUpdateUserConflict(t *testing.T) {
+	db, handler, router := setupTestEnvironment(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	router.GET("/user/:id", handler.GetUser)
+	router.PUT("/user/:id", handler.UpdateUser)
+
+	db.Create(&model.User{Uid: "testuser", Username: "testusername", Role: "user"})
+
+	put := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/user/testuser", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"username":"renamed","role":"user"}`, `"1"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = put(`{"username":"testusername","role":"admin"}`, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"renamed"`)
+
+	req, _ := http.NewRequest("PUT", "/user/nobody", bytes.NewBufferString(`{"username":"nobody","role":"user"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var dbUser model.User
+	assert.NoError(t, db.First(&dbUser, "uid = ?", "testuser").Error)
+	assert.Equal(t, "renamed", dbUser.Username)
+	assert.Equal(t, "user", dbUser.Role)
+	assert.Equal(t, uint64(2), dbUser.Version)
+}
+
 func TestDeleteUser(t *testing.T) {
 	db, handler, router := setupTestEnvironment(t)
 	defer func() {
@@ -238,5 +285,261 @@ func TestDeleteUser(t *testing.T) {
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNoContent, w.Code) // The API returns 204 even if the user doesn't exist
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUserTrash(t *testing.T) {
+	db, handler, router := setupTestEnvironment(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	router.GET("/user/trash", handler.GetTrash)
+	router.DELETE("/user/:id", handler.DeleteUser)
+	router.POST("/user/:id/restore", handler.RestoreUser)
+	router.DELETE("/user/:id/purge", handler.PurgeUser)
+
+	testUser := model.User{Uid: "testuser", Username: "testusername", Role: "user"}
+	db.Create(&testUser)
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("POST", "/user/testuser/restore").Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/user/testuser").Code)
+
+	w := do("GET", "/user/trash")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page repository.Page[model.User]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "testuser", page.Items[0].Uid)
+
+	assert.Equal(t, http.StatusOK, do("POST", "/user/testuser/restore").Code)
+	var dbUser model.User
+	assert.NoError(t, db.First(&dbUser, "uid = ?", testUser.Uid).Error)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/user/testuser").Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/user/testuser/purge").Code)
+	assert.ErrorIs(t, db.Unscoped().First(&dbUser, "uid = ?", testUser.Uid).Error, gorm.ErrRecordNotFound)
+}
+
+func TestPatchUser(t *testing.T) {
+	db, handler, router := setupTestEnvironment(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	router.PATCH("/user/:id", handler.PatchUser)
+
+	db.Create(&model.User{Uid: "testuser", Username: "testusername", Role: "user"})
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/user/testuser", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", MergePatchType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch(`{"role":"admin","uid":"other"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// The merged user is validated as a whole
+	assert.Equal(t, http.StatusBadRequest, patch(`{"role":"root"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch(`{"username":null}`).Code)
+
+	var dbUser model.User
+	assert.NoError(t, db.First(&dbUser, "uid = ?", "testuser").Error)
+	assert.Equal(t, "testusername", dbUser.Username)
+	assert.Equal(t, "admin", dbUser.Role)
+	assert.Equal(t, uint64(2), dbUser.Version)
+}
+
+func TestUsersAreScopedToOrganization(t *testing.T) {
+	db, handler, _ := setupTestEnvironment(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	db.Create(&model.User{Uid: "a1", Username: "alice", Role: "admin"})
+	db.Create(&model.User{Uid: "b1", Username: "bob", Role: "user"})
+	db.Create(&model.User{Uid: "p1", Username: "pat", Role: "user"})
+	db.Create(&model.Organization{ID: "org_a", Name: "Acme"})
+	db.Create(&model.Organization{ID: "org_b", Name: "Beta"})
+	db.Create(&model.Membership{UserUid: "a1", OrgID: "org_a"})
+	db.Create(&model.Membership{UserUid: "b1", OrgID: "org_b"})
+
+	routerAs := func(subject, orgID, role string) *gin.Engine {
+		router := gin.New()
+		group := router.Group("/", withOrgPrincipal(subject, orgID, role), middleware.ResolveTenant())
+		handler.CRUD.Register(group.Group("/user"), Routes{})
+		return router
+	}
+	do := func(router *gin.Engine, method, url, body string, header map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listUids := func(router *gin.Engine, header map[string]string) []string {
+		w := do(router, "GET", "/user/", "", header)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page repository.Page[model.User]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		uids := []string{}
+		for _, user := range page.Items {
+			uids = append(uids, user.Uid)
+		}
+		return uids
+	}
+
+	t.Run("Admin only sees the members of its organization", func(t *testing.T) {
+		router := routerAs("a1", "org_a", middleware.RoleAdmin)
+		assert.ElementsMatch(t, []string{"a1"}, listUids(router, nil))
+
+		assert.Equal(t, http.StatusNotFound, do(router, "GET", "/user/b1", "", nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(router, "PUT", "/user/b1", `{"username":"bob","role":"admin"}`, nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(router, "DELETE", "/user/p1", "", nil).Code)
+		var bob model.User
+		assert.NoError(t, db.First(&bob, "uid = ?", "b1").Error)
+		assert.Equal(t, "user", bob.Role)
+
+		// Created users join the organization
+		w := do(router, "POST", "/user/", `{"uid":"a2","username":"amy","role":"user"}`, nil)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.ElementsMatch(t, []string{"a1", "a2"}, listUids(router, nil))
+	})
+
+	t.Run("Users without organization only see each other", func(t *testing.T) {
+		router := routerAs("p1", "", middleware.RoleAdmin)
+		assert.ElementsMatch(t, []string{"p1"}, listUids(router, nil))
+		assert.Equal(t, http.StatusNotFound, do(router, "GET", "/user/a1", "", nil).Code)
+	})
+
+	t.Run("Super admin sees every user or the members of the organization picked", func(t *testing.T) {
+		router := routerAs("root", "", middleware.RoleSuperAdmin)
+		assert.ElementsMatch(t, []string{"a1", "a2", "b1", "p1"}, listUids(router, nil))
+		assert.ElementsMatch(t, []string{"b1"}, listUids(router, map[string]string{"X-Tenant": "org_b"}))
+	})
+}
+
+func TestUserRoleEscalation(t *testing.T) {
+	db, handler, _ := setupTestEnvironment(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	db.Create(&model.User{Uid: "a1", Username: "alice", Role: middleware.RoleAdmin})
+	db.Create(&model.User{Uid: "u1", Username: "uma", Role: middleware.RoleUser})
+	db.Create(&model.User{Uid: "s1", Username: "sam", Role: middleware.RoleSuperAdmin})
+
+	routerAs := func(subject, role string) *gin.Engine {
+		router := gin.New()
+		group := router.Group("/", withOrgPrincipal(subject, "", role), middleware.ResolveTenant())
+		handler.CRUD.Register(group.Group("/user"), Routes{})
+		return router
+	}
+	do := func(router *gin.Engine, method, url, body string) int {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	role := func(uid string) string {
+		var user model.User
+		assert.NoError(t, db.First(&user, "uid = ?", uid).Error)
+		return user.Role
+	}
+
+	t.Run("Admin can't grant or remove superadmin", func(t *testing.T) {
+		router := routerAs("a1", middleware.RoleAdmin)
+		assert.Equal(t, http.StatusForbidden, do(router, "PATCH", "/user/a1", `{"role":"superadmin"}`))
+		assert.Equal(t, http.StatusForbidden, do(router, "PUT", "/user/u1", `{"username":"uma","role":"superadmin"}`))
+		assert.Equal(t, http.StatusForbidden, do(router, "POST", "/user/", `{"uid":"x1","username":"xavier","role":"superadmin"}`))
+		assert.Equal(t, http.StatusForbidden, do(router, "PATCH", "/user/s1", `{"role":"user"}`))
+		assert.Equal(t, middleware.RoleAdmin, role("a1"))
+		assert.Equal(t, middleware.RoleUser, role("u1"))
+		assert.Equal(t, middleware.RoleSuperAdmin, role("s1"))
+
+		// Up to their own role
+		assert.Equal(t, http.StatusOK, do(router, "PATCH", "/user/u1", `{"role":"admin"}`))
+		assert.Equal(t, middleware.RoleAdmin, role("u1"))
+	})
+
+	t.Run("Super admin can grant superadmin", func(t *testing.T) {
+		router := routerAs("s1", middleware.RoleSuperAdmin)
+		assert.Equal(t, http.StatusOK, do(router, "PATCH", "/user/u1", `{"role":"superadmin"}`))
+		assert.Equal(t, middleware.RoleSuperAdmin, role("u1"))
+	})
+}
+
+func TestSharedUsersAreChangedBySuperAdmins(t *testing.T) {
+	db, handler, _ := setupTestEnvironment(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	db.Create(&model.User{Uid: "a1", Username: "alice", Role: middleware.RoleUser})
+	db.Create(&model.User{Uid: "s1", Username: "sue", Role: middleware.RoleUser})
+	db.Create(&model.Organization{ID: "org_a", Name: "Acme"})
+	db.Create(&model.Organization{ID: "org_b", Name: "Beta"})
+	db.Create(&model.Membership{UserUid: "a1", OrgID: "org_a"})
+	db.Create(&model.Membership{UserUid: "s1", OrgID: "org_a"})
+	db.Create(&model.Membership{UserUid: "s1", OrgID: "org_b"})
+
+	routerAs := func(subject, orgID, role string) *gin.Engine {
+		router := gin.New()
+		group := router.Group("/", withOrgPrincipal(subject, orgID, role), middleware.ResolveTenant())
+		handler.CRUD.Register(group.Group("/user"), Routes{})
+		group.POST("/user/:id/restore", handler.RestoreUser)
+		group.DELETE("/user/:id/purge", handler.PurgeUser)
+		return router
+	}
+	do := func(router *gin.Engine, method, url, body string) int {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	user := func(uid string) model.User {
+		var user model.User
+		assert.NoError(t, db.Unscoped().First(&user, "uid = ?", uid).Error)
+		return user
+	}
+
+	// The role, the name and the trash would change in org_b too
+	admin := routerAs("admin-uid", "org_a", middleware.RoleAdmin)
+	assert.Equal(t, http.StatusForbidden, do(admin, "PATCH", "/user/s1", `{"role":"admin"}`))
+	assert.Equal(t, http.StatusForbidden, do(admin, "PUT", "/user/s1", `{"username":"susan","role":"user"}`))
+	assert.Equal(t, http.StatusForbidden, do(admin, "DELETE", "/user/s1", ""))
+	assert.Equal(t, "sue", user("s1").Username)
+	assert.Equal(t, middleware.RoleUser, user("s1").Role)
+	assert.False(t, user("s1").DeletedAt.Valid)
+
+	// Members of the organization alone are still theirs to manage
+	assert.Equal(t, http.StatusOK, do(admin, "PATCH", "/user/a1", `{"role":"admin"}`))
+
+	root := routerAs("root", "org_a", middleware.RoleSuperAdmin)
+	assert.Equal(t, http.StatusOK, do(root, "PATCH", "/user/s1", `{"role":"admin"}`))
+	assert.Equal(t, http.StatusNoContent, do(root, "DELETE", "/user/s1", ""))
+	assert.Equal(t, http.StatusForbidden, do(admin, "POST", "/user/s1/restore", ""))
+	assert.Equal(t, http.StatusForbidden, do(admin, "DELETE", "/user/s1/purge", ""))
+	assert.Equal(t, http.StatusOK, do(root, "POST", "/user/s1/restore", ""))
 }
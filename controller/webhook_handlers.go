@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"gotempl/middleware"
+	"gotempl/service"
+	"net/http"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// webhookActor is recorded as deleted_by and in the audit log for changes
+// made by the identity provider
+const webhookActor = "clerk"
+
+type WebhookHandler struct {
+	Service *service.UserService
+}
+
+func NewWebhookHandler(service *service.UserService) *WebhookHandler {
+	return &WebhookHandler{Service: service}
+}
+
+// clerkEvent is the envelope of every Clerk webhook
+type clerkEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ClerkWebhook godoc
+// @Summary      Receive Clerk webhooks
+// @Description  Applies user.created, user.updated and user.deleted events to the local users table. Requests must carry valid Svix signature headers.
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        svix-id         header    string  true  "Message ID"
+// @Param        svix-timestamp  header    string  true  "Unix timestamp of the message"
+// @Param        svix-signature  header    string  true  "Space separated list of v1 signatures"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  object
+// @Failure      401  {object}  object
+// @Failure      500  {object}  object
+// @Router       /webhooks/clerk [post]
+func (h *WebhookHandler) ClerkWebhook(c *gin.Context) {
+	var event clerkEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users := h.Service.As(service.Actor{Subject: webhookActor, RequestID: middleware.CurrentRequestID(c), IP: c.ClientIP()})
+	switch event.Type {
+	case "user.created", "user.updated":
+		var usr clerk.User
+		if err := json.Unmarshal(event.Data, &usr); err != nil || usr.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user payload"})
+			return
+		}
+
+		_, err := users.UpsertUser(c.Request.Context(), usr.ID, middleware.ClerkUsername(&usr))
+		if errors.Is(err, service.ErrUserDeleted) {
+			log.Info("Not synchronizing trashed user ", usr.ID)
+			break
+		}
+		if err != nil {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to synchronize user"})
+			return
+		}
+	case "user.deleted":
+		var deleted clerk.DeletedResource
+		if err := json.Unmarshal(event.Data, &deleted); err != nil || deleted.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user payload"})
+			return
+		}
+
+		_, err := users.Get(c.Request.Context(), deleted.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err == nil {
+			err = users.Delete(c.Request.Context(), deleted.ID, 0, webhookActor)
+		}
+		if err != nil {
+			log.Error("Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+			return
+		}
+	default:
+		// Other events are acknowledged so the provider doesn't retry them
+		log.Info("Ignoring webhook event ", event.Type)
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"gotempl/middleware"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testWebhookSecret = "whsec_" + base64.StdEncoding.EncodeToString([]byte("test-webhook-secret"))
+
+func setupWebhookEnvironment(t *testing.T) (*gorm.DB, *middleware.SvixVerifier, *gin.Engine) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&model.User{})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	verifier, err := middleware.NewSvixVerifier(testWebhookSecret)
+	assert.NoError(t, err)
+
+	handler := NewWebhookHandler(service.NewUserService(repository.NewUserRepository(db)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/webhooks/clerk", middleware.VerifyWebhook(verifier), handler.ClerkWebhook)
+	return db, verifier, router
+}
+
+func signedWebhookRequest(verifier *middleware.SvixVerifier, body string, sentAt time.Time) *http.Request {
+	id := "msg_" + strconv.FormatInt(sentAt.UnixNano(), 10)
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	req, _ := http.NewRequest("POST", "/webhooks/clerk", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("svix-id", id)
+	req.Header.Set("svix-timestamp", timestamp)
+	req.Header.Set("svix-signature", "v1,"+verifier.Sign(id, timestamp, []byte(body)))
+	return req
+}
+
+func TestClerkWebhookUserLifecycle(t *testing.T) {
+	db, verifier, router := setupWebhookEnvironment(t)
+
+	created := `{"type":"user.created","data":{"id":"user_1","username":null,"primary_email_address_id":"idn_2",
+		"email_addresses":[{"id":"idn_1","email_address":"other@example.com"},{"id":"idn_2","email_address":"jane@example.com"}]}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedWebhookRequest(verifier, created, time.Now()))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var dbUser model.User
+	err := db.First(&dbUser, "uid = ?", "user_1").Error
+	assert.NoError(t, err)
+	assert.Equal(t, "jane@example.com", dbUser.Username)
+	assert.Equal(t, "user", dbUser.Role)
+
+	db.Model(&dbUser).Update("role", "admin")
+
+	updated := `{"type":"user.updated","data":{"id":"user_1","username":"jane"}}`
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, signedWebhookRequest(verifier, updated, time.Now()))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	err = db.First(&dbUser, "uid = ?", "user_1").Error
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", dbUser.Username)
+	assert.Equal(t, "admin", dbUser.Role)
+
+	deleted := `{"type":"user.deleted","data":{"id":"user_1","deleted":true,"object":"user"}}`
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, signedWebhookRequest(verifier, deleted, time.Now()))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	err = db.First(&dbUser, "uid = ?", "user_1").Error
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	// Deleting a user we never knew about is acknowledged
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, signedWebhookRequest(verifier, deleted, time.Now()))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestClerkWebhookRejectsInvalidSignatures(t *testing.T) {
+	db, verifier, router := setupWebhookEnvironment(t)
+	body := `{"type":"user.created","data":{"id":"user_1","username":"jane"}}`
+
+	t.Run("Missing headers", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/webhooks/clerk", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Tampered body", func(t *testing.T) {
+		req := signedWebhookRequest(verifier, body, time.Now())
+		req.Body = httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"type":"user.created","data":{"id":"user_2","username":"mallory"}}`)).Body
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Replayed outside the tolerance window", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, signedWebhookRequest(verifier, body, time.Now().Add(-10*time.Minute)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Signed with another secret", func(t *testing.T) {
+		other, err := middleware.NewSvixVerifier("whsec_" + base64.StdEncoding.EncodeToString([]byte("other")))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, signedWebhookRequest(other, body, time.Now()))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	var count int64
+	db.Model(&model.User{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
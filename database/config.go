@@ -0,0 +1,151 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	DriverMySQL        = "mysql"
+	DriverPostgres     = "postgres"
+	DriverSQLite       = "sqlite"
+	DriverSQLiteMemory = "sqlite-memory"
+)
+
+// Config describes the database connection, see ConfigFromEnv for the
+// environment variables setting each field
+type Config struct {
+	Driver string
+
+	// Server databases (mysql, postgres)
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Name     string
+	SSLMode  string
+
+	// Path of the SQLite database file
+	Path string
+
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	LogLevel        logger.LogLevel
+
+	// LegacyTimeZone is the time zone MySQL times were written in before they
+	// were stored in UTC, see the add_event_time_zones migration
+	LegacyTimeZone *time.Location
+
+	// MigrateOnStart applies the pending migrations when the server starts
+	MigrateOnStart bool
+}
+
+// ConfigFromEnv reads the DB_* environment variables. Only DB_DRIVER and the
+// settings of the selected driver are needed, the rest have defaults.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Driver:   strings.ToLower(envOrDefault("DB_DRIVER", DriverMySQL)),
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Host:     os.Getenv("DB_HOST"),
+		Port:     os.Getenv("DB_PORT"),
+		Name:     os.Getenv("DB_NAME"),
+		SSLMode:  envOrDefault("DB_SSLMODE", "disable"),
+		Path:     envOrDefault("DB_PATH", "gotempl.db"),
+	}
+
+	var err error
+	if cfg.MaxIdleConns, err = envInt("DB_MAX_IDLE_CONNS", 10); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxOpenConns, err = envInt("DB_MAX_OPEN_CONNS", 100); err != nil {
+		return cfg, err
+	}
+	if cfg.ConnMaxLifetime, err = time.ParseDuration(envOrDefault("DB_CONN_MAX_LIFETIME", "1h")); err != nil {
+		return cfg, fmt.Errorf("invalid DB_CONN_MAX_LIFETIME: %v", err)
+	}
+	if cfg.MigrateOnStart, err = strconv.ParseBool(envOrDefault("DB_MIGRATE_ON_START", "true")); err != nil {
+		return cfg, fmt.Errorf("invalid DB_MIGRATE_ON_START: %v", err)
+	}
+	if cfg.LogLevel, err = ParseLogLevel(envOrDefault("DB_LOG_LEVEL", "info")); err != nil {
+		return cfg, err
+	}
+	if cfg.LegacyTimeZone, err = time.LoadLocation(envOrDefault("DB_LEGACY_TIME_ZONE", "UTC")); err != nil {
+		return cfg, fmt.Errorf("invalid DB_LEGACY_TIME_ZONE: %v", err)
+	}
+	return cfg, nil
+}
+
+// Dialector returns the GORM dialector of the configured driver
+func (cfg Config) Dialector() (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
+			cfg.User,
+			cfg.Password,
+			cfg.Host,
+			cfg.Port,
+			cfg.Name)
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
+			cfg.Host,
+			cfg.User,
+			cfg.Password,
+			cfg.Name,
+			cfg.Port,
+			cfg.SSLMode)
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(cfg.Path), nil
+	case DriverSQLiteMemory:
+		return sqlite.Open(":memory:"), nil
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", cfg.Driver)
+	}
+}
+
+// ParseLogLevel converts silent, error, warn or info to a GORM log level
+func ParseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent, nil
+	case "error":
+		return logger.Error, nil
+	case "warn":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	default:
+		return 0, fmt.Errorf("invalid DB_LOG_LEVEL %q", level)
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return n, nil
+}
@@ -1,13 +1,12 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"gotempl/model"
-	"log"
-	"os"
+	"gotempl/database/migrate"
+	"gotempl/database/migrations"
 	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -16,42 +15,77 @@ import (
 // once the resource is updated this can be deleted
 var DB *gorm.DB
 
-func InitDB() *gorm.DB {
-	DB_USER := os.Getenv("DB_USER")
-	DB_PASSWORD := os.Getenv("DB_PASSWORD")
-	DB_HOST := os.Getenv("DB_HOST")
-	DB_PORT := os.Getenv("DB_PORT")
-	DB_NAME := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		DB_USER,
-		DB_PASSWORD,
-		DB_HOST,
-		DB_PORT,
-		DB_NAME)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// InitDB connects to the database configured by the environment and applies
+// the pending migrations unless DB_MIGRATE_ON_START is false
+func InitDB() (*gorm.DB, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MigrateOnStart {
+		if _, err := Migrate(context.Background(), db, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Open connects to the database
+func Open(cfg Config) (*gorm.DB, error) {
+	dialector, err := cfg.Dialector()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(cfg.LogLevel),
+		// Times are stored in UTC, SQLite compares them as text
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("Failed to get database instance:", err)
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	if cfg.Driver == DriverSQLiteMemory {
+		// Every connection to :memory: is a new empty database, keep a single one open
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	} else {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
 
-	// Auto Migrate the schema
-	err = db.AutoMigrate(&model.Event{}, &model.User{})
+	return db, nil
+}
+
+// NewMigrator returns a migrator running the application migrations with the
+// settings of cfg
+func NewMigrator(db *gorm.DB, cfg Config) (*migrate.Migrator, error) {
+	return migrate.New(db, migrations.All(migrations.Options{LegacyTimeZone: cfg.LegacyTimeZone}))
+}
+
+// Migrate applies the pending migrations and returns how many ran
+func Migrate(ctx context.Context, db *gorm.DB, cfg Config) (int, error) {
+	migrator, err := NewMigrator(db, cfg)
 	if err != nil {
-		log.Fatal("Failed to auto migrate:", err)
+		return 0, err
 	}
-
-	return db
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return applied, fmt.Errorf("failed to migrate: %w", err)
+	}
+	return applied, nil
 }
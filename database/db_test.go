@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"gotempl/database/migrations"
+	"gotempl/model"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_DRIVER", "SQLite")
+	t.Setenv("DB_PATH", "/tmp/test.db")
+	t.Setenv("DB_MAX_OPEN_CONNS", "4")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "5m")
+	t.Setenv("DB_LOG_LEVEL", "warn")
+
+	cfg, err := ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, DriverSQLite, cfg.Driver)
+	assert.Equal(t, "/tmp/test.db", cfg.Path)
+	assert.Equal(t, 10, cfg.MaxIdleConns)
+	assert.Equal(t, 4, cfg.MaxOpenConns)
+	assert.Equal(t, 5*time.Minute, cfg.ConnMaxLifetime)
+	assert.Equal(t, logger.Warn, cfg.LogLevel)
+	assert.Equal(t, time.UTC, cfg.LegacyTimeZone)
+
+	t.Setenv("DB_LEGACY_TIME_ZONE", "Europe/Paris")
+	cfg, err = ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Paris", cfg.LegacyTimeZone.String())
+
+	t.Setenv("DB_LEGACY_TIME_ZONE", "Mars/Olympus")
+	_, err = ConfigFromEnv()
+	assert.ErrorContains(t, err, "DB_LEGACY_TIME_ZONE")
+	t.Setenv("DB_LEGACY_TIME_ZONE", "")
+
+	t.Setenv("DB_MAX_IDLE_CONNS", "many")
+	_, err = ConfigFromEnv()
+	assert.ErrorContains(t, err, "DB_MAX_IDLE_CONNS")
+
+	t.Setenv("DB_MAX_IDLE_CONNS", "")
+	t.Setenv("DB_LOG_LEVEL", "verbose")
+	_, err = ConfigFromEnv()
+	assert.ErrorContains(t, err, "DB_LOG_LEVEL")
+}
+
+func TestOpenAndMigrate(t *testing.T) {
+	_, err := Open(Config{Driver: "oracle"})
+	assert.ErrorContains(t, err, "unknown DB_DRIVER")
+
+	db, err := Open(Config{Driver: DriverSQLiteMemory, LogLevel: logger.Silent})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	applied, err := Migrate(context.Background(), db, Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations.All(migrations.Options{})), applied)
+
+	// The migrations must produce the schema the models expect
+	for _, value := range []any{&model.Event{}, &model.User{}, &model.APIToken{}, &model.Organization{}, &model.Membership{}, &model.AuditEntry{}, &model.Attendance{}} {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(value))
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(value, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+
+	// The schema must survive across queries on the single in-memory connection
+	assert.NoError(t, db.Create(&model.User{Uid: "uid", Username: "user", Role: "user"}).Error)
+	var count int64
+	assert.NoError(t, db.Model(&model.User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// schema lists the tables, indexes and columns of a SQLite database
+func schema(t *testing.T, db *gorm.DB) []string {
+	var items []string
+	assert.NoError(t, db.Raw(`SELECT type || ' ' || name || ': ' || COALESCE(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name NOT LIKE 'schema_migrations%' ORDER BY type, name`).Scan(&items).Error)
+	for i, item := range items {
+		items[i] = sortConstraints(item)
+	}
+	return items
+}
+
+// sortConstraints orders the constraints closing a CREATE TABLE statement, GORM
+// writes the foreign keys of a table in no particular order
+func sortConstraints(sql string) string {
+	start := strings.Index(sql, ",CONSTRAINT ")
+	if start < 0 || !strings.HasSuffix(sql, ")") {
+		return sql
+	}
+	constraints := strings.Split(sql[start+1:len(sql)-1], ",CONSTRAINT ")
+	constraints[0] = strings.TrimPrefix(constraints[0], "CONSTRAINT ")
+	sort.Strings(constraints)
+	return sql[:start] + ",CONSTRAINT " + strings.Join(constraints, ",CONSTRAINT ") + ")"
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	db, err := Open(Config{Driver: DriverSQLiteMemory, LogLevel: logger.Silent})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	migrator, err := NewMigrator(db, Config{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	migrated := schema(t, db)
+
+	// Every migration is reverted, one at a time
+	for range migrations.All(migrations.Options{}) {
+		reverted, err := migrator.Down(ctx, 1)
+		if !assert.NoError(t, err) || !assert.Equal(t, 1, reverted) {
+			return
+		}
+	}
+	assert.Empty(t, schema(t, db))
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations.All(migrations.Options{})), applied)
+	assert.Equal(t, migrated, schema(t, db))
+}
+
+// stepsSince is how many migrations to roll back to run version again
+func stepsSince(version string) int {
+	steps := 0
+	for _, migration := range migrations.All(migrations.Options{}) {
+		if migration.Version >= version {
+			steps++
+		}
+	}
+	return steps
+}
+
+func TestNormalizeEventLists(t *testing.T) {
+	db, err := Open(Config{Driver: DriverSQLiteMemory, LogLevel: logger.Silent})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	migrator, err := NewMigrator(db, Config{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	_, err = migrator.Down(ctx, stepsSince("20241107000000"))
+	assert.NoError(t, err)
+
+	// Lists saved as text before they were checked
+	rows := [][2]any{
+		{nil, `Go, golang,, Meetup Night`},
+		{`["https://example.com/a.png"]`, `"[\"go\",\"GO\"]"`},
+		{"", `[]`},
+	}
+	for _, row := range rows {
+		assert.NoError(t, db.Exec("INSERT INTO events (title, created_by, images, tags) VALUES ('Event', 'uid', ?, ?)", row[0], row[1]).Error)
+	}
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+
+	var events []model.Event
+	assert.NoError(t, db.Order("id").Find(&events).Error)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, model.StringList{"go", "golang", "meetup-night"}, events[0].Tags)
+		assert.Empty(t, events[0].Images)
+		assert.Equal(t, model.StringList{"https://example.com/a.png"}, events[1].Images)
+		assert.Equal(t, model.StringList{"go"}, events[1].Tags)
+		assert.Empty(t, events[2].Tags)
+	}
+}
+
+func TestEventTimesMoveToUTC(t *testing.T) {
+	db, err := Open(Config{Driver: DriverSQLiteMemory, LogLevel: logger.Silent})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	migrator, err := NewMigrator(db, Config{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	_, err = migrator.Down(ctx, stepsSince("20241110000000"))
+	assert.NoError(t, err)
+
+	// SQLite compares the times as text, whatever their offset
+	assert.NoError(t, db.Exec("INSERT INTO events (title, created_by, start_time, end_time) VALUES ('Paris', 'uid', '2024-11-05 18:00:00+01:00', '2024-11-05 19:30:00+01:00')").Error)
+	assert.NoError(t, db.Exec("INSERT INTO events (title, created_by, start_time) VALUES ('London', 'uid', '2024-11-05 17:30:00+00:00')").Error)
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+
+	var times []string
+	assert.NoError(t, db.Raw("SELECT CAST(start_time AS TEXT) FROM events ORDER BY start_time").Scan(&times).Error)
+	assert.Equal(t, []string{"2024-11-05 17:00:00+00:00", "2024-11-05 17:30:00+00:00"}, times)
+
+	var event model.Event
+	assert.NoError(t, db.First(&event, "title = ?", "Paris").Error)
+	assert.Equal(t, "UTC", event.TimeZone)
+	if assert.NotNil(t, event.EndTime) {
+		assert.True(t, event.EndTime.Equal(time.Date(2024, 11, 5, 18, 30, 0, 0, time.UTC)))
+	}
+}
@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+const migrationTemplate = `package migrations
+
+import (
+	"gotempl/database/migrate"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(migrate.Migration{
+		Version: "%s",
+		Name:    "%s",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
+
+// Create writes an empty migration named after the current time to dir and
+// returns the path of the new file
+func Create(dir, name string, now time.Time) (string, error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("migration name is empty")
+	}
+
+	version := now.UTC().Format("20060102150405")
+	path := filepath.Join(dir, version+"_"+name+".go")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, migrationTemplate, version, name); err != nil {
+		return "", err
+	}
+	return path, nil
+}
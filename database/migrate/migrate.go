@@ -0,0 +1,317 @@
+// Package migrate applies versioned schema migrations and records them in the
+// schema_migrations table
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change. Versions are UTC timestamps
+// (20060102150405) so they sort in the order the migrations were written.
+type Migration struct {
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey;type:varchar(14)"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaLock is held while migrations run, its single row is the lock
+type schemaLock struct {
+	ID       uint      `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string    `gorm:"type:varchar(255);not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (schemaLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Status describes a migration known to the binary or recorded in the database
+type Status struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+	// Missing is set for applied migrations the binary doesn't know about
+	Missing bool
+}
+
+var (
+	ErrLocked          = errors.New("migrations are locked by another process")
+	ErrNoDownMigration = errors.New("migration can't be reverted")
+)
+
+// Migrator runs migrations against a database
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+
+	// LockTimeout is how long to wait for another process to finish migrating
+	LockTimeout time.Duration
+	// StaleLockAfter releases locks left behind by crashed processes
+	StaleLockAfter time.Duration
+	// Heartbeat is how often the lock is refreshed while migrations run, so
+	// long migrations don't look stale. It must be well below StaleLockAfter.
+	Heartbeat time.Duration
+}
+
+func New(db *gorm.DB, migrations []Migration) (*Migrator, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return strings.Compare(a.Version, b.Version)
+	})
+	for i, m := range sorted {
+		if m.Version == "" || m.Up == nil {
+			return nil, fmt.Errorf("migration %q needs a version and an Up function", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %s", m.Version)
+		}
+	}
+
+	return &Migrator{
+		DB:             db,
+		Migrations:     sorted,
+		LockTimeout:    time.Minute,
+		StaleLockAfter: 15 * time.Minute,
+		Heartbeat:      time.Minute,
+	}, nil
+}
+
+// Up applies every pending migration in version order and returns how many ran
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		done, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.Migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			log.Infof("Applying migration %s %s", migration.Version, migration.Name)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now().UTC(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s %s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns how many ran,
+// steps must be at least 1
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps < 1 {
+		return 0, fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	reverted := 0
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		var records []SchemaMigration
+		err := db.Order("version desc").Limit(steps).Find(&records).Error
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			i := slices.IndexFunc(m.Migrations, func(migration Migration) bool {
+				return migration.Version == record.Version
+			})
+			if i < 0 || m.Migrations[i].Down == nil {
+				return fmt.Errorf("migration %s %s: %w", record.Version, record.Name, ErrNoDownMigration)
+			}
+
+			migration := m.Migrations[i]
+			log.Infof("Reverting migration %s %s", migration.Version, migration.Name)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s %s: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists the known migrations with their applied time, followed by the
+// applied migrations missing from the binary
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.DB.WithContext(ctx)
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	done, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := done[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	missing := make([]Status, 0, len(done))
+	for _, record := range done {
+		missing = append(missing, Status{Version: record.Version, Name: record.Name, AppliedAt: &record.AppliedAt, Missing: true})
+	}
+	slices.SortFunc(missing, func(a, b Status) int {
+		return strings.Compare(a.Version, b.Version)
+	})
+	return append(statuses, missing...), nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[string]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
+
+// withLock runs fn while holding the migration lock, so replicas starting
+// together don't apply the same migration twice
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.DB.WithContext(ctx)
+	if err := db.AutoMigrate(&SchemaMigration{}, &schemaLock{}); err != nil {
+		return err
+	}
+
+	owner, err := m.lock(ctx, db)
+	if err != nil {
+		return err
+	}
+	stop := m.heartbeat(db, owner)
+	defer func() {
+		stop()
+		// Release the lock even when ctx is cancelled, unless another process
+		// took it over as stale
+		if err := m.DB.Where("id = ? AND owner = ?", 1, owner).Delete(&schemaLock{}).Error; err != nil {
+			log.Error("Failed to release the migration lock: ", err)
+		}
+	}()
+
+	return fn(db)
+}
+
+// heartbeat refreshes the time of the lock until stop is called, so other
+// processes don't take it over as stale
+func (m *Migrator) heartbeat(db *gorm.DB, owner string) (stop func()) {
+	if m.Heartbeat <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(m.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				result := db.Model(&schemaLock{}).Where("id = ? AND owner = ?", 1, owner).Update("locked_at", time.Now().UTC())
+				if result.Error != nil {
+					log.Warn("Failed to refresh the migration lock: ", result.Error)
+				} else if result.RowsAffected == 0 {
+					log.Warn("The migration lock was taken over by another process")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// lock takes the migration lock, it returns the owner it was taken as. The
+// owner is unique to the run, as one process may run several migrators.
+func (m *Migrator) lock(ctx context.Context, db *gorm.DB) (string, error) {
+	hostname, _ := os.Hostname()
+	nonce := make([]byte, 4)
+	rand.Read(nonce)
+	owner := fmt.Sprintf("%s:%d:%x", hostname, os.Getpid(), nonce)
+	deadline := time.Now().Add(m.LockTimeout)
+
+	for {
+		err := db.Create(&schemaLock{ID: 1, Owner: owner, LockedAt: time.Now().UTC()}).Error
+		if err == nil {
+			return owner, nil
+		}
+
+		// The insert only fails on the primary key when another process holds the lock
+		var held schemaLock
+		if findErr := db.Limit(1).Find(&held, 1).Error; findErr != nil {
+			return "", findErr
+		}
+		if held.ID == 0 {
+			return "", err
+		}
+
+		if m.StaleLockAfter > 0 && time.Since(held.LockedAt) > m.StaleLockAfter {
+			log.Warnf("Releasing stale migration lock held by %s since %s", held.Owner, held.LockedAt)
+			db.Where("id = ? AND owner = ?", held.ID, held.Owner).Delete(&schemaLock{})
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("%w: held by %s since %s", ErrLocked, held.Owner, held.LockedAt)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type widget struct {
+	ID   uint64 `gorm:"primaryKey"`
+	Name string
+}
+
+func setupMigrator(t *testing.T, migrations ...Migration) *Migrator {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	migrator, err := New(db, migrations)
+	assert.NoError(t, err)
+	return migrator
+}
+
+var testMigrations = []Migration{
+	{
+		Version: "20240102000000",
+		Name:    "add_widget_name",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&widget{}, "Name")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&widget{}, "Name")
+		},
+	},
+	{
+		Version: "20240101000000",
+		Name:    "create_widgets",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE widgets (id integer PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("widgets")
+		},
+	},
+}
+
+func TestUpAndDown(t *testing.T) {
+	m := setupMigrator(t, testMigrations...)
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.True(t, m.DB.Migrator().HasColumn(&widget{}, "Name"))
+
+	applied, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, "create_widgets", statuses[0].Name)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.NotNil(t, statuses[1].AppliedAt)
+
+	reverted, err := m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.True(t, m.DB.Migrator().HasTable("widgets"))
+	assert.False(t, m.DB.Migrator().HasColumn(&widget{}, "Name"))
+
+	statuses, err = m.Status(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	// Without a positive number of steps nothing is reverted
+	for _, steps := range []int{0, -1} {
+		_, err = m.Down(ctx, steps)
+		assert.ErrorContains(t, err, "steps must be at least 1")
+	}
+	assert.True(t, m.DB.Migrator().HasTable("widgets"))
+
+	// The lock is released after each run
+	var locks int64
+	m.DB.Model(&schemaLock{}).Count(&locks)
+	assert.Equal(t, int64(0), locks)
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	failing := Migration{
+		Version: "20240103000000",
+		Name:    "broken",
+		Up: func(tx *gorm.DB) error {
+			return errors.New("boom")
+		},
+	}
+	m := setupMigrator(t, append(testMigrations, failing)...)
+
+	applied, err := m.Up(context.Background())
+	assert.ErrorContains(t, err, "20240103000000 broken: boom")
+	assert.Equal(t, 2, applied)
+
+	statuses, err := m.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, statuses[2].AppliedAt)
+
+	// Reverting a migration without Down is refused
+	m.Migrations[1].Down = nil
+	_, err = m.Down(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNoDownMigration)
+}
+
+func TestLock(t *testing.T) {
+	m := setupMigrator(t, testMigrations...)
+	m.LockTimeout = 0
+	assert.NoError(t, m.DB.AutoMigrate(&SchemaMigration{}, &schemaLock{}))
+
+	m.DB.Create(&schemaLock{ID: 1, Owner: "other:1", LockedAt: time.Now().UTC()})
+	_, err := m.Up(context.Background())
+	assert.ErrorIs(t, err, ErrLocked)
+	statuses, _ := m.Status(context.Background())
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	// Locks of crashed processes are taken over
+	m.DB.Model(&schemaLock{}).Where("id = 1").Update("locked_at", time.Now().Add(-time.Hour))
+	applied, err := m.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+}
+
+func TestLockReleaseKeepsTakenOverLock(t *testing.T) {
+	m := setupMigrator(t, Migration{
+		Version: "20240101000000",
+		Name:    "slow",
+		Up: func(tx *gorm.DB) error {
+			// Another process found the lock stale and took it over
+			return tx.Model(&schemaLock{}).Where("id = 1").Update("owner", "other:1").Error
+		},
+		Down: func(tx *gorm.DB) error { return nil },
+	})
+
+	_, err := m.Up(context.Background())
+	assert.NoError(t, err)
+	var held schemaLock
+	assert.NoError(t, m.DB.First(&held, 1).Error)
+	assert.Equal(t, "other:1", held.Owner)
+}
+
+func TestLockHeartbeat(t *testing.T) {
+	// The heartbeat writes on its own connection, in-memory databases aren't shared
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	var other *Migrator
+	var otherErr error
+	slow := Migration{
+		Version: "20240101000000",
+		Name:    "slow",
+		Up: func(tx *gorm.DB) error {
+			time.Sleep(300 * time.Millisecond)
+			// The lock is older than StaleLockAfter but was kept fresh
+			_, otherErr = other.Up(context.Background())
+			return nil
+		},
+	}
+	m, err := New(db, []Migration{slow})
+	assert.NoError(t, err)
+	m.StaleLockAfter = 200 * time.Millisecond
+	m.Heartbeat = 50 * time.Millisecond
+	fast := Migration{Version: slow.Version, Name: "fast", Up: func(tx *gorm.DB) error { return nil }}
+	other, err = New(db, []Migration{fast})
+	assert.NoError(t, err)
+	other.StaleLockAfter = 200 * time.Millisecond
+	other.LockTimeout = 0
+
+	applied, err := m.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.ErrorIs(t, otherErr, ErrLocked)
+}
+
+func TestNewRejectsDuplicateVersions(t *testing.T) {
+	_, err := New(nil, append(testMigrations, testMigrations[0]))
+	assert.ErrorContains(t, err, "duplicate migration version 20240102000000")
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The events and users tables as they were created by AutoMigrate. Databases
+// that already have them are left as they are.
+
+type baselineUser struct {
+	Uid      string `gorm:"primaryKey;type:varchar(255)"`
+	Username string `gorm:"type:varchar(255);not null;unique"`
+	Role     string `gorm:"type:varchar(50);not null;default:'user'"`
+}
+
+func (baselineUser) TableName() string {
+	return "users"
+}
+
+type baselineEvent struct {
+	ID                   uint64       `gorm:"primaryKey"`
+	CreatedBy            string       `gorm:"createdBy;not null"`
+	User                 baselineUser `gorm:"foreignKey:CreatedBy"`
+	Title                string       `gorm:"not null"`
+	Description          string
+	Location             string
+	Images               string    `gorm:"type:json"`
+	StartTime            time.Time `gorm:"default:null"`
+	EndTime              time.Time `gorm:"default:null"`
+	CreatedAt            time.Time `gorm:"autoCreateTime"`
+	UpdatedAt            time.Time `gorm:"autoUpdateTime"`
+	UpdatedBy            string
+	Status               string `gorm:"default:'draft'"`
+	MaxAttendees         uint
+	AttendeesCount       uint
+	IsPublic             bool   `gorm:"default:true"`
+	RSVPRequired         bool   `gorm:"default:false"`
+	Tags                 string `gorm:"type:json"`
+	OrganizerContactInfo string
+	ExternalLink         string
+	IsFeatured           bool `gorm:"default:false"`
+	EventType            string
+}
+
+func (baselineEvent) TableName() string {
+	return "events"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241001000000",
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&baselineUser{}, &baselineEvent{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&baselineEvent{}, &baselineUser{})
+		},
+	})
+}
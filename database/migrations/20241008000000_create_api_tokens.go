@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiTokenV1 struct {
+	ID         uint64       `gorm:"primaryKey"`
+	Name       string       `gorm:"type:varchar(255);not null"`
+	OwnerUid   string       `gorm:"type:varchar(255);not null;index"`
+	Owner      baselineUser `gorm:"foreignKey:OwnerUid"`
+	OrgID      string       `gorm:"type:varchar(255);not null;default:''"`
+	Prefix     string       `gorm:"type:varchar(32);not null"`
+	TokenHash  string       `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     string       `gorm:"type:varchar(255);not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+func (apiTokenV1) TableName() string {
+	return "api_tokens"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241008000000",
+		Name:    "create_api_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&apiTokenV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiTokenV1{})
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type organizationV1 struct {
+	ID        string    `gorm:"primaryKey;type:varchar(255)"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (organizationV1) TableName() string {
+	return "organizations"
+}
+
+type membershipV1 struct {
+	UserUid      string         `gorm:"primaryKey;type:varchar(255)"`
+	User         baselineUser   `gorm:"foreignKey:UserUid"`
+	OrgID        string         `gorm:"primaryKey;type:varchar(255)"`
+	Organization organizationV1 `gorm:"foreignKey:OrgID"`
+	Role         string         `gorm:"type:varchar(100);not null;default:''"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime"`
+}
+
+func (membershipV1) TableName() string {
+	return "memberships"
+}
+
+// eventOrgV1 adds the owning organization to events
+type eventOrgV1 struct {
+	OrgID string `gorm:"type:varchar(255);index"`
+}
+
+func (eventOrgV1) TableName() string {
+	return "events"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241010000000",
+		Name:    "create_organizations",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&organizationV1{}, &membershipV1{}, &eventOrgV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndex(tx, &eventOrgV1{}, "idx_events_org_id"); err != nil {
+				return err
+			}
+			if err := dropColumn(tx, &eventOrgV1{}, "OrgID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&membershipV1{}, &organizationV1{})
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// eventSearchV1 is the MySQL full-text index of events. JSON columns like tags
+// can't be in a FULLTEXT index, so the text is copied to its own table.
+type eventSearchV1 struct {
+	EventID              uint64 `gorm:"primaryKey;autoIncrement:false"`
+	OrgID                string `gorm:"type:varchar(255);index"`
+	Title                string `gorm:"type:text;index:ft_event_search,class:FULLTEXT"`
+	Description          string `gorm:"type:text;index:ft_event_search,class:FULLTEXT"`
+	Location             string `gorm:"type:text;index:ft_event_search,class:FULLTEXT"`
+	Tags                 string `gorm:"type:text;index:ft_event_search,class:FULLTEXT"`
+	OrganizerContactInfo string `gorm:"type:text;index:ft_event_search,class:FULLTEXT"`
+}
+
+func (eventSearchV1) TableName() string {
+	return "event_search"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241017000000",
+		Name:    "create_event_search",
+		Up: func(tx *gorm.DB) error {
+			switch tx.Dialector.Name() {
+			case "mysql":
+				if err := tx.AutoMigrate(&eventSearchV1{}); err != nil {
+					return err
+				}
+				return tx.Exec(`INSERT INTO event_search (event_id, org_id, title, description, location, tags, organizer_contact_info)
+					SELECT id, org_id, title, description, location, tags, organizer_contact_info FROM events`).Error
+			case "sqlite":
+				err := tx.Exec(`CREATE VIRTUAL TABLE event_search USING fts5(
+					title, description, location, tags, organizer_contact_info, org_id UNINDEXED,
+					tokenize = 'porter unicode61 remove_diacritics 2')`).Error
+				if err != nil && strings.Contains(err.Error(), "no such module") {
+					// Built without the sqlite_fts5 tag, search falls back to LIKE
+					log.Warn("SQLite has no FTS5 support, events are searched without a full-text index")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				return tx.Exec(`INSERT INTO event_search (rowid, title, description, location, tags, organizer_contact_info, org_id)
+					SELECT id, title, description, location, tags, organizer_contact_info, org_id FROM events`).Error
+			default:
+				// Other databases use the LIKE search
+				return nil
+			}
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("event_search")
+		},
+	})
+}
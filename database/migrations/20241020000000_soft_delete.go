@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+
+	"gorm.io/gorm"
+)
+
+// The trash columns of events and users
+
+type eventTrashV1 struct {
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	DeletedBy string         `gorm:"type:varchar(255)"`
+}
+
+func (eventTrashV1) TableName() string {
+	return "events"
+}
+
+type userTrashV1 struct {
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	DeletedBy string         `gorm:"type:varchar(255)"`
+}
+
+func (userTrashV1) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241020000000",
+		Name:    "soft_delete",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&eventTrashV1{}, &userTrashV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, value := range []any{&eventTrashV1{}, &userTrashV1{}} {
+				if err := dropIndex(tx, value, "DeletedAt"); err != nil {
+					return err
+				}
+				for _, column := range []string{"DeletedAt", "DeletedBy"} {
+					if err := dropColumn(tx, value, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type auditEntryV1 struct {
+	ID        uint64    `gorm:"primaryKey"`
+	Entity    string    `gorm:"type:varchar(32);not null;index:idx_audit_entity"`
+	EntityID  string    `gorm:"type:varchar(255);not null;index:idx_audit_entity"`
+	Action    string    `gorm:"type:varchar(16);not null"`
+	Actor     string    `gorm:"type:varchar(255);index"`
+	OrgID     string    `gorm:"type:varchar(255);index"`
+	RequestID string    `gorm:"type:varchar(64);index"`
+	IP        string    `gorm:"type:varchar(45)"`
+	Changes   string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (auditEntryV1) TableName() string {
+	return "audit_entries"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241024000000",
+		Name:    "create_audit_entries",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&auditEntryV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&auditEntryV1{})
+		},
+	})
+}
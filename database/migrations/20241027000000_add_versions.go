@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+
+	"gorm.io/gorm"
+)
+
+// The optimistic locking versions of events and users, existing rows start at 1
+
+type eventVersionV1 struct {
+	Version uint64 `gorm:"not null;default:1"`
+}
+
+func (eventVersionV1) TableName() string {
+	return "events"
+}
+
+type userVersionV1 struct {
+	Version uint64 `gorm:"not null;default:1"`
+}
+
+func (userVersionV1) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241027000000",
+		Name:    "add_versions",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&eventVersionV1{}, &userVersionV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, value := range []any{&eventVersionV1{}, &userVersionV1{}} {
+				if err := dropColumn(tx, value, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
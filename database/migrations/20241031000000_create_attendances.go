@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type attendanceV1 struct {
+	ID        uint64        `gorm:"primaryKey"`
+	EventID   uint64        `gorm:"not null;uniqueIndex:idx_attendances_event_user"`
+	Event     baselineEvent `gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE"`
+	UserUid   string        `gorm:"type:varchar(255);not null;uniqueIndex:idx_attendances_event_user;index"`
+	User      baselineUser  `gorm:"foreignKey:UserUid"`
+	Status    string        `gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time     `gorm:"autoCreateTime"`
+	UpdatedAt time.Time     `gorm:"autoUpdateTime"`
+}
+
+func (attendanceV1) TableName() string {
+	return "attendances"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241031000000",
+		Name:    "create_attendances",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&attendanceV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&attendanceV1{})
+		},
+	})
+}
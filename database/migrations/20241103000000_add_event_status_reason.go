@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+
+	"gorm.io/gorm"
+)
+
+// eventStatusReasonV1 records why an event was cancelled or postponed
+type eventStatusReasonV1 struct {
+	StatusReason string
+}
+
+func (eventStatusReasonV1) TableName() string {
+	return "events"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241103000000",
+		Name:    "add_event_status_reason",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&eventStatusReasonV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &eventStatusReasonV1{}, "StatusReason")
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"encoding/json"
+	"gotempl/database/migrate"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// eventListsV1 reads the images and tags of events as the text clients sent,
+// which wasn't checked to be a JSON array
+type eventListsV1 struct {
+	ID     uint64 `gorm:"primaryKey"`
+	Images *string
+	Tags   *string
+}
+
+func (eventListsV1) TableName() string {
+	return "events"
+}
+
+// parseList reads a JSON array of strings, a JSON string holding one, or a
+// comma separated list
+func parseList(text string) []string {
+	text = strings.TrimSpace(text)
+	var list []string
+	if err := json.Unmarshal([]byte(text), &list); err == nil {
+		return list
+	}
+	var encoded string
+	if err := json.Unmarshal([]byte(text), &encoded); err == nil {
+		return parseList(encoded)
+	}
+	return strings.Split(text, ",")
+}
+
+// normalizeList rewrites text as a JSON array without blank or repeated items,
+// tags are also lower cased with dashes for spaces
+func normalizeList(text *string, tags bool) string {
+	list := []string{}
+	if text != nil {
+		for _, item := range parseList(*text) {
+			item = strings.TrimSpace(item)
+			if tags {
+				item = strings.Join(strings.Fields(strings.ToLower(item)), "-")
+			}
+			if item != "" && !slices.Contains(list, item) {
+				list = append(list, item)
+			}
+		}
+	}
+	raw, _ := json.Marshal(list)
+	return string(raw)
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241107000000",
+		Name:    "normalize_event_lists",
+		Up: func(tx *gorm.DB) error {
+			var events []eventListsV1
+			return tx.FindInBatches(&events, 500, func(tx *gorm.DB, batch int) error {
+				for _, event := range events {
+					err := tx.Model(&eventListsV1{}).Where("id = ?", event.ID).UpdateColumns(map[string]any{
+						"images": normalizeList(event.Images, false),
+						"tags":   normalizeList(event.Tags, true),
+					}).Error
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		},
+		// The arrays are still valid text for the previous code
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
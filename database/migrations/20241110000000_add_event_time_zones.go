@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// eventTimeZoneV1 is the IANA time zone of an event and whether it lasts whole days
+type eventTimeZoneV1 struct {
+	TimeZone string `gorm:"type:varchar(64);not null;default:'UTC'"`
+	AllDay   bool   `gorm:"default:false"`
+}
+
+func (eventTimeZoneV1) TableName() string {
+	return "events"
+}
+
+// timeColumns lists the time columns of every table
+var timeColumns = map[string][]string{
+	"events":        {"start_time", "end_time", "created_at", "updated_at", "deleted_at"},
+	"users":         {"deleted_at"},
+	"api_tokens":    {"expires_at", "last_used_at", "revoked_at", "created_at"},
+	"organizations": {"created_at"},
+	"memberships":   {"created_at"},
+	"audit_entries": {"created_at"},
+	"attendances":   {"created_at", "updated_at"},
+}
+
+// toUTC returns how the stored times move to UTC, if they must. MySQL was
+// connected with loc=Local: its DATETIME columns hold the wall clock of the
+// server that wrote them, legacy, now read as UTC. It is UTC when nil, leaving
+// the times as they are: the zone of the host running the migration may not be
+// that of the server. SQLite keeps the offset of every time in the text, which
+// must be UTC for the text to sort as the times.
+func toUTC(dialect string, legacy *time.Location) func(time.Time) time.Time {
+	switch dialect {
+	case "mysql":
+		if legacy == nil || legacy == time.UTC {
+			return nil
+		}
+		return func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), legacy).UTC()
+		}
+	case "sqlite":
+		return func(t time.Time) time.Time { return t.UTC() }
+	}
+	return nil
+}
+
+// rewriteTimes moves the distinct values of a column one by one. Rows with a
+// moved value must not be matched again: when times move back they are
+// rewritten from the earliest, otherwise from the latest.
+func rewriteTimes(tx *gorm.DB, table, column string, move func(time.Time) time.Time) error {
+	var values []time.Time
+	err := tx.Table(table).Distinct(column).Where(column+" IS NOT NULL").Order(column).Pluck(column, &values).Error
+	if err != nil {
+		return err
+	}
+	if len(values) > 0 && move(values[0]).After(values[0]) {
+		slices.Reverse(values)
+	}
+	for _, value := range values {
+		moved := move(value)
+		if moved.Equal(value) && moved.Location() == value.Location() {
+			continue
+		}
+		if err := tx.Table(table).Where(column+" = ?", value).UpdateColumn(column, moved).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	registerWith(func(opts Options) migrate.Migration {
+		return migrate.Migration{
+			Version: "20241110000000",
+			Name:    "add_event_time_zones",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&eventTimeZoneV1{}); err != nil {
+					return err
+				}
+				move := toUTC(tx.Dialector.Name(), opts.LegacyTimeZone)
+				if move == nil {
+					return nil
+				}
+				for table, columns := range timeColumns {
+					for _, column := range columns {
+						if err := rewriteTimes(tx, table, column, move); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+			// The times are left in UTC
+			Down: func(tx *gorm.DB) error {
+				if err := dropColumn(tx, &eventTimeZoneV1{}, "TimeZone"); err != nil {
+					return err
+				}
+				return dropColumn(tx, &eventTimeZoneV1{}, "AllDay")
+			},
+		}
+	})
+}
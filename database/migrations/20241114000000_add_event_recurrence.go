@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// eventRecurrenceV1 is the recurrence rule of an event with its removed
+// occurrences, or the occurrence of a series an event overrides
+type eventRecurrenceV1 struct {
+	RRule        string  `gorm:"column:rrule;type:varchar(255);not null;default:''"`
+	ExDates      string  `gorm:"column:exdates;type:json"`
+	SeriesID     *uint64 `gorm:"index"`
+	RecurrenceID *time.Time
+}
+
+func (eventRecurrenceV1) TableName() string {
+	return "events"
+}
+
+func init() {
+	register(migrate.Migration{
+		Version: "20241114000000",
+		Name:    "add_event_recurrence",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&eventRecurrenceV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndex(tx, &eventRecurrenceV1{}, "SeriesID"); err != nil {
+				return err
+			}
+			for _, field := range []string{"RRule", "ExDates", "SeriesID", "RecurrenceID"} {
+				if err := dropColumn(tx, &eventRecurrenceV1{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,96 @@
+// Package migrations holds the schema migrations of the application, one file
+// per migration. New files are created with `go run ./cmd/migrate create <name>`.
+//
+// Migrations must not use the model package: they describe the schema at the
+// time they were written with their own structs.
+package migrations
+
+import (
+	"gotempl/database/migrate"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Options are the settings of the migrations that depend on the deployment
+type Options struct {
+	// LegacyTimeZone is the time zone MySQL times were written in before they
+	// were stored in UTC, UTC when nil
+	LegacyTimeZone *time.Location
+}
+
+var registry []func(opts Options) migrate.Migration
+
+func register(migration migrate.Migration) {
+	registry = append(registry, func(Options) migrate.Migration { return migration })
+}
+
+// registerWith adds a migration that depends on the options
+func registerWith(migration func(opts Options) migrate.Migration) {
+	registry = append(registry, migration)
+}
+
+// All returns every migration set up with opts, in no particular order
+func All(opts Options) []migrate.Migration {
+	migrations := make([]migrate.Migration, 0, len(registry))
+	for _, migration := range registry {
+		migrations = append(migrations, migration(opts))
+	}
+	return migrations
+}
+
+// dropColumn drops the column of a field. SQLite drops columns by rebuilding
+// the table, which loses its indexes: those on the other columns are created
+// again.
+func dropColumn(tx *gorm.DB, value any, field string) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return tx.Migrator().DropColumn(value, field)
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(value); err != nil {
+		return err
+	}
+	column := field
+	if f := stmt.Schema.LookUpField(field); f != nil {
+		column = f.DBName
+	}
+
+	var indexes []struct{ Name, SQL string }
+	err := tx.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", stmt.Table).
+		Scan(&indexes).Error
+	if err != nil {
+		return err
+	}
+	kept := indexes[:0]
+	for _, index := range indexes {
+		var columns []string
+		if err := tx.Raw("SELECT name FROM pragma_index_info(?)", index.Name).Scan(&columns).Error; err != nil {
+			return err
+		}
+		if !slices.Contains(columns, column) {
+			kept = append(kept, index)
+		}
+	}
+
+	if err := tx.Migrator().DropColumn(value, field); err != nil {
+		return err
+	}
+	for _, index := range kept {
+		if tx.Migrator().HasIndex(value, index.Name) {
+			continue
+		}
+		if err := tx.Exec(index.SQL).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropIndex drops the index if it is still there
+func dropIndex(tx *gorm.DB, value any, name string) error {
+	if !tx.Migrator().HasIndex(value, name) {
+		return nil
+	}
+	return tx.Migrator().DropIndex(value, name)
+}
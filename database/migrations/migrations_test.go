@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToUTC(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	// MySQL reads the wall clock the server wrote as UTC
+	stored := time.Date(2024, 11, 5, 18, 0, 0, 0, time.UTC)
+
+	move := toUTC("mysql", paris)
+	if assert.NotNil(t, move) {
+		assert.Equal(t, time.Date(2024, 11, 5, 17, 0, 0, 0, time.UTC), move(stored))
+	}
+
+	// Without a legacy zone the times are left as they are
+	assert.Nil(t, toUTC("mysql", nil))
+	assert.Nil(t, toUTC("mysql", time.UTC))
+	assert.Nil(t, toUTC("postgres", paris))
+}
@@ -15,9 +15,49 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/audit": {
+            "get": {
+                "description": "Fetches a page of the audit log and renders an HTML page to browse and filter it (non-REST endpoint)",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "This is a non-REST endpoint that returns an HTML page - not JSON data",
+                "responses": {
+                    "200": {
+                        "description": "HTML page content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/event/": {
             "get": {
-                "description": "Fetches all events and renders an HTML page with a CRUD form for event management (non-REST endpoint)",
+                "description": "Fetches a page of events and renders an HTML page with a CRUD form for event management (non-REST endpoint)",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "This is a non-REST endpoint that returns an HTML page - not JSON data",
+                "responses": {
+                    "200": {
+                        "description": "HTML page content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/event/trash": {
+            "get": {
+                "description": "Fetches a page of trashed events and renders an HTML page to restore or purge them (non-REST endpoint)",
                 "produces": [
                     "text/html"
                 ],
@@ -35,9 +75,101 @@ const docTemplate = `{
                 }
             }
         },
+        "/admin/tenant": {
+            "get": {
+                "description": "Lists every organization so a super admin can pick the tenant the admin pages work on (non-REST endpoint)",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Organization"
+                ],
+                "summary": "This is a non-REST endpoint that returns an HTML page - not JSON data",
+                "responses": {
+                    "200": {
+                        "description": "HTML page content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Stores the tenant picked by a super admin, an empty org shows every organization (non-REST endpoint)",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "tags": [
+                    "Organization"
+                ],
+                "summary": "This is a non-REST endpoint that redirects to an HTML page - not JSON data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "org",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Redirect to the event page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown organization",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/token": {
+            "get": {
+                "description": "Fetches the API tokens and members of the caller's organization and renders an HTML page to mint and revoke them (non-REST endpoint)",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "This is a non-REST endpoint that returns an HTML page - not JSON data",
+                "responses": {
+                    "200": {
+                        "description": "HTML page content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/user": {
             "get": {
-                "description": "Fetches all users and renders an HTML page with a CRUD form for user management (non-REST endpoint)",
+                "description": "Fetches a page of users and renders an HTML page with a CRUD form for user management (non-REST endpoint)",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "This is a non-REST endpoint that returns an HTML page - not JSON data",
+                "responses": {
+                    "200": {
+                        "description": "HTML page content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/user/trash": {
+            "get": {
+                "description": "Fetches a page of trashed users and renders an HTML page to restore or purge them (non-REST endpoint)",
                 "produces": [
                     "text/html"
                 ],
@@ -55,9 +187,135 @@ const docTemplate = `{
                 }
             }
         },
+        "/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a page of the audit log of events and users, by default the latest change first. Each entry has the actor, the request ID, the client IP and the changed fields with their value before and after.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List audit entries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-id",
+                        "description": "id or created_at, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "event",
+                            "user"
+                        ],
+                        "type": "string",
+                        "description": "Only changes of this kind of record",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes of the record with this ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore",
+                            "purge"
+                        ],
+                        "type": "string",
+                        "description": "Only this kind of change",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made by this user ID",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made by this request",
+                        "name": "request_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made at or after this RFC 3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made before this RFC 3339 time",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Page-model_AuditEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/event": {
             "get": {
-                "description": "Retrieve a list of all events",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a page of events. Pass next_cursor back as cursor, with the same sort and filters, to read the next page.",
                 "consumes": [
                     "application/json"
                 ],
@@ -67,14 +325,1853 @@ const docTemplate = `{
                 "tags": [
                     "Event"
                 ],
-                "summary": "Get all events",
+                "summary": "List events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "id, start_time, end_time, created_at, updated_at or title, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events of this type",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events created by this user ID",
+                        "name": "created_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only public or private events",
+                        "name": "is_public",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only featured or not featured events",
+                        "name": "is_featured",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events starting at or after this RFC 3339 time",
+                        "name": "start_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events starting before this RFC 3339 time",
+                        "name": "start_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only recurring or not recurring events",
+                        "name": "recurring",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "List the occurrences starting from from to to instead, in order, without pagination",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the expanded window, an RFC 3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the expanded window, an RFC 3339 time",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "With expand=true",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Occurrence"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new event with the provided information. createdBy and updated_by are set to the caller, attendees_count starts at 0 and the status at draft. start_time and end_time are returned in UTC, time_zone is an IANA name (UTC by default) and all-day events start and end at midnight in it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Create a new event",
+                "parameters": [
+                    {
+                        "description": "Event information",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Full-text search of the title, description, location, tags and organizer contact info of events. Every word must match, as a prefix. Results are ordered by relevance and carry an HTML snippet with the matches in \u003cmark\u003e.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Search events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Words to search for",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of results, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Page-repository_SearchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/event/trash": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a page of the events in the trash, by default the last deleted first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "List trashed events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-deleted_at",
+                        "description": "deleted_at or a sort of the event list, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Page-model_Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a event's information using their ID. The ETag header is its version, to send back in If-Match when changing it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Get a event by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached version, answered with 304 while it is current",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a event's information in the system. createdBy, attendees_count and the status are kept, the status only changes through the lifecycle actions. updated_by is set to the caller.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Update a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated event information",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move a event to the trash using their ID. It can be restored until it is purged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Delete a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change some fields of a event. The body is a JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json): its members replace those of the event and null resets them. A JSON Patch (RFC 6902, application/json-patch+json) is accepted too. id, createdBy, created_at, org_id, attendees_count, status and the trash fields can't be changed, updated_by is set to the caller.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Patch a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch, e.g. {\\",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/attendees": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the registrations to an event, those with a seat first, then the waitlist in order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "List the attendees of an event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Attendance"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change the status of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the status changes",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controller.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The action doesn't apply to the current status",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change the status of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the status changes",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controller.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The action doesn't apply to the current status",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/occurrences": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Expand the recurrence rule of a event into its occurrences starting from from to to, in order. The exceptions (exdates) are left out and the overridden occurrences are replaced by the events overriding them, wherever they moved. A event that doesn't recur has one occurrence. At most 1000 occurrences are returned, in a window of at most 366 days starting at most 100 years after the series.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "List the occurrences of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the window, an RFC 3339 time",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the window, an RFC 3339 time",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Occurrence"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/occurrences/{start}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the occurrence of a recurring event starting at start, as given by its rule. With scope=this (the default) it becomes an exception of the series (exdates); with scope=following the series ends before it; with scope=all the series goes to the trash. The events overriding the removed occurrences go to the trash.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Delete an occurrence of a recurring event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID of the series",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 start of the occurrence, given by the rule",
+                        "name": "start",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "this",
+                        "description": "this, following or all",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version of the series the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The series changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Patch the occurrence of a recurring event starting at start, as given by its rule. With scope=this (the default) an event overriding the occurrence is created, or changed, with the fields of the series; with scope=following the series ends before the occurrence and a new series, changed by the patch, continues it with the following exceptions and overrides; with scope=all the series itself is changed, its exceptions and overrides move with its start. The body is a patch as for PATCH /event/{id}, of the event changed, which is returned.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change an occurrence of a recurring event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID of the series",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 start of the occurrence, given by the rule",
+                        "name": "start",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "this",
+                        "description": "this, following or all",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Merge patch, e.g. {\\",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version of the series the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The series changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/postpone": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change the status of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the status changes",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controller.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The action doesn't apply to the current status",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/publish": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change the status of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the status changes",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controller.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The action doesn't apply to the current status",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/purge": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently delete a event that is in the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Purge a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Take a event out of the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Restore a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/rsvp": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Register the caller to a published event. They get a seat while attendees_count is below max_attendees (0 doesn't limit it), otherwise they are put on the waitlist.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Register to an event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Attendance"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The caller is already registered, or the event isn't published",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel the caller's registration to an event. A seat it frees goes to the first user of the waitlist.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Cancel a registration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/schedule": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change the status of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the status changes",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controller.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The action doesn't apply to the current status",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{id}/start": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a lifecycle action to a event, the last part of the path: schedule (draft or postponed → scheduled), publish (draft, scheduled or postponed → published), start (scheduled or published → live), complete (live → completed), postpone (scheduled or published → postponed) or cancel (any but completed → cancelled). Scheduling and publishing need a title and a start time, cancelling needs a reason. updated_by is set to the caller. The status can't be changed otherwise.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Change the status of a event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the status changes",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controller.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "The action doesn't apply to the current status",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The event changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the tags of the events with how many events have them, the most used first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "List tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repository.TagCount"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/token": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the API tokens of the caller's organization, without their secrets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Get all API tokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.APIToken"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create an API token for machine clients. The secret is only returned once. The owner defaults to the caller, it can be another member of the caller's organization whose role isn't above the caller's. The token is limited to the caller's organization. Callers authenticated with a token can only grant its scopes, and the new token expires no later than it (by default when it does).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Mint a personal access token",
+                "parameters": [
+                    {
+                        "description": "Token information",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateAPITokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateAPITokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke an API token of the caller's organization, it stops working immediately",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Revoke an API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a page of users. Pass next_cursor back as cursor, with the same sort and filters, to read the next page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "username",
+                        "description": "uid, username or role, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users with this role",
+                        "name": "role",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Event"
+                            "$ref": "#/definitions/repository.Page-model_User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -90,7 +2187,12 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new event with the provided information",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new user with the provided information. Callers can't grant a role above their own or change users whose role is above theirs.",
                 "consumes": [
                     "application/json"
                 ],
@@ -98,17 +2200,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Event"
+                    "User"
                 ],
-                "summary": "Create a new event",
+                "summary": "Create a new user",
                 "parameters": [
                     {
-                        "description": "Event information",
-                        "name": "event",
+                        "description": "User information",
+                        "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Event"
+                            "$ref": "#/definitions/model.User"
                         }
                     }
                 ],
@@ -116,7 +2218,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Event"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
@@ -125,6 +2227,12 @@ const docTemplate = `{
                             "type": "object"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -134,9 +2242,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/event/{id}": {
+        "/user/trash": {
             "get": {
-                "description": "Retrieve a event's information using their ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a page of the users in the trash, by default the last deleted first",
                 "consumes": [
                     "application/json"
                 ],
@@ -144,25 +2257,102 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Event"
+                    "User"
                 ],
-                "summary": "Get a event by ID",
+                "summary": "List trashed users",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Event ID",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-deleted_at",
+                        "description": "deleted_at or a sort of the user list, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Page-model_User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a user's information using their ID. The ETag header is its version, to send back in If-Match when changing it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get a user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached version, answered with 304 while it is current",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Event"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
+                    "304": {
+                        "description": "Not Modified"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -178,7 +2368,12 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update a event's information in the system",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a user's information in the system. Callers can't grant a role above their own or change users whose role is above theirs. Only super admins change users who also belong to other organizations.",
                 "consumes": [
                     "application/json"
                 ],
@@ -186,32 +2381,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Event"
+                    "User"
                 ],
-                "summary": "Update a event",
+                "summary": "Update a user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Event ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated event information",
-                        "name": "event",
+                        "description": "Updated user information",
+                        "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Event"
+                            "$ref": "#/definitions/model.User"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Event"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
@@ -220,6 +2421,24 @@ const docTemplate = `{
                             "type": "object"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The user changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -229,7 +2448,12 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a event from the system using their ID.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move a user to the trash using their ID, which revokes their access. It can be restored until it is purged. Only super admins change users who also belong to other organizations.",
                 "consumes": [
                     "application/json"
                 ],
@@ -237,69 +2461,65 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Event"
+                    "User"
                 ],
-                "summary": "Delete a event",
+                "summary": "Delete a user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Event ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "204": {
                         "description": "No Content"
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object"
                         }
-                    }
-                }
-            }
-        },
-        "/user": {
-            "get": {
-                "description": "Retrieve a list of all users",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "User"
-                ],
-                "summary": "Get all users",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.User"
-                            }
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The user changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "object"
                         }
                     }
                 }
             },
-            "post": {
-                "description": "Create a new user with the provided information",
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change some fields of a user. The body is a JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json): its members replace those of the user and null resets them. A JSON Patch (RFC 6902, application/json-patch+json) is accepted too. uid and the trash fields can't be changed. Callers can't grant a role above their own or change users whose role is above theirs. Only super admins change users who also belong to other organizations.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
                 ],
                 "produces": [
                     "application/json"
@@ -307,21 +2527,34 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Create a new user",
+                "summary": "Patch a user",
                 "parameters": [
                     {
-                        "description": "User information",
-                        "name": "user",
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch, e.g. {\\",
+                        "name": "patch",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "type": "object"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/model.User"
                         }
@@ -332,6 +2565,30 @@ const docTemplate = `{
                             "type": "object"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "412": {
+                        "description": "The user changed, the body has its current version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -341,9 +2598,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/{id}": {
-            "get": {
-                "description": "Retrieve a user's information using their ID",
+        "/user/{id}/purge": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently delete a user that is in the trash. Only super admins change users who also belong to other organizations.",
                 "consumes": [
                     "application/json"
                 ],
@@ -353,7 +2615,7 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Get a user by ID",
+                "summary": "Purge a user",
                 "parameters": [
                     {
                         "type": "string",
@@ -364,14 +2626,11 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.User"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object"
                         }
@@ -381,11 +2640,24 @@ const docTemplate = `{
                         "schema": {
                             "type": "object"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
                     }
-                }
-            },
-            "put": {
-                "description": "Update a user's information in the system",
+                ],
+                "description": "Take a user out of the trash, which gives their access back. Only super admins change users who also belong to other organizations.",
                 "consumes": [
                     "application/json"
                 ],
@@ -395,7 +2667,7 @@ const docTemplate = `{
                 "tags": [
                     "User"
                 ],
-                "summary": "Update a user",
+                "summary": "Restore a user",
                 "parameters": [
                     {
                         "type": "string",
@@ -403,15 +2675,6 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Updated user information",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.User"
-                        }
                     }
                 ],
                 "responses": {
@@ -421,8 +2684,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.User"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object"
                         }
@@ -434,9 +2703,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a user from the system using their ID.",
+            }
+        },
+        "/webhooks/clerk": {
+            "post": {
+                "description": "Applies user.created, user.updated and user.deleted events to the local users table. Requests must carry valid Svix signature headers.",
                 "consumes": [
                     "application/json"
                 ],
@@ -444,15 +2715,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Webhook"
                 ],
-                "summary": "Delete a user",
+                "summary": "Receive Clerk webhooks",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Message ID",
+                        "name": "svix-id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix timestamp of the message",
+                        "name": "svix-timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Space separated list of v1 signatures",
+                        "name": "svix-signature",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -460,6 +2745,18 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content"
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -471,11 +2768,186 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "controller.CreateAPITokenRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner_uid": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controller.CreateAPITokenResponse": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "token": {
+                    "$ref": "#/definitions/model.APIToken"
+                }
+            }
+        },
+        "controller.TransitionRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "Reason is required to cancel an event",
+                    "type": "string",
+                    "example": "The venue is unavailable"
+                }
+            }
+        },
+        "model.APIToken": {
+            "type": "object",
+            "required": [
+                "name",
+                "owner_uid",
+                "scopes"
+            ],
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt (time.Time): The timestamp when the token was minted.",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt (*time.Time): When the token stops working, nil for never.",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID (uint64): The unique identifier of the token.",
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "description": "LastUsedAt (*time.Time): When the token was last used to authenticate.",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name (string): What the token is used for, e.g. \"CI deploy job\".",
+                    "type": "string",
+                    "minLength": 1
+                },
+                "org_id": {
+                    "description": "OrgID (string): The organization whose data the token can reach, empty for personal data.",
+                    "type": "string"
+                },
+                "owner_uid": {
+                    "description": "OwnerUid (string): The user the token acts as, linking to the User entity.",
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix (string): The first characters of the token, to recognize it in listings.",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "RevokedAt (*time.Time): When the token was revoked, nil while active.",
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "Scopes (string): Space separated list of granted scopes.",
+                    "type": "string"
+                }
+            }
+        },
+        "model.Attendance": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt (time.Time): The timestamp of the registration.",
+                    "type": "string"
+                },
+                "event_id": {
+                    "description": "EventID (uint64): The event, linking to the Event entity.",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID (uint64): The unique identifier of the registration, waitlisted users are promoted in its order.",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Status (string): going while the user has a seat, waitlisted while the event is full.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt (time.Time): The timestamp of the last status change, e.g. a promotion from the waitlist.",
+                    "type": "string"
+                },
+                "user_uid": {
+                    "description": "UserUid (string): The attendee, linking to the User entity.",
+                    "type": "string"
+                }
+            }
+        },
+        "model.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Action (string): What happened: create, update, delete, restore or purge.",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Actor (string): The user ID of the caller, or \"clerk\" for webhooks.",
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "Changes (AuditChanges): The changed fields with their value before and after.",
+                    "type": "object"
+                },
+                "created_at": {
+                    "description": "CreatedAt (time.Time): When the change was made.",
+                    "type": "string"
+                },
+                "entity": {
+                    "description": "Entity (string): The kind of record that changed, e.g. \"event\" or \"user\".",
+                    "type": "string"
+                },
+                "entity_id": {
+                    "description": "EntityID (string): The primary key of the record that changed.",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID (uint64): The unique identifier of the entry.",
+                    "type": "integer"
+                },
+                "ip": {
+                    "description": "IP (string): The client address of the request.",
+                    "type": "string"
+                },
+                "org_id": {
+                    "description": "OrgID (string): The organization of the record, empty for personal events and users without one.",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "RequestID (string): The X-Request-ID of the request that made the change.",
+                    "type": "string"
+                }
+            }
+        },
         "model.Event": {
             "type": "object",
             "properties": {
+                "all_day": {
+                    "description": "AllDay (bool): Whether the event lasts whole days, its times are then midnights in its time zone.",
+                    "type": "boolean"
+                },
                 "attendees_count": {
-                    "description": "AttendeesCount (uint): The current number of registered attendees.",
+                    "description": "AttendeesCount (uint): The number of attendees with a seat, maintained by registrations.",
                     "type": "integer"
                 },
                 "createdBy": {
@@ -486,18 +2958,33 @@ const docTemplate = `{
                     "description": "CreatedAt (time.Time): The timestamp when the event was created, automatically set.",
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "DeletedAt (gorm.DeletedAt): When the event was moved to the trash, null while it is live.",
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "description": "DeletedBy (string): The user ID of the person who moved the event to the trash.",
+                    "type": "string"
+                },
                 "description": {
                     "description": "Description (string): A brief explanation of what the event is about.",
                     "type": "string"
                 },
                 "end_time": {
-                    "description": "EndTime (time.Time): When the event is scheduled to end.",
+                    "description": "EndTime (*time.Time): When the event is scheduled to end, stored in UTC. The day after the last one for all-day events.",
                     "type": "string"
                 },
                 "event_type": {
                     "description": "EventType (string): The type or category of the event (e.g., webinar, in-person, hybrid).",
                     "type": "string"
                 },
+                "exdates": {
+                    "description": "ExDates ([]time.Time): The starts of the occurrences removed from the series.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "external_link": {
                     "description": "ExternalLink (string): Link to an external site related to the event (e.g., event registration page or official website).",
                     "type": "string"
@@ -508,7 +2995,11 @@ const docTemplate = `{
                 },
                 "images": {
                     "description": "Images ([]string): Array of image URLs associated with the event (e.g., event posters).",
-                    "type": "string"
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
                 },
                 "is_featured": {
                     "description": "IsFeatured (bool): Indicates whether this event is featured or highlighted on the platform.",
@@ -526,26 +3017,56 @@ const docTemplate = `{
                     "description": "MaxAttendees (uint): The maximum number of attendees allowed.",
                     "type": "integer"
                 },
+                "org_id": {
+                    "description": "OrgID (string): The organization (tenant) owning the event, empty for personal accounts.",
+                    "type": "string"
+                },
                 "organizer_contact_info": {
                     "description": "OrganizerContactInfo (string): Contact details for the event organizer.",
                     "type": "string"
                 },
+                "recurrence_id": {
+                    "description": "RecurrenceID (*time.Time): The start of the occurrence this event overrides, given by the rule of the series.",
+                    "type": "string"
+                },
+                "rrule": {
+                    "description": "RRule (string): The RFC 5545 recurrence rule of the event, starting at its start time. Empty when it doesn't recur.",
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
                 "rsvp_required": {
                     "description": "RSVPRequired (bool): Whether an RSVP is required to attend the event.",
                     "type": "boolean"
                 },
+                "series_id": {
+                    "description": "SeriesID (*uint64): The recurring event whose occurrence this event overrides, null otherwise.",
+                    "type": "integer"
+                },
                 "start_time": {
-                    "description": "StartTime (time.Time): When the event is scheduled to begin.",
+                    "description": "StartTime (*time.Time): When the event is scheduled to begin, stored in UTC. Null while unscheduled.",
                     "type": "string"
                 },
                 "status": {
-                    "description": "Status (string): The current state of the event (e.g., draft, published, cancelled).",
+                    "description": "Status (string): The lifecycle state of the event: draft, scheduled, published, live, completed, cancelled or postponed.",
                     "type": "string"
                 },
-                "tags": {
-                    "description": "Tags ([]string): For categorizing events using tags like \"conference\", \"workshop\", etc.",
+                "status_reason": {
+                    "description": "StatusReason (string): Why the event was cancelled or postponed, given with the last status change.",
                     "type": "string"
                 },
+                "tags": {
+                    "description": "Tags ([]string): For categorizing events using tags like \"conference\", \"workshop\", etc. Lower case letters, digits and dashes.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "time_zone": {
+                    "description": "TimeZone (string): The IANA time zone the event takes place in, UTC by default.",
+                    "type": "string",
+                    "example": "Europe/Paris"
+                },
                 "title": {
                     "description": "Title (string): The title of the event, required for easy identification.",
                     "type": "string"
@@ -557,6 +3078,43 @@ const docTemplate = `{
                 "updated_by": {
                     "description": "UpdatedBy (string): The user ID of the person who last updated the event.",
                     "type": "string"
+                },
+                "version": {
+                    "description": "Version (uint64): Incremented by every change, it is the ETag of the event.",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Occurrence": {
+            "type": "object",
+            "properties": {
+                "end_time": {
+                    "description": "EndTime (*time.Time): When the occurrence ends, null when the event has no end.",
+                    "type": "string",
+                    "example": "2024-11-04T09:15:00Z"
+                },
+                "event": {
+                    "description": "Event (Event): The series, or the event overriding this occurrence.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Event"
+                        }
+                    ]
+                },
+                "recurrence_id": {
+                    "description": "RecurrenceID (time.Time): The start of the occurrence given by the rule, which identifies it even when it was moved.",
+                    "type": "string",
+                    "example": "2024-11-04T09:00:00Z"
+                },
+                "series_id": {
+                    "description": "SeriesID (uint64): The recurring event, or the event itself when it doesn't recur.",
+                    "type": "integer",
+                    "example": 42
+                },
+                "start_time": {
+                    "description": "StartTime (time.Time): When the occurrence begins.",
+                    "type": "string",
+                    "example": "2024-11-04T09:00:00Z"
                 }
             }
         },
@@ -567,11 +3125,18 @@ const docTemplate = `{
                 "username"
             ],
             "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "type": "string"
+                },
                 "role": {
                     "type": "string",
                     "enum": [
                         "user",
-                        "admin"
+                        "admin",
+                        "superadmin"
                     ]
                 },
                 "uid": {
@@ -581,6 +3146,105 @@ const docTemplate = `{
                 "username": {
                     "type": "string",
                     "minLength": 1
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.Page-model_AuditEntry": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AuditEntry"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.Page-model_Event": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Event"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.Page-model_User": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.User"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.Page-repository_SearchResult": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.SearchResult"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.SearchResult": {
+            "type": "object",
+            "properties": {
+                "event": {
+                    "$ref": "#/definitions/model.Event"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "snippet": {
+                    "description": "Snippet is HTML: an escaped excerpt of the event with the matches in \u003cmark\u003e",
+                    "type": "string"
+                }
+            }
+        },
+        "repository.TagCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "tag": {
+                    "type": "string",
+                    "example": "workshop"
                 }
             }
         }
@@ -588,6 +3252,12 @@ const docTemplate = `{
     "securityDefinitions": {
         "BasicAuth": {
             "type": "basic"
+        },
+        "BearerAuth": {
+            "description": "Clerk session token as \"Bearer \u003ctoken\u003e\", the __session cookie is accepted as well",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -1,46 +1,42 @@
 package main
 
 import (
+	"context"
 	"gotempl/controller"
 	"gotempl/database"
 	"gotempl/middleware"
+	"gotempl/model"
 	"gotempl/repository"
 	"gotempl/service"
+	"gotempl/views/layout"
 	"os"
+	// Time zones of events and viewers, without depending on the system's database
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 
-	"github.com/clerk/clerk-sdk-go/v2"
-
 	docs "gotempl/docs"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-var clerkMiddleware middleware.ClerkPublicAuthMiddleware
+var authenticator middleware.Authenticator
 
 func init() {
-	// Load env secrets
+	// Load env secrets, the environment alone is enough when there is no .env file
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		panic("Error loading .env file")
 	}
 
-	apiKey := os.Getenv("CLERK_API_KEY")
-	if apiKey == "" {
-		panic("CLERK_API_KEY environment variable is not set")
-	}
-
-	// Load Secrets
-	err = clerkMiddleware.Init()
+	// Select the identity provider
+	authenticator, err = middleware.NewAuthenticatorFromEnv()
 	if err != nil {
 		panic(err)
-
 	}
-	clerk.SetKey(apiKey)
 
 	// Set logger to include the file and line number
 	logrus.SetReportCaller(true)
@@ -68,10 +64,20 @@ func init() {
 
 // @securityDefinitions.basic  BasicAuth
 
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Clerk session token as "Bearer <token>", the __session cookie is accepted as well
+
 // externalDocs.description  OpenAPI
 // externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
 	r := gin.Default()
+	// The client IP is recorded in the audit log, only trusted proxies may set it
+	if err := r.SetTrustedProxies(middleware.TrustedProxiesFromEnv()); err != nil {
+		logrus.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+	r.Use(middleware.RequestID())
 	docs.SwaggerInfo.BasePath = "/api/"
 
 	// Public routes
@@ -79,49 +85,148 @@ func main() {
 
 	r.Static("/public", "./public")
 
-	db := database.InitDB()
+	db, err := database.InitDB()
+	if err != nil {
+		logrus.Fatal("Failed to initialize the database: ", err)
+	}
+
+	auditRepo := repository.NewAuditRepository(db)
+	auditService := service.NewAuditService(auditRepo)
+	auditHandler := controller.NewAuditHandler(auditService)
+
+	orgRepo := repository.NewOrganizationRepository(db)
+	orgService := service.NewOrganizationService(orgRepo)
+	orgHandler := controller.NewOrganizationHandler(orgService)
 
 	userRepo := repository.NewUserRepository(db)
 	userService := service.NewUserService(userRepo)
+	userService.Audit = auditService
 	userHandler := controller.NewUserHandler(userService)
 
 	eventRepo := repository.NewEventRepository(db)
-	eventService := service.NewEventService(eventRepo)
+	eventService := service.NewEventService(eventRepo, repository.NewSearchRepository(db))
+	eventService.Audit = auditService
+	attendanceService := service.NewAttendanceService(eventRepo, repository.NewAttendanceRepository(db))
+	eventService.Attendance = attendanceService
 	eventHandler := controller.NewEventHandler(eventService)
+	attendanceHandler := controller.NewAttendanceHandler(attendanceService)
+
+	tokenRepo := repository.NewAPITokenRepository(db)
+	tokenService := service.NewAPITokenService(tokenRepo, userRepo)
+	tokenHandler := controller.NewAPITokenHandler(tokenService, userService)
+
+	authz := middleware.NewAuthorization(userRepo)
+	provisioner := middleware.NewProvisioner(userService, authenticator)
+	provisioner.Orgs = orgService
+	requireAuth := middleware.RequireAuth(authenticator, provisioner.Hook())
+	// API routes also accept personal access tokens
+	requireAPIAuth := middleware.RequireAPIAuth(middleware.NewTokenAuthenticator(tokenService, authenticator), provisioner.Hook())
+	requireMember := authz.RequireRole(middleware.RoleUser, middleware.RoleAdmin, middleware.RoleSuperAdmin)
+	requireAdmin := authz.RequireRole(middleware.RoleAdmin, middleware.RoleSuperAdmin)
+	requireSuperAdmin := authz.RequireRole(middleware.RoleSuperAdmin)
+	// Scope data to the caller's organization
+	resolveTenant := middleware.ResolveTenant()
+	// Cookie authenticated requests that change state must carry the CSRF token
+	csrf := middleware.CSRF()
+
+	readEvents := middleware.RequireScope(model.ScopeEventsRead)
+	writeEvents := middleware.RequireScope(model.ScopeEventsWrite)
+	adminUsers := middleware.RequireScope(model.ScopeUsersAdmin)
+	readAudit := middleware.RequireScope(model.ScopeAuditRead)
 
 	// Define routes
-	eventRoutes := r.Group("/api/event")
+	eventRoutes := r.Group("/api/event", csrf, requireAPIAuth, requireMember, resolveTenant)
 	{
-		eventRoutes.POST("/", eventHandler.CreateEvent)
-		eventRoutes.GET("/", eventHandler.GetAllEvents)
-		eventRoutes.GET("/:id", eventHandler.GetEvent)
-		eventRoutes.PUT("/:id", eventHandler.UpdateEvent)
-		eventRoutes.DELETE("/:id", eventHandler.DeleteEvent)
+		eventHandler.Register(eventRoutes, controller.Routes{
+			Read:   []gin.HandlerFunc{readEvents},
+			Write:  []gin.HandlerFunc{writeEvents},
+			Delete: []gin.HandlerFunc{writeEvents, requireAdmin},
+		})
+		eventRoutes.GET("/search", readEvents, eventHandler.SearchEvents)
+		eventRoutes.GET("/trash", readEvents, requireAdmin, eventHandler.GetTrash)
+		eventRoutes.POST("/:id/restore", writeEvents, requireAdmin, eventHandler.RestoreEvent)
+		eventRoutes.DELETE("/:id/purge", writeEvents, requireAdmin, eventHandler.PurgeEvent)
+		for action := range service.EventTransitions {
+			eventRoutes.POST("/:id/"+action, writeEvents, eventHandler.TransitionEvent)
+		}
+		attendanceHandler.Register(eventRoutes, controller.Routes{
+			Read:  []gin.HandlerFunc{readEvents},
+			Write: []gin.HandlerFunc{writeEvents},
+		})
+		eventRoutes.GET("/:id/occurrences", readEvents, eventHandler.GetOccurrences)
+		eventRoutes.PATCH("/:id/occurrences/:start", writeEvents, eventHandler.EditOccurrence)
+		eventRoutes.DELETE("/:id/occurrences/:start", writeEvents, requireAdmin, eventHandler.DeleteOccurrence)
 	}
 
+	r.GET("/api/tags", csrf, requireAPIAuth, requireMember, resolveTenant, readEvents, eventHandler.GetTags)
+
 	// User routes
-	userRoutes := r.Group("/api/user")
+	userRoutes := r.Group("/api/user", csrf, requireAPIAuth, requireMember, adminUsers, resolveTenant)
+	{
+		userHandler.CRUD.Register(userRoutes, controller.Routes{
+			Write:  []gin.HandlerFunc{requireAdmin},
+			Delete: []gin.HandlerFunc{requireAdmin},
+		})
+		userRoutes.GET("/trash", requireAdmin, userHandler.GetTrash)
+		userRoutes.POST("/:id/restore", requireAdmin, userHandler.RestoreUser)
+		userRoutes.DELETE("/:id/purge", requireAdmin, userHandler.PurgeUser)
+
+	}
+
+	// API token routes
+	tokenRoutes := r.Group("/api/token", csrf, requireAPIAuth, requireAdmin, adminUsers, resolveTenant)
 	{
-		userRoutes.POST("/", userHandler.CreateUser)
-		userRoutes.GET("/", userHandler.GetAllUsers)
-		userRoutes.GET("/:id", userHandler.GetUser)
-		userRoutes.PUT("/:id", userHandler.UpdateUser)
-		userRoutes.DELETE("/:id", userHandler.DeleteUser)
+		tokenRoutes.POST("/", tokenHandler.CreateToken)
+		tokenRoutes.GET("/", tokenHandler.GetAllTokens)
+		tokenRoutes.DELETE("/:id", tokenHandler.RevokeToken)
+	}
 
+	// Audit log routes
+	auditRoutes := r.Group("/api/audit", csrf, requireAPIAuth, requireAdmin, readAudit, resolveTenant)
+	{
+		auditRoutes.GET("/", auditHandler.GetAuditLog)
 	}
 
-	adminRoutes := r.Group("/admin", clerkMiddleware.ClerkAuthMiddleware())
+	adminRoutes := r.Group("/admin", csrf, middleware.ViewerTimeZone(), requireAuth, requireMember, resolveTenant)
 	{
 
 		adminRoutes.GET("/", controller.HomeHandler)
-		adminRoutes.GET("/user", userHandler.UserCRUDHandler)
+		adminRoutes.GET("/user", requireAdmin, userHandler.UserCRUDHandler)
+		adminRoutes.GET("/user/trash", requireAdmin, userHandler.UserTrashHandler)
 		adminRoutes.GET("/event", eventHandler.EventCRUDHandler)
+		adminRoutes.GET("/event/trash", requireAdmin, eventHandler.EventTrashHandler)
+		adminRoutes.GET("/token", requireAdmin, tokenHandler.TokenCRUDHandler)
+		adminRoutes.GET("/audit", requireAdmin, auditHandler.AuditLogHandler)
+		adminRoutes.GET("/tenant", requireSuperAdmin, orgHandler.TenantSwitchHandler)
+		adminRoutes.POST("/tenant", requireSuperAdmin, orgHandler.SwitchTenant)
 
 	}
 
-	r.GET("/sign-in", controller.LoginHandler)
+	if devAuth, ok := authenticator.(*middleware.DevAuthenticator); ok {
+		if err := devAuth.EnsureUser(context.Background(), userRepo); err != nil {
+			logrus.Fatal("Failed to create the development user:", err)
+		}
+
+		layout.AuthProvider = middleware.ProviderDev
+		devLoginHandler := controller.NewDevLoginHandler(devAuth)
+		r.GET("/sign-in", csrf, devLoginHandler.LoginForm)
+		r.POST("/sign-in", csrf, devLoginHandler.Login)
+		r.POST("/sign-out", csrf, devLoginHandler.Logout)
+	} else {
+		r.GET("/sign-in", controller.LoginHandler)
+	}
+
+	// Keep the users table in sync with Clerk, even for users who never sign in
+	if secret := os.Getenv("CLERK_WEBHOOK_SECRET"); secret != "" {
+		verifier, err := middleware.NewSvixVerifier(secret)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		webhookHandler := controller.NewWebhookHandler(userService)
+		r.POST("/webhooks/clerk", middleware.VerifyWebhook(verifier), webhookHandler.ClerkWebhook)
+	}
 
-	r.GET("/swagger/*any", clerkMiddleware.ClerkAuthMiddleware(), ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/swagger/*any", requireAuth, requireMember, ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Run the server
 	r.Run()
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"gotempl/views"
+	"gotempl/views/layout"
+	"net/http"
+	"os"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ProviderClerk = "clerk"
+	ProviderDev   = "dev"
+)
+
+// Authenticator verifies the credentials carried by a request. Routes depend on
+// this interface so the identity provider can be selected by configuration.
+type Authenticator interface {
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+// AuthHook runs after a successful authentication, before the route handlers
+type AuthHook func(c *gin.Context, principal *Principal) error
+
+// AuthError is returned by authenticators to control the response status
+type AuthError struct {
+	Status  int
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+var ErrNotAuthenticated = &AuthError{Status: http.StatusUnauthorized, Message: "Access denied: authentication is needed"}
+
+// NewAuthenticatorFromEnv builds the provider selected by AUTH_PROVIDER,
+// defaulting to Clerk
+func NewAuthenticatorFromEnv() (Authenticator, error) {
+	switch provider := os.Getenv("AUTH_PROVIDER"); provider {
+	case "", ProviderClerk:
+		apiKey := os.Getenv("CLERK_API_KEY")
+		if apiKey == "" {
+			return nil, errors.New("CLERK_API_KEY environment variable is not set")
+		}
+
+		cpam := &ClerkPublicAuthMiddleware{}
+		if err := cpam.Init(); err != nil {
+			return nil, err
+		}
+		clerk.SetKey(apiKey)
+		return cpam, nil
+	case ProviderDev:
+		return NewDevAuthenticatorFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown AUTH_PROVIDER %q", provider)
+	}
+}
+
+// Middleware for authentication of HTML pages, failures render the error page
+func RequireAuth(auth Authenticator, hooks ...AuthHook) gin.HandlerFunc {
+	return authMiddleware(auth, hooks, func(c *gin.Context, status int, msg string) {
+		layout.Render(c, status, views.Error500(msg))
+		c.Abort()
+	})
+}
+
+// Middleware for authentication of API routes, failures are returned as JSON
+func RequireAPIAuth(auth Authenticator, hooks ...AuthHook) gin.HandlerFunc {
+	return authMiddleware(auth, hooks, func(c *gin.Context, status int, msg string) {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg})
+	})
+}
+
+func authMiddleware(auth Authenticator, hooks []AuthHook, fail func(c *gin.Context, status int, msg string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := auth.Authenticate(c)
+		if err != nil {
+			var authErr *AuthError
+			if errors.As(err, &authErr) {
+				fail(c, authErr.Status, authErr.Message)
+			} else {
+				fail(c, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		if principal.Subject == "" {
+			fail(c, http.StatusForbidden, "Access denied: token has no subject")
+			return
+		}
+
+		for _, hook := range hooks {
+			if err := hook(c, principal); err != nil {
+				log.Error("Error:", err)
+				fail(c, http.StatusInternalServerError, "Failed to load the authenticated user")
+				return
+			}
+		}
+
+		c.Set(SubjectKey, principal.Subject)
+		c.Set(PrincipalKey, principal)
+		c.Next()
+	}
+}
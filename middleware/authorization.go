@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"errors"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/views"
+	"gotempl/views/layout"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Context keys set by the authentication and authorization middlewares
+const (
+	SubjectKey     = "subject"
+	PrincipalKey   = "principal"
+	RoleKey        = "role"
+	CurrentUserKey = "currentUser"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleSuperAdmin can see and switch between every organization
+	RoleSuperAdmin = "superadmin"
+)
+
+// Authorization maps the authenticated subject to a local model.User and
+// enforces the roles declared on each route
+type Authorization struct {
+	Users repository.UserRepository
+}
+
+func NewAuthorization(users repository.UserRepository) *Authorization {
+	return &Authorization{Users: users}
+}
+
+// RequireRole only lets the request through when the authenticated user has
+// one of the given roles. It must run after an authentication middleware.
+func (a *Authorization) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		usr, err := a.resolve(c)
+		if err != nil {
+			if errors.Is(err, errNoSubject) {
+				abortWithError(c, http.StatusUnauthorized, "Access denied: authentication is needed")
+			} else if errors.Is(err, gorm.ErrRecordNotFound) {
+				abortWithError(c, http.StatusForbidden, "Access denied: user is not registered")
+			} else {
+				log.Error("Error:", err)
+				abortWithError(c, http.StatusInternalServerError, "Failed to resolve user role")
+			}
+			return
+		}
+
+		for _, role := range roles {
+			if usr.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		abortWithError(c, http.StatusForbidden, "Access denied: insufficient role")
+	}
+}
+
+var errNoSubject = errors.New("no authenticated subject")
+
+func (a *Authorization) resolve(c *gin.Context) (*model.User, error) {
+	if usr, ok := c.Get(CurrentUserKey); ok {
+		return usr.(*model.User), nil
+	}
+
+	subject := c.GetString(SubjectKey)
+	if subject == "" {
+		return nil, errNoSubject
+	}
+
+	usr, err := a.Users.GetByID(c.Request.Context(), subject)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set(CurrentUserKey, usr)
+	c.Set(RoleKey, usr.Role)
+	if principal := CurrentPrincipal(c); principal != nil {
+		principal.Role = usr.Role
+	}
+	return usr, nil
+}
+
+// roleRanks orders the roles, a role can do everything the lower ones can
+var roleRanks = map[string]int{RoleUser: 1, RoleAdmin: 2, RoleSuperAdmin: 3}
+
+// RoleRank returns the rank of the role, 0 for unknown roles
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
+
+// CurrentRole returns the role resolved for the request, if any
+func CurrentRole(c *gin.Context) string {
+	return c.GetString(RoleKey)
+}
+
+// API routes reply with JSON, everything else gets the HTML error page
+func abortWithError(c *gin.Context, status int, msg string) {
+	if isAPIRequest(c) {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg})
+		return
+	}
+	layout.Render(c, status, views.Error500(msg))
+	c.Abort()
+}
+
+func isAPIRequest(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, "/api/")
+}
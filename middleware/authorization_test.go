@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"encoding/json"
+	"gotempl/model"
+	"gotempl/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAuthorization(t *testing.T) (*gorm.DB, *Authorization) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&model.User{})
+	assert.NoError(t, err)
+
+	testUsers := []model.User{
+		{Uid: "admin-uid", Username: "admin", Role: RoleAdmin},
+		{Uid: "user-uid", Username: "user", Role: RoleUser},
+	}
+	for _, user := range testUsers {
+		db.Create(&user)
+	}
+
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	return db, NewAuthorization(repository.NewUserRepository(db))
+}
+
+// fakeAuthentication stands in for the Clerk middleware
+func fakeAuthentication(subject string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subject != "" {
+			c.Set(SubjectKey, subject)
+		}
+		c.Next()
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	_, authz := setupAuthorization(t)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		subject string
+		path    string
+		status  int
+		json    bool
+	}{
+		{"admin can reach admin page", "admin-uid", "/admin/user", http.StatusOK, false},
+		{"user is rejected from admin page", "user-uid", "/admin/user", http.StatusForbidden, false},
+		{"unknown user is rejected from admin page", "unknown-uid", "/admin/user", http.StatusForbidden, false},
+		{"admin can delete through the API", "admin-uid", "/api/event/1", http.StatusOK, true},
+		{"user is rejected from API delete", "user-uid", "/api/event/1", http.StatusForbidden, true},
+		{"missing subject is unauthorized", "", "/api/event/1", http.StatusUnauthorized, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			var role string
+			handler := func(c *gin.Context) {
+				role = CurrentRole(c)
+				c.Status(http.StatusOK)
+			}
+			router.GET("/admin/user", fakeAuthentication(tt.subject), authz.RequireRole(RoleAdmin), handler)
+			router.DELETE("/api/event/:id", fakeAuthentication(tt.subject), authz.RequireRole(RoleAdmin), handler)
+
+			method := http.MethodGet
+			if tt.json {
+				method = http.MethodDelete
+			}
+			req, _ := http.NewRequest(method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, RoleAdmin, role)
+				return
+			}
+
+			if tt.json {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.NotEmpty(t, body["error"])
+			} else {
+				assert.Contains(t, w.Body.String(), "Access denied")
+			}
+		})
+	}
+}
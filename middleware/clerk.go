@@ -1,119 +1,143 @@
 package middleware
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"gotempl/views"
-	"gotempl/views/layout"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
-	clerkjwt "github.com/clerk/clerk-sdk-go/v2/jwt"
+	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/user"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/joho/godotenv"
 )
 
 type ClerkPublicAuthMiddleware struct {
 	JwtPublicSigningKey string
+
+	// JWKS is the primary source of signing keys, the PEM key is only used as a
+	// fallback when it is configured
+	JWKS *JWKSKeySet
+
+	publicKey *rsa.PublicKey
 }
 
 func (c *ClerkPublicAuthMiddleware) Init() error {
-
-	err := godotenv.Load()
-	if err != nil {
-		return err
+	var err error
+	if jwksURL := os.Getenv("JWT_JWKS_URL"); jwksURL != "" {
+		ttl := defaultJWKSTTL
+		if rawTTL := os.Getenv("JWT_JWKS_TTL"); rawTTL != "" {
+			ttl, err = time.ParseDuration(rawTTL)
+			if err != nil {
+				return fmt.Errorf("invalid JWT_JWKS_TTL: %v", err)
+			}
+		}
+		c.JWKS = NewJWKSKeySet(jwksURL, ttl)
 	}
 
 	keyPath := os.Getenv("JWT_PUBLIC_KEY_PATH")
 	if keyPath != "" {
 		key, err := os.ReadFile(keyPath)
-		if err == nil {
-			c.JwtPublicSigningKey = string(key)
-			return nil
+		if err != nil {
+			return err
 		}
-		return err
+		return c.SetPublicKey(key)
 	}
-	return errors.New("unable to load JWT_PUBLIC_KEY_PATH from .env file")
-}
-
-// Middleware for authentication
-func (cpam *ClerkPublicAuthMiddleware) ClerkAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		cookie, err := c.Cookie("__session")
-		if err != nil || cookie == "" {
-			layout.Render(c, http.StatusForbidden, views.Error500("Access denied: authentication is needed"))
-			c.Abort()
-			return
-		}
-
-		// Extract session token from the cookie
-		sessionToken := strings.TrimSpace(cookie)
 
-		ret, err := cpam.VerifyTokenLocal(sessionToken)
-		if err != nil {
-			layout.Render(c, http.StatusForbidden, views.Error500(fmt.Sprintf("Access denied: %s (1001)", err.Error())))
-			c.Abort()
-			return
-		}
-		if ret.Valid {
-			c.Next()
-			return
+	if c.JWKS != nil {
+		return nil
+	}
+	return errors.New("unable to load JWT_JWKS_URL or JWT_PUBLIC_KEY_PATH ")
+}
 
-		}
+// SetPublicKey parses and stores the PEM encoded fallback signing key
+func (c *ClerkPublicAuthMiddleware) SetPublicKey(pemKey []byte) error {
+	publicKey, err := c.ParseRSAPublicKey(pemKey)
+	if err != nil {
+		return err
+	}
+	c.JwtPublicSigningKey = string(pemKey)
+	c.publicKey = publicKey
+	return nil
+}
 
-		// Verify the session
-		claims, err := clerkjwt.Verify(c.Request.Context(), &clerkjwt.VerifyParams{
-			Token:  sessionToken,
-			Leeway: 10 * time.Second,
-		})
-		if err != nil {
-			layout.Render(c, http.StatusForbidden, views.Error500(fmt.Sprintf("Access denied: %v (1002)", err)))
+// Authenticate verifies the Clerk session token carried by the request. It is
+// verified locally, Clerk isn't called: banning a user revokes their sessions
+// at Clerk, which stops issuing them tokens, so a banned user is locked out once
+// their last session token expires (a minute by default).
+func (cpam *ClerkPublicAuthMiddleware) Authenticate(c *gin.Context) (*Principal, error) {
+	sessionToken := SessionToken(c)
+	if sessionToken == "" {
+		return nil, ErrNotAuthenticated
+	}
 
-			c.Abort()
-			return
-		}
+	token, err := cpam.VerifyTokenLocal(c.Request.Context(), sessionToken)
+	if err != nil {
+		return nil, &AuthError{Status: http.StatusForbidden, Message: fmt.Sprintf("Access denied: %s (1001)", err.Error())}
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, &AuthError{Status: http.StatusForbidden, Message: "Access denied: unexpected claims (1001)"}
+	}
+	return principalFromClaims(claims), nil
+}
 
-		// Get user information
-		usr, err := user.Get(c.Request.Context(), claims.Subject)
-		if err != nil {
-			layout.Render(c, http.StatusInternalServerError, views.Error500(fmt.Sprintf("%v (1002)", err)))
-			c.Abort()
-			return
-		}
+// ResolveUsername fetches the Clerk user record to name users whose session
+// token doesn't carry a username
+func (cpam *ClerkPublicAuthMiddleware) ResolveUsername(ctx context.Context, subject string) (string, error) {
+	usr, err := user.Get(ctx, subject)
+	if err != nil {
+		return "", err
+	}
+	return ClerkUsername(usr), nil
+}
 
-		// Check if the user is banned
-		if usr.Banned {
-			layout.Render(c, http.StatusForbidden, views.Error500("Access denied: user is banned"))
-			c.Abort()
-			return
+// ClerkUsername returns the Clerk username, falling back to the primary email address
+func ClerkUsername(usr *clerk.User) string {
+	if usr.Username != nil && *usr.Username != "" {
+		return *usr.Username
+	}
+	for _, email := range usr.EmailAddresses {
+		if usr.PrimaryEmailAddressID != nil && email.ID == *usr.PrimaryEmailAddressID {
+			return email.EmailAddress
 		}
-
-		// Set user info in context for use in other controller
-		c.Set("user", usr)
-
-		c.Next()
 	}
+	if len(usr.EmailAddresses) > 0 {
+		return usr.EmailAddresses[0].EmailAddress
+	}
+	return ""
 }
 
-func (c *ClerkPublicAuthMiddleware) VerifyTokenLocal(tokenString string) (*jwt.Token, error) {
-
-	publicKey, err := c.ParseRSAPublicKey([]byte(c.JwtPublicSigningKey))
-	if err != nil {
-		fmt.Errorf("Failed to parse public key: %v", err)
+// SessionToken extracts the session token from the Authorization bearer header
+// or, for browser requests, from the __session cookie
+func SessionToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
 	}
 
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		return publicKey, nil
+	cookie, err := c.Cookie("__session")
+	if err != nil {
+		return ""
 	}
+	return strings.TrimSpace(cookie)
+}
 
-	token, err := jwt.Parse(tokenString, keyFunc)
+// VerifyTokenLocal verifies the token's signature and claims without calling
+// Clerk, the JWKS is only fetched within ctx
+func (c *ClerkPublicAuthMiddleware) VerifyTokenLocal(ctx context.Context, tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return c.signingKey(ctx, token)
+	})
 	if err != nil {
 		// Check if the error is because the token is expired
 		if ve, ok := err.(*jwt.ValidationError); ok {
@@ -131,6 +155,30 @@ func (c *ClerkPublicAuthMiddleware) VerifyTokenLocal(tokenString string) (*jwt.T
 	return token, nil
 }
 
+// Select the key used to verify the token: the JWKS key matching the token's kid,
+// or the PEM key when no JWKS is configured or it doesn't know the key
+func (c *ClerkPublicAuthMiddleware) signingKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	if c.JWKS != nil {
+		kid, _ := token.Header["kid"].(string)
+		key, err := c.JWKS.Key(ctx, kid)
+		if err == nil {
+			return key, nil
+		}
+		if c.publicKey == nil {
+			return nil, err
+		}
+	}
+
+	if c.publicKey == nil {
+		return nil, errors.New("no signing key configured")
+	}
+	return c.publicKey, nil
+}
+
 // Parse the RSA public key from the PEM-encoded data
 func (c *ClerkPublicAuthMiddleware) ParseRSAPublicKey(pemKey []byte) (*rsa.PublicKey, error) {
 	block, _ := pem.Decode(pemKey)
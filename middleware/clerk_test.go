@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIAuthWithClerk(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+	cpam := ClerkPublicAuthMiddleware{JWKS: NewJWKSKeySet(server.URL, time.Hour)}
+	validToken := signTestToken(t, key, "kid-1", testClaims())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/event/", RequireAPIAuth(&cpam), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"subject": c.GetString(SubjectKey)})
+	})
+
+	tests := []struct {
+		name   string
+		setup  func(req *http.Request)
+		status int
+	}{
+		{"bearer token", func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+validToken)
+		}, http.StatusOK},
+		{"session cookie", func(req *http.Request) {
+			req.AddCookie(&http.Cookie{Name: "__session", Value: validToken})
+		}, http.StatusOK},
+		{"missing token", func(req *http.Request) {}, http.StatusUnauthorized},
+		{"unsupported authorization scheme", func(req *http.Request) {
+			req.Header.Set("Authorization", "Basic "+validToken)
+		}, http.StatusUnauthorized},
+		{"invalid token", func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, generateTestKey(t), "kid-1", testClaims()))
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/event/", nil)
+			tt.setup(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+			var body map[string]string
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "user_123", body["subject"])
+			} else {
+				assert.NotEmpty(t, body["error"])
+			}
+		})
+	}
+}
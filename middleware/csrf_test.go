@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"gotempl/views/layout"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCSRFRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, layout.CSRFToken(c.Request.Context()))
+	}
+	router.GET("/admin/", CSRF(), handler)
+	router.POST("/admin/tenant", CSRF(), handler)
+	router.DELETE("/api/event/:id", CSRF(), handler)
+	return router
+}
+
+// fetchCSRFToken loads a page to get the cookie and the token exposed to templates
+func fetchCSRFToken(t *testing.T, router *gin.Engine) (*http.Cookie, string) {
+	req, _ := http.NewRequest(http.MethodGet, "/admin/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == CSRFCookie {
+			cookie = c
+		}
+	}
+	assert.NotNil(t, cookie)
+	assert.Equal(t, cookie.Value, w.Body.String())
+	return cookie, w.Body.String()
+}
+
+func TestCSRFAcceptsMatchingToken(t *testing.T) {
+	router := setupCSRFRouter()
+	cookie, token := fetchCSRFToken(t, router)
+
+	// Header, as sent by fetch and htmx
+	req, _ := http.NewRequest(http.MethodDelete, "/api/event/1", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(CSRFHeader, token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Form field, as sent by plain HTML forms
+	form := url.Values{CSRFFormField: {token}, "org": {"org_a"}}
+	req, _ = http.NewRequest(http.MethodPost, "/admin/tenant", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFRejectsMissingOrInvalidToken(t *testing.T) {
+	router := setupCSRFRouter()
+	cookie, _ := fetchCSRFToken(t, router)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/event/1", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"error"`)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api/event/1", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(CSRFHeader, "forged")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Without the cookie the attacker can't know the token
+	req, _ = http.NewRequest(http.MethodPost, "/admin/tenant", nil)
+	req.Header.Set(CSRFHeader, cookie.Value)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "CSRF")
+}
+
+func TestCSRFIgnoresBearerRequests(t *testing.T) {
+	router := setupCSRFRouter()
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/event/1", nil)
+	req.Header.Set("Authorization", "Bearer gtp_token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
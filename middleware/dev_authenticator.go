@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gotempl/model"
+	"gotempl/repository"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const devSessionTTL = 12 * time.Hour
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// DevAuthenticator signs in as a single configured local user from a login
+// form. It lets the server run without any external identity provider and
+// must not be used in production.
+type DevAuthenticator struct {
+	Uid      string
+	Username string
+	Role     string
+	// OrgID is the active organization of the session, optional
+	OrgID string
+	// Password is optional, any password is accepted when it is empty
+	Password string
+
+	secret []byte
+}
+
+func NewDevAuthenticator(uid, username, role, password string, secret []byte) (*DevAuthenticator, error) {
+	if uid == "" || username == "" {
+		return nil, errors.New("dev authenticator needs a uid and a username")
+	}
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &DevAuthenticator{
+		Uid:      uid,
+		Username: username,
+		Role:     role,
+		Password: password,
+		secret:   secret,
+	}, nil
+}
+
+func NewDevAuthenticatorFromEnv() (*DevAuthenticator, error) {
+	auth, err := NewDevAuthenticator(
+		envOrDefault("DEV_AUTH_UID", "dev-user"),
+		envOrDefault("DEV_AUTH_USERNAME", "dev"),
+		envOrDefault("DEV_AUTH_ROLE", RoleAdmin),
+		os.Getenv("DEV_AUTH_PASSWORD"),
+		[]byte(os.Getenv("DEV_AUTH_SECRET")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	auth.OrgID = os.Getenv("DEV_AUTH_ORG")
+	return auth, nil
+}
+
+// EnsureUser creates the configured user in the users table if it is missing
+func (d *DevAuthenticator) EnsureUser(ctx context.Context, users repository.UserRepository) error {
+	_, err := users.GetByID(ctx, d.Uid)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return users.Create(ctx, &model.User{Uid: d.Uid, Username: d.Username, Role: d.Role})
+}
+
+// SignIn checks the credentials and returns a signed session token
+func (d *DevAuthenticator) SignIn(username, password string) (string, error) {
+	if username != d.Username {
+		return "", ErrInvalidCredentials
+	}
+	if d.Password != "" && subtle.ConstantTimeCompare([]byte(password), []byte(d.Password)) != 1 {
+		return "", ErrInvalidCredentials
+	}
+	return d.sign(d.Uid, time.Now().Add(devSessionTTL)), nil
+}
+
+// Authenticate verifies the session token issued by SignIn
+func (d *DevAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	token := SessionToken(c)
+	if token == "" {
+		return nil, ErrNotAuthenticated
+	}
+
+	subject, expiresAt, err := d.verify(token)
+	if err != nil {
+		return nil, &AuthError{Status: http.StatusForbidden, Message: fmt.Sprintf("Access denied: %s", err.Error())}
+	}
+	return &Principal{Subject: subject, Username: d.Username, OrgID: d.OrgID, ExpiresAt: expiresAt}, nil
+}
+
+func (d *DevAuthenticator) sign(subject string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(subject + "|" + strconv.FormatInt(expiresAt.Unix(), 10)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(d.mac(payload))
+}
+
+func (d *DevAuthenticator) verify(token string) (string, time.Time, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", time.Time{}, errors.New("malformed token")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, d.mac(payload)) {
+		return "", time.Time{}, errors.New("token is invalid")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", time.Time{}, errors.New("malformed token")
+	}
+	subject, rawExpiry, found := strings.Cut(string(raw), "|")
+	if !found {
+		return "", time.Time{}, errors.New("malformed token")
+	}
+	expiry, err := strconv.ParseInt(rawExpiry, 10, 64)
+	if err != nil {
+		return "", time.Time{}, errors.New("malformed token")
+	}
+	expiresAt := time.Unix(expiry, 0)
+	if time.Now().After(expiresAt) {
+		return "", time.Time{}, errors.New("token is expired")
+	}
+	return subject, expiresAt, nil
+}
+
+func (d *DevAuthenticator) mac(payload string) []byte {
+	h := hmac.New(sha256.New, d.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevAuthenticatorSignIn(t *testing.T) {
+	auth, err := NewDevAuthenticator("dev-user", "dev", RoleAdmin, "secret", nil)
+	assert.NoError(t, err)
+
+	_, err = auth.SignIn("someone-else", "secret")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = auth.SignIn("dev", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	token, err := auth.SignIn("dev", "secret")
+	assert.NoError(t, err)
+
+	subject, expiresAt, err := auth.verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "dev-user", subject)
+	assert.True(t, expiresAt.After(time.Now()))
+}
+
+func TestDevAuthenticatorRejectsForgedTokens(t *testing.T) {
+	auth, err := NewDevAuthenticator("dev-user", "dev", RoleAdmin, "", []byte("secret"))
+	assert.NoError(t, err)
+
+	other, err := NewDevAuthenticator("dev-user", "dev", RoleAdmin, "", []byte("other-secret"))
+	assert.NoError(t, err)
+
+	forged, err := other.SignIn("dev", "")
+	assert.NoError(t, err)
+	_, _, err = auth.verify(forged)
+	assert.EqualError(t, err, "token is invalid")
+
+	_, _, err = auth.verify(auth.sign("dev-user", time.Now().Add(-time.Minute)))
+	assert.EqualError(t, err, "token is expired")
+
+	_, _, err = auth.verify("not-a-token")
+	assert.Error(t, err)
+}
+
+func TestRequireAuthWithDevAuthenticator(t *testing.T) {
+	auth, err := NewDevAuthenticator("dev-user", "dev", RoleAdmin, "", nil)
+	assert.NoError(t, err)
+	token, err := auth.SignIn("dev", "")
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/", RequireAuth(auth), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(SubjectKey))
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/", nil)
+	req.AddCookie(&http.Cookie{Name: "__session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "dev-user", w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/admin/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultJWKSTTL             = time.Hour
+	defaultJWKSRefreshInterval = 5 * time.Second
+	defaultJWKSTimeout         = 10 * time.Second
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// JWKSKeySet fetches and caches the JSON Web Key Set published by the identity
+// provider. The set is refreshed when the TTL expires or when a token refers to
+// a kid that is not in the cache, so key rotations are picked up without a redeploy.
+type JWKSKeySet struct {
+	URL        string
+	TTL        time.Duration
+	HTTPClient *http.Client
+
+	// MinRefreshInterval rate limits the refreshes made by Key, it runs from the
+	// last attempt whether it succeeded or not
+	MinRefreshInterval time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	attemptErr  error
+	// refreshing is held by the refresh in progress, see refresh
+	refreshing chan struct{}
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+func NewJWKSKeySet(url string, ttl time.Duration) *JWKSKeySet {
+	if ttl <= 0 {
+		ttl = defaultJWKSTTL
+	}
+	return &JWKSKeySet{
+		URL:                url,
+		TTL:                ttl,
+		HTTPClient:         &http.Client{Timeout: defaultJWKSTimeout},
+		MinRefreshInterval: defaultJWKSRefreshInterval,
+	}
+}
+
+// Key returns the public key matching kid, refreshing the cached set when it is
+// stale or does not contain the requested key.
+func (k *JWKSKeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.mu.RLock()
+	key, found := k.lookup(kid)
+	expired := time.Since(k.fetchedAt) > k.TTL
+	k.mu.RUnlock()
+
+	if found && !expired {
+		return key, nil
+	}
+
+	if err := k.refresh(ctx); err != nil {
+		// Keep serving the last known key if the provider is temporarily unreachable
+		if found {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key, found := k.lookup(kid); found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownSigningKey, kid)
+}
+
+// refresh calls Refresh unless it was attempted within MinRefreshInterval or
+// while waiting for another caller's refresh, in which case the error of that
+// attempt is returned. Only one refresh runs at a time, so a burst of tokens with
+// unknown kids makes a single request to the provider.
+func (k *JWKSKeySet) refresh(ctx context.Context) error {
+	k.mu.Lock()
+	if k.refreshing == nil {
+		k.refreshing = make(chan struct{}, 1)
+	}
+	refreshing := k.refreshing
+	attemptedAt := k.attemptedAt
+	k.mu.Unlock()
+
+	select {
+	case refreshing <- struct{}{}:
+		defer func() { <-refreshing }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	k.mu.RLock()
+	attempted := !k.attemptedAt.Equal(attemptedAt) || time.Since(k.attemptedAt) < k.MinRefreshInterval
+	err := k.attemptErr
+	k.mu.RUnlock()
+	if attempted {
+		return err
+	}
+
+	err = k.Refresh(ctx)
+	if ctx.Err() != nil {
+		// The caller went away, that says nothing about the provider
+		return err
+	}
+	k.mu.Lock()
+	k.attemptedAt = time.Now()
+	k.attemptErr = err
+	k.mu.Unlock()
+	return err
+}
+
+// Refresh downloads the key set and replaces the cached keys. Keys that are not
+// RSA signing keys or can't be parsed are skipped, the set is only rejected
+// when none is left.
+func (k *JWKSKeySet) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := k.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAJWK(jwk)
+		if err != nil {
+			log.Warn("Skipping JWKS key: ", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return errors.New("JWKS does not contain any usable RSA signing keys")
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+// lookup must be called with the lock held. An empty kid only matches when the
+// set holds a single key, which is how providers without kids behave.
+func (k *JWKSKeySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func parseRSAJWK(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus for key %q: %v", jwk.Kid, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent for key %q: %v", jwk.Kid, err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() <= 1 {
+		return nil, fmt.Errorf("invalid exponent for key %q", jwk.Kid)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
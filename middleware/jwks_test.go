@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type testJWKSServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests int32
+	// extra keys are published as they are, fail answers 500 instead of the set
+	// and delay holds every answer back
+	extra []jsonWebKey
+	fail  bool
+	delay time.Duration
+}
+
+func newTestJWKSServer(t *testing.T) *testJWKSServer {
+	s := &testJWKSServer{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		time.Sleep(s.delay)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		set := jsonWebKeySet{Keys: append([]jsonWebKey{}, s.extra...)}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotate replaces the published keys with a single new key
+func (s *testJWKSServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	key := generateTestKey(t)
+	s.mu.Lock()
+	s.keys = map[string]*rsa.PrivateKey{kid: key}
+	s.mu.Unlock()
+	return key
+}
+
+func generateTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return key
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "user_123",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func encodePublicKeyPEM(t *testing.T, key *rsa.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyTokenLocalWithJWKS(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+
+	cpam := ClerkPublicAuthMiddleware{JWKS: NewJWKSKeySet(server.URL, time.Hour)}
+
+	token, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+	assert.True(t, token.Valid)
+
+	// The key set is cached between requests
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests))
+
+	// Tokens signed by an unpublished key are rejected
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, generateTestKey(t), "kid-1", testClaims()))
+	assert.Error(t, err)
+
+	// Expired tokens are rejected
+	expired := testClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", expired))
+	assert.EqualError(t, err, "token is expired")
+}
+
+func TestVerifyTokenLocalRefreshesOnUnknownKid(t *testing.T) {
+	server := newTestJWKSServer(t)
+	oldKey := server.rotate(t, "kid-1")
+
+	keySet := NewJWKSKeySet(server.URL, time.Hour)
+	keySet.MinRefreshInterval = 0
+	cpam := ClerkPublicAuthMiddleware{JWKS: keySet}
+
+	_, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, oldKey, "kid-1", testClaims()))
+	assert.NoError(t, err)
+
+	newKey := server.rotate(t, "kid-2")
+
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, newKey, "kid-2", testClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.requests))
+
+	// The retired key is gone after the refresh
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, oldKey, "kid-1", testClaims()))
+	assert.Error(t, err)
+}
+
+func TestJWKSKeySetRateLimitsUnknownKidRefreshes(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+
+	cpam := ClerkPublicAuthMiddleware{JWKS: NewJWKSKeySet(server.URL, time.Hour)}
+
+	_, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "unknown", testClaims()))
+		assert.ErrorIs(t, err, ErrUnknownSigningKey)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests))
+}
+
+func TestJWKSKeySetSkipsUnusableKeys(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+	server.extra = []jsonWebKey{
+		{Kty: "EC", Kid: "ec", Use: "sig"},
+		{Kty: "RSA", Kid: "enc", Use: "enc", N: "AQAB", E: "AQAB"},
+		{Kty: "RSA", Kid: "bad-exponent", Use: "sig", N: "AQAB", E: "AQ"},
+		{Kty: "RSA", Kid: "bad-modulus", Use: "sig", N: "!", E: "AQAB"},
+	}
+
+	keySet := NewJWKSKeySet(server.URL, time.Hour)
+	assert.NoError(t, keySet.Refresh(context.Background()))
+	cpam := ClerkPublicAuthMiddleware{JWKS: keySet}
+	_, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+
+	// A set without any usable key is rejected and the cached keys are kept
+	server.mu.Lock()
+	server.keys = map[string]*rsa.PrivateKey{}
+	server.mu.Unlock()
+	assert.Error(t, keySet.Refresh(context.Background()))
+	_, err = keySet.Key(context.Background(), "kid-1")
+	assert.NoError(t, err)
+}
+
+func TestJWKSKeySetRateLimitsFailedRefreshes(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+	server.fail = true
+
+	keySet := NewJWKSKeySet(server.URL, time.Hour)
+	cpam := ClerkPublicAuthMiddleware{JWKS: keySet}
+	_, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.Error(t, err)
+
+	// The failure counts against the refresh rate limit
+	server.mu.Lock()
+	server.fail = false
+	server.mu.Unlock()
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.ErrorContains(t, err, "unexpected status 500")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests))
+
+	keySet.MinRefreshInterval = 0
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.requests))
+
+	// The JWKS is fetched within the request context
+	keySet.MinRefreshInterval = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cpam.VerifyTokenLocal(ctx, signTestToken(t, key, "kid-2", testClaims()))
+	assert.ErrorContains(t, err, context.Canceled.Error())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.requests))
+}
+
+func TestJWKSKeySetCoalescesConcurrentRefreshes(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+	server.fail = true
+	server.delay = 100 * time.Millisecond
+
+	for _, interval := range []time.Duration{defaultJWKSRefreshInterval, 0} {
+		atomic.StoreInt32(&server.requests, 0)
+		keySet := NewJWKSKeySet(server.URL, time.Hour)
+		keySet.MinRefreshInterval = interval
+		cpam := ClerkPublicAuthMiddleware{JWKS: keySet}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "forged", testClaims()))
+				assert.Error(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests), "interval %v", interval)
+	}
+}
+
+func TestJWKSKeySetRefreshesAfterTTL(t *testing.T) {
+	server := newTestJWKSServer(t)
+	key := server.rotate(t, "kid-1")
+
+	keySet := NewJWKSKeySet(server.URL, time.Millisecond)
+	// Expired sets are rate limited too
+	keySet.MinRefreshInterval = 0
+	cpam := ClerkPublicAuthMiddleware{JWKS: keySet}
+
+	_, err := cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, key, "kid-1", testClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.requests))
+}
+
+func TestVerifyTokenLocalFallsBackToPEM(t *testing.T) {
+	server := newTestJWKSServer(t)
+	server.rotate(t, "kid-1")
+
+	pemKey := generateTestKey(t)
+	cpam := ClerkPublicAuthMiddleware{JWKS: NewJWKSKeySet(server.URL, time.Hour)}
+	err := cpam.SetPublicKey(encodePublicKeyPEM(t, &pemKey.PublicKey))
+	assert.NoError(t, err)
+
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, pemKey, "pem-key", testClaims()))
+	assert.NoError(t, err)
+
+	// The PEM key is also used when the JWKS endpoint is unreachable
+	server.Close()
+	cpam.JWKS = NewJWKSKeySet(server.URL, time.Hour)
+	_, err = cpam.VerifyTokenLocal(context.Background(), signTestToken(t, pemKey, "kid-1", testClaims()))
+	assert.NoError(t, err)
+}
+
+func TestVerifyTokenLocalRejectsUnexpectedAlgorithm(t *testing.T) {
+	pemKey := generateTestKey(t)
+	cpam := ClerkPublicAuthMiddleware{}
+	err := cpam.SetPublicKey(encodePublicKeyPEM(t, &pemKey.PublicKey))
+	assert.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	signed, err := token.SignedString([]byte(cpam.JwtPublicSigningKey))
+	assert.NoError(t, err)
+
+	_, err = cpam.VerifyTokenLocal(context.Background(), signed)
+	assert.Error(t, err)
+}
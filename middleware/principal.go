@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Principal is the identity established by an Authenticator. It is attached to
+// the gin context on every successful authentication, see CurrentPrincipal.
+type Principal struct {
+	Subject   string
+	Username  string
+	SessionID string
+	// OrgID is the active organization of the session, if any
+	OrgID string
+	// Role is the local model.User role, set once RequireRole has resolved it
+	Role      string
+	ExpiresAt time.Time
+	// Claims holds every claim of the verified token
+	Claims map[string]any
+	// Scopes is only set for API tokens, sessions are limited by role alone
+	Scopes []string
+	// TokenID is the API token used to authenticate, 0 for sessions
+	TokenID uint64
+}
+
+// CurrentPrincipal returns the authenticated caller, or nil when the route is
+// not behind an authentication middleware
+func CurrentPrincipal(c *gin.Context) *Principal {
+	value, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*Principal)
+	return principal
+}
+
+// principalFromClaims maps the standard and Clerk session claims
+func principalFromClaims(claims jwt.MapClaims) *Principal {
+	principal := &Principal{Claims: claims}
+	principal.Subject, _ = claims["sub"].(string)
+	principal.SessionID, _ = claims["sid"].(string)
+	principal.OrgID, _ = claims["org_id"].(string)
+	// Only present when the session token is customized to include it
+	principal.Username, _ = claims["username"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		principal.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return principal
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"gotempl/service"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const defaultSyncInterval = 5 * time.Minute
+
+// UsernameResolver is implemented by authenticators that can look up the
+// username of a subject when the token doesn't carry it
+type UsernameResolver interface {
+	ResolveUsername(ctx context.Context, subject string) (string, error)
+}
+
+// Provisioner creates the local model.User behind a verified identity the first
+// time it is seen and keeps its username in sync on later sign-ins
+type Provisioner struct {
+	Users    *service.UserService
+	Resolver UsernameResolver
+	// Orgs records the memberships of sessions with an active organization
+	Orgs *service.OrganizationService
+
+	// SyncInterval limits how often the same subject is written to the database
+	SyncInterval time.Duration
+
+	mu     sync.Mutex
+	synced map[string]time.Time
+}
+
+// NewProvisioner uses the authenticator to resolve usernames when it supports it
+func NewProvisioner(users *service.UserService, auth Authenticator) *Provisioner {
+	resolver, _ := auth.(UsernameResolver)
+	return &Provisioner{
+		Users:        users,
+		Resolver:     resolver,
+		SyncInterval: defaultSyncInterval,
+		synced:       map[string]time.Time{},
+	}
+}
+
+// Hook returns the AuthHook to pass to RequireAuth and RequireAPIAuth
+func (p *Provisioner) Hook() AuthHook {
+	return func(c *gin.Context, principal *Principal) error {
+		actor := service.Actor{Subject: principal.Subject, RequestID: CurrentRequestID(c), IP: c.ClientIP()}
+		return p.provision(c.Request.Context(), principal, actor)
+	}
+}
+
+func (p *Provisioner) Provision(ctx context.Context, principal *Principal) error {
+	return p.provision(ctx, principal, service.Actor{Subject: principal.Subject})
+}
+
+// provision records the users it creates or renames as changed by actor
+func (p *Provisioner) provision(ctx context.Context, principal *Principal, actor service.Actor) error {
+	syncKey := principal.Subject + "|" + principal.OrgID
+	if principal.Subject == "" || p.recentlySynced(syncKey) {
+		return nil
+	}
+
+	username := principal.Username
+	if username == "" {
+		// Only ask the identity provider when the user doesn't exist yet, the
+		// token claims are what keeps known users in sync
+		_, err := p.Users.Get(ctx, principal.Subject)
+		if err == nil {
+			return p.provisionMembership(ctx, principal, syncKey)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if p.Resolver != nil {
+			username, err = p.Resolver.ResolveUsername(ctx, principal.Subject)
+			if err != nil {
+				log.Warn("Failed to resolve username of ", principal.Subject, ": ", err)
+			}
+		}
+	}
+
+	if _, err := p.Users.As(actor).UpsertUser(ctx, principal.Subject, username); err != nil {
+		if errors.Is(err, service.ErrUserDeleted) {
+			// Trashed users stay out, RequireRole rejects them
+			p.markSynced(syncKey)
+			return nil
+		}
+		return err
+	}
+
+	return p.provisionMembership(ctx, principal, syncKey)
+}
+
+func (p *Provisioner) provisionMembership(ctx context.Context, principal *Principal, syncKey string) error {
+	if principal.OrgID != "" && p.Orgs != nil {
+		orgName, _ := principal.Claims["org_slug"].(string)
+		orgRole, _ := principal.Claims["org_role"].(string)
+		if err := p.Orgs.EnsureMembership(ctx, principal.OrgID, orgName, principal.Subject, orgRole); err != nil {
+			return err
+		}
+	}
+
+	p.markSynced(syncKey)
+	return nil
+}
+
+func (p *Provisioner) recentlySynced(subject string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	syncedAt, ok := p.synced[subject]
+	return ok && time.Since(syncedAt) < p.SyncInterval
+}
+
+func (p *Provisioner) markSynced(subject string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.synced[subject] = time.Now()
+}
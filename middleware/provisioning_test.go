@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeResolver struct {
+	usernames map[string]string
+	calls     int
+}
+
+func (r *fakeResolver) ResolveUsername(ctx context.Context, subject string) (string, error) {
+	r.calls++
+	return r.usernames[subject], nil
+}
+
+func setupProvisioner(t *testing.T) (*gorm.DB, *Provisioner, *fakeResolver) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&model.User{})
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	resolver := &fakeResolver{usernames: map[string]string{"user_1": "clerk-name"}}
+	provisioner := NewProvisioner(service.NewUserService(repository.NewUserRepository(db)), nil)
+	provisioner.Resolver = resolver
+	// Sync on every call so each step of the tests hits the database
+	provisioner.SyncInterval = 0
+
+	return db, provisioner, resolver
+}
+
+func TestProvisionCreatesUserOnFirstSignIn(t *testing.T) {
+	db, provisioner, resolver := setupProvisioner(t)
+
+	err := provisioner.Provision(context.Background(), &Principal{Subject: "user_1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resolver.calls)
+
+	var dbUser model.User
+	err = db.First(&dbUser, "uid = ?", "user_1").Error
+	assert.NoError(t, err)
+	assert.Equal(t, "clerk-name", dbUser.Username)
+	assert.Equal(t, RoleUser, dbUser.Role)
+
+	// Known users without a username claim don't hit the identity provider again
+	err = provisioner.Provision(context.Background(), &Principal{Subject: "user_1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resolver.calls)
+}
+
+func TestProvisionSyncsUsernameAndKeepsRole(t *testing.T) {
+	db, provisioner, _ := setupProvisioner(t)
+	db.Create(&model.User{Uid: "user_1", Username: "old-name", Role: RoleAdmin})
+
+	err := provisioner.Provision(context.Background(), &Principal{Subject: "user_1", Username: "new-name"})
+	assert.NoError(t, err)
+
+	var dbUser model.User
+	err = db.First(&dbUser, "uid = ?", "user_1").Error
+	assert.NoError(t, err)
+	assert.Equal(t, "new-name", dbUser.Username)
+	assert.Equal(t, RoleAdmin, dbUser.Role)
+}
+
+func TestProvisionFallsBackToUidOnUsernameConflict(t *testing.T) {
+	db, provisioner, _ := setupProvisioner(t)
+	db.Create(&model.User{Uid: "user_2", Username: "taken", Role: RoleUser})
+
+	err := provisioner.Provision(context.Background(), &Principal{Subject: "user_3", Username: "taken"})
+	assert.NoError(t, err)
+
+	var dbUser model.User
+	err = db.First(&dbUser, "uid = ?", "user_3").Error
+	assert.NoError(t, err)
+	assert.Equal(t, "user_3", dbUser.Username)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"gotempl/service"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIDKey    = "requestID"
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Request IDs set by a proxy are kept when they are reasonably short and plain
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID tags every request with an ID, the X-Request-ID header set by a
+// proxy or a random one. It is echoed in the response and recorded in the
+// audit log.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// CurrentRequestID returns the ID given to the request by RequestID
+func CurrentRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// CurrentActor describes the caller for the audit log
+func CurrentActor(c *gin.Context) service.Actor {
+	return service.Actor{
+		Subject:   c.GetString(SubjectKey),
+		RequestID: CurrentRequestID(c),
+		IP:        c.ClientIP(),
+	}
+}
+
+// TrustedProxiesFromEnv returns TRUSTED_PROXIES, the comma separated IPs and
+// CIDRs of the proxies whose X-Forwarded-For header gives the client IP. There
+// are none by default: the audit log records the IP of the connection, which
+// clients can't forge.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
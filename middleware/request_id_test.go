@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", RequestID(), func(c *gin.Context) {
+		c.String(http.StatusOK, CurrentActor(c).RequestID)
+	})
+
+	get := func(header string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A proxy's ID is kept
+	w := get("edge-1234.abcd")
+	assert.Equal(t, "edge-1234.abcd", w.Body.String())
+	assert.Equal(t, "edge-1234.abcd", w.Header().Get(RequestIDHeader))
+
+	// Otherwise, or when it is not plain, a random one is generated
+	for _, header := range []string{"", "bad id\nwith newline", string(make([]byte, 100))} {
+		w = get(header)
+		assert.Len(t, w.Body.String(), 32)
+		assert.Equal(t, w.Body.String(), w.Header().Get(RequestIDHeader))
+	}
+	assert.NotEqual(t, get("").Body.String(), get("").Body.String())
+}
+
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clientIP := func(remoteAddr string) string {
+		router := gin.New()
+		assert.NoError(t, router.SetTrustedProxies(TrustedProxiesFromEnv()))
+		router.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, CurrentActor(c).IP)
+		})
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// Without trusted proxies clients can't forge their IP
+	t.Setenv("TRUSTED_PROXIES", "")
+	assert.Empty(t, TrustedProxiesFromEnv())
+	assert.Equal(t, "192.0.2.10", clientIP("192.0.2.10:4321"))
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, TrustedProxiesFromEnv())
+	assert.Equal(t, "203.0.113.7", clientIP("10.1.2.3:4321"))
+	assert.Equal(t, "192.0.2.10", clientIP("192.0.2.10:4321"))
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TenantKey = "tenant"
+	// TenantCookie holds the organization picked by a super admin
+	TenantCookie = "tenant"
+	// AllTenants is the tenant of super admins that didn't pick an organization
+	AllTenants = "*"
+)
+
+// ResolveTenant decides which organization's data the request works on: the
+// active organization of the session, or for super admins the one picked with
+// the X-Tenant header or the tenant cookie. API tokens always work on the
+// organization they were minted for. It must run after RequireRole.
+func ResolveTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := ""
+		principal := CurrentPrincipal(c)
+		if principal != nil {
+			tenant = principal.OrgID
+		}
+
+		isToken := principal != nil && principal.TokenID != 0
+		if CurrentRole(c) == RoleSuperAdmin && !isToken {
+			tenant = AllTenants
+			if org := c.GetHeader("X-Tenant"); org != "" {
+				tenant = org
+			} else if org, err := c.Cookie(TenantCookie); err == nil && org != "" {
+				tenant = org
+			}
+		}
+
+		c.Set(TenantKey, tenant)
+		c.Next()
+	}
+}
+
+// CurrentTenant returns the organization resolved for the request, "" for
+// users without an organization and AllTenants for unrestricted super admins
+func CurrentTenant(c *gin.Context) string {
+	return c.GetString(TenantKey)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		role      string
+		header    string
+		cookie    string
+		tenant    string
+	}{
+		{"session of a member", &Principal{Subject: "u1", OrgID: "org_a"}, RoleAdmin, "org_b", "org_c", "org_a"},
+		{"session of a super admin", &Principal{Subject: "s1", OrgID: "org_a"}, RoleSuperAdmin, "", "", AllTenants},
+		{"super admin picking a tenant", &Principal{Subject: "s1", OrgID: "org_a"}, RoleSuperAdmin, "org_b", "org_c", "org_b"},
+		{"super admin picking a tenant with the cookie", &Principal{Subject: "s1"}, RoleSuperAdmin, "", "org_c", "org_c"},
+		{"token of a super admin", &Principal{Subject: "s1", OrgID: "org_a", TokenID: 1}, RoleSuperAdmin, "org_b", "org_c", "org_a"},
+		{"personal token of a super admin", &Principal{Subject: "s1", TokenID: 1}, RoleSuperAdmin, "", "org_c", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			var tenant string
+			router.GET("/", func(c *gin.Context) {
+				c.Set(PrincipalKey, tt.principal)
+				c.Set(RoleKey, tt.role)
+				c.Next()
+			}, ResolveTenant(), func(c *gin.Context) {
+				tenant = CurrentTenant(c)
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: TenantCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.tenant, tenant)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"gotempl/views/layout"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeZoneCookie holds the IANA time zone of the browser, set by the layout script
+const TimeZoneCookie = "tz"
+
+// ViewerTimeZone renders the times of the pages in the browser's time zone,
+// UTC until the cookie is set or when it names an unknown zone
+func ViewerTimeZone() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		loc := time.UTC
+		if zone, err := c.Cookie(TimeZoneCookie); err == nil && zone != "" && zone != "Local" {
+			if zoneLoc, err := time.LoadLocation(zone); err == nil {
+				loc = zoneLoc
+			}
+		}
+		c.Request = c.Request.WithContext(layout.WithLocation(c.Request.Context(), loc))
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"gotempl/views/layout"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewerTimeZone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/", ViewerTimeZone(), func(c *gin.Context) {
+		at := time.Date(2024, 11, 5, 17, 0, 0, 0, time.UTC)
+		c.String(http.StatusOK, layout.FormatTime(c.Request.Context(), at, "15:04 MST"))
+	})
+
+	for zone, want := range map[string]string{
+		"":                 "17:00 UTC",
+		"Europe%2FParis":   "18:00 CET",
+		"America/New_York": "12:00 EST",
+		"Mars/Olympus":     "17:00 UTC",
+		"Local":            "17:00 UTC",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/", nil)
+		if zone != "" {
+			req.AddCookie(&http.Cookie{Name: TimeZoneCookie, Value: zone})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Body.String(), zone)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"gotempl/model"
+	"gotempl/service"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenVerifier resolves the secret of a personal access token
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, secret string) (*model.APIToken, error)
+}
+
+// TokenAuthenticator accepts personal access tokens in the Authorization
+// header and hands every other request to the session authenticator
+type TokenAuthenticator struct {
+	Tokens TokenVerifier
+	Next   Authenticator
+}
+
+func NewTokenAuthenticator(tokens TokenVerifier, next Authenticator) *TokenAuthenticator {
+	return &TokenAuthenticator{Tokens: tokens, Next: next}
+}
+
+func (a *TokenAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	secret := SessionToken(c)
+	if !strings.HasPrefix(secret, model.APITokenPrefix) {
+		return a.Next.Authenticate(c)
+	}
+
+	token, err := a.Tokens.VerifyToken(c.Request.Context(), secret)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIToken) {
+			return nil, &AuthError{Status: http.StatusUnauthorized, Message: "Access denied: invalid or expired API token"}
+		}
+		return nil, err
+	}
+
+	principal := &Principal{
+		Subject: token.OwnerUid,
+		OrgID:   token.OrgID,
+		Scopes:  token.ScopeList(),
+		TokenID: token.ID,
+	}
+	if token.ExpiresAt != nil {
+		principal.ExpiresAt = *token.ExpiresAt
+	}
+	return principal, nil
+}
+
+// ResolveUsername delegates to the session authenticator so provisioning keeps working
+func (a *TokenAuthenticator) ResolveUsername(ctx context.Context, subject string) (string, error) {
+	if resolver, ok := a.Next.(UsernameResolver); ok {
+		return resolver.ResolveUsername(ctx, subject)
+	}
+	return "", nil
+}
+
+// RequireScope rejects API tokens that were not granted the scope. Sessions are
+// not restricted by scopes, their role is checked by RequireRole.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			abortWithError(c, http.StatusUnauthorized, "Access denied: authentication is needed")
+			return
+		}
+
+		if principal.TokenID != 0 && !slices.Contains(principal.Scopes, scope) {
+			abortWithError(c, http.StatusForbidden, "Access denied: token lacks the "+scope+" scope")
+			return
+		}
+		c.Next()
+	}
+}
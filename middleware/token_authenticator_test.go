@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"gotempl/model"
+	"gotempl/repository"
+	"gotempl/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sessionAuthenticator accepts any non token bearer value as a session
+type sessionAuthenticator struct{}
+
+func (sessionAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	if token := SessionToken(c); token != "" {
+		return &Principal{Subject: token}, nil
+	}
+	return nil, ErrNotAuthenticated
+}
+
+func setupTokenAuthentication(t *testing.T) (*gorm.DB, *service.APITokenService, *gin.Engine) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(&model.User{}, &model.APIToken{})
+	assert.NoError(t, err)
+	db.Create(&model.User{Uid: "owner-uid", Username: "owner", Role: RoleUser})
+
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	userRepo := repository.NewUserRepository(db)
+	tokenService := service.NewAPITokenService(repository.NewAPITokenRepository(db), userRepo)
+	auth := NewTokenAuthenticator(tokenService, sessionAuthenticator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(SubjectKey))
+	}
+	router.GET("/api/event/", RequireAPIAuth(auth), RequireScope(model.ScopeEventsRead), handler)
+	router.POST("/api/event/", RequireAPIAuth(auth), RequireScope(model.ScopeEventsWrite), handler)
+
+	return db, tokenService, router
+}
+
+func serveWithBearer(router *gin.Engine, method, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/api/event/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTokenAuthenticatorEnforcesScopes(t *testing.T) {
+	db, tokenService, router := setupTokenAuthentication(t)
+
+	token := model.APIToken{Name: "ci", OwnerUid: "owner-uid"}
+	secret, err := tokenService.CreateToken(context.Background(), &token, []string{model.ScopeEventsRead})
+	assert.NoError(t, err)
+
+	w := serveWithBearer(router, http.MethodGet, secret)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "owner-uid", w.Body.String())
+
+	w = serveWithBearer(router, http.MethodPost, secret)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Only the hash is stored and the use is recorded
+	var dbToken model.APIToken
+	err = db.First(&dbToken, token.ID).Error
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret, dbToken.TokenHash)
+	assert.NotNil(t, dbToken.LastUsedAt)
+
+	// Sessions are not restricted by scopes
+	w = serveWithBearer(router, http.MethodPost, "session-uid")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "session-uid", w.Body.String())
+}
+
+func TestTokenAuthenticatorRejectsInactiveTokens(t *testing.T) {
+	db, tokenService, router := setupTokenAuthentication(t)
+
+	revoked := model.APIToken{Name: "revoked", OwnerUid: "owner-uid"}
+	revokedSecret, err := tokenService.CreateToken(context.Background(), &revoked, []string{model.ScopeEventsRead})
+	assert.NoError(t, err)
+	assert.NoError(t, tokenService.RevokeToken(context.Background(), revoked.ID))
+
+	expired := model.APIToken{Name: "expired", OwnerUid: "owner-uid"}
+	expiredSecret, err := tokenService.CreateToken(context.Background(), &expired, []string{model.ScopeEventsRead})
+	assert.NoError(t, err)
+	db.Model(&expired).Update("expires_at", time.Now().Add(-time.Hour))
+
+	for _, secret := range []string{revokedSecret, expiredSecret, model.APITokenPrefix + "unknown"} {
+		w := serveWithBearer(router, http.MethodGet, secret)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestCreateTokenValidation(t *testing.T) {
+	_, tokenService, _ := setupTokenAuthentication(t)
+
+	_, err := tokenService.CreateToken(context.Background(), &model.APIToken{Name: "bad", OwnerUid: "owner-uid"}, []string{"events:delete"})
+	assert.Error(t, err)
+
+	_, err = tokenService.CreateToken(context.Background(), &model.APIToken{Name: "orphan", OwnerUid: "nobody"}, []string{model.ScopeEventsRead})
+	assert.Error(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = tokenService.CreateToken(context.Background(), &model.APIToken{Name: "past", OwnerUid: "owner-uid", ExpiresAt: &past}, []string{model.ScopeEventsRead})
+	assert.Error(t, err)
+}
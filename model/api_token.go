@@ -0,0 +1,51 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix marks personal access tokens so they can be told apart from session tokens
+const APITokenPrefix = "gtp_"
+
+const (
+	ScopeEventsRead  = "events:read"
+	ScopeEventsWrite = "events:write"
+	ScopeUsersAdmin  = "users:admin"
+	ScopeAuditRead   = "audit:read"
+)
+
+// Scopes lists every scope a token can be granted
+var Scopes = []string{ScopeEventsRead, ScopeEventsWrite, ScopeUsersAdmin, ScopeAuditRead}
+
+type APIToken struct {
+	ID         uint64     `json:"id" gorm:"primaryKey"`                                                  // ID (uint64): The unique identifier of the token.
+	Name       string     `json:"name" gorm:"type:varchar(255);not null" validate:"required,min=1"`      // Name (string): What the token is used for, e.g. "CI deploy job".
+	OwnerUid   string     `json:"owner_uid" gorm:"type:varchar(255);not null;index" validate:"required"` // OwnerUid (string): The user the token acts as, linking to the User entity.
+	Owner      User       `json:"-" gorm:"foreignKey:OwnerUid" validate:"-"`                             // Owner (User): The user object associated with the token.
+	OrgID      string     `json:"org_id" gorm:"type:varchar(255);not null;default:''"`                   // OrgID (string): The organization whose data the token can reach, empty for personal data.
+	Prefix     string     `json:"prefix" gorm:"type:varchar(32);not null"`                               // Prefix (string): The first characters of the token, to recognize it in listings.
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`                        // TokenHash (string): SHA-256 of the token, the token itself is never stored.
+	Scopes     string     `json:"scopes" gorm:"type:varchar(255);not null" validate:"required"`          // Scopes (string): Space separated list of granted scopes.
+	ExpiresAt  *time.Time `json:"expires_at"`                                                            // ExpiresAt (*time.Time): When the token stops working, nil for never.
+	LastUsedAt *time.Time `json:"last_used_at"`                                                          // LastUsedAt (*time.Time): When the token was last used to authenticate.
+	RevokedAt  *time.Time `json:"revoked_at"`                                                            // RevokedAt (*time.Time): When the token was revoked, nil while active.
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`                                      // CreatedAt (time.Time): The timestamp when the token was minted.
+}
+
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.ScopeList(), scope)
+}
+
+// Active reports whether the token can still be used at the given time
+func (t *APIToken) Active(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Attendance statuses
+const (
+	AttendanceGoing      = "going"
+	AttendanceWaitlisted = "waitlisted"
+)
+
+// Attendance is a user's registration to an event
+type Attendance struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`                                                                    // ID (uint64): The unique identifier of the registration, waitlisted users are promoted in its order.
+	EventID   uint64    `json:"event_id" gorm:"not null;uniqueIndex:idx_attendances_event_user"`                         // EventID (uint64): The event, linking to the Event entity.
+	Event     Event     `json:"-" gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE" validate:"-"`                    // Event (Event): The event object.
+	UserUid   string    `json:"user_uid" gorm:"type:varchar(255);not null;uniqueIndex:idx_attendances_event_user;index"` // UserUid (string): The attendee, linking to the User entity.
+	User      User      `json:"-" gorm:"foreignKey:UserUid" validate:"-"`                                                // User (User): The user object of the attendee.
+	Status    string    `json:"status" gorm:"type:varchar(16);not null"`                                                 // Status (string): going while the user has a seat, waitlisted while the event is full.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`                                                        // CreatedAt (time.Time): The timestamp of the registration.
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`                                                        // UpdatedAt (time.Time): The timestamp of the last status change, e.g. a promotion from the waitlist.
+}
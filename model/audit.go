@@ -0,0 +1,64 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Audited entities
+const (
+	AuditEntityEvent = "event"
+	AuditEntityUser  = "user"
+)
+
+// Audited actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+type AuditEntry struct {
+	ID        uint64       `json:"id" gorm:"primaryKey"`                                               // ID (uint64): The unique identifier of the entry.
+	Entity    string       `json:"entity" gorm:"type:varchar(32);not null;index:idx_audit_entity"`     // Entity (string): The kind of record that changed, e.g. "event" or "user".
+	EntityID  string       `json:"entity_id" gorm:"type:varchar(255);not null;index:idx_audit_entity"` // EntityID (string): The primary key of the record that changed.
+	Action    string       `json:"action" gorm:"type:varchar(16);not null"`                            // Action (string): What happened: create, update, delete, restore or purge.
+	Actor     string       `json:"actor" gorm:"type:varchar(255);index"`                               // Actor (string): The user ID of the caller, or "clerk" for webhooks.
+	OrgID     string       `json:"org_id" gorm:"type:varchar(255);index"`                              // OrgID (string): The organization of the record, empty for personal events and users without one.
+	RequestID string       `json:"request_id" gorm:"type:varchar(64);index"`                           // RequestID (string): The X-Request-ID of the request that made the change.
+	IP        string       `json:"ip" gorm:"type:varchar(45)"`                                         // IP (string): The client address of the request.
+	Changes   AuditChanges `json:"changes" gorm:"type:text" swaggertype:"object"`                      // Changes (AuditChanges): The changed fields with their value before and after.
+	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime;index"`                             // CreatedAt (time.Time): When the change was made.
+}
+
+// AuditChange is the value of a field before and after a change
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditChanges maps the JSON names of the changed fields to their change, it is
+// stored as JSON
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	raw, err := json.Marshal(c)
+	return string(raw), err
+}
+
+func (c *AuditChanges) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("can't scan %T into AuditChanges", value)
+}
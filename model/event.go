@@ -1,28 +1,54 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event statuses, they are changed by the lifecycle actions of EventService
+const (
+	EventDraft     = "draft"
+	EventScheduled = "scheduled"
+	EventPublished = "published"
+	EventLive      = "live"
+	EventCompleted = "completed"
+	EventCancelled = "cancelled"
+	EventPostponed = "postponed"
+)
 
 type Event struct {
-	ID                   uint64    `json:"id" gorm:"primaryKey"`                       // ID (uint): The unique identifier for the event, serves as the primary key.
-	CreatedBy            string    `json:"createdBy" gorm:"createdBy;not null"`        // CreatedBy (string): The user ID of the event creator, linking to the User entity.
-	User                 User      `json:"-" gorm:"foreignKey:CreatedBy" validate:"-"` // User (User): The user object associated with the event creator.
-	Title                string    `json:"title" gorm:"not null"`                      // Title (string): The title of the event, required for easy identification.
-	Description          string    `json:"description"`                                // Description (string): A brief explanation of what the event is about.
-	Location             string    `json:"location"`                                   // Location (string): The physical or virtual location where the event will take place.
-	Images               string    `json:"images" gorm:"type:json"`                    // Images ([]string): Array of image URLs associated with the event (e.g., event posters).
-	StartTime            time.Time `json:"start_time" gorm:"default:null"`             // StartTime (time.Time): When the event is scheduled to begin.
-	EndTime              time.Time `json:"end_time" gorm:"default:null"`               // EndTime (time.Time): When the event is scheduled to end.
-	CreatedAt            time.Time `json:"created_at" gorm:"autoCreateTime"`           // CreatedAt (time.Time): The timestamp when the event was created, automatically set.
-	UpdatedAt            time.Time `json:"updated_at" gorm:"autoUpdateTime"`           // UpdatedAt (time.Time): The timestamp when the event was last updated, automatically set.
-	UpdatedBy            string    `json:"updated_by"`                                 // UpdatedBy (string): The user ID of the person who last updated the event.
-	Status               string    `json:"status" gorm:"default:'draft'"`              // Status (string): The current state of the event (e.g., draft, published, cancelled).
-	MaxAttendees         uint      `json:"max_attendees"`                              // MaxAttendees (uint): The maximum number of attendees allowed.
-	AttendeesCount       uint      `json:"attendees_count"`                            // AttendeesCount (uint): The current number of registered attendees.
-	IsPublic             bool      `json:"is_public" gorm:"default:true"`              // IsPublic (bool): Whether the event is public or private. Defaults to public.
-	RSVPRequired         bool      `json:"rsvp_required" gorm:"default:false"`         // RSVPRequired (bool): Whether an RSVP is required to attend the event.
-	Tags                 string    `json:"tags" gorm:"type:json"`                      // Tags ([]string): For categorizing events using tags like "conference", "workshop", etc.
-	OrganizerContactInfo string    `json:"organizer_contact_info"`                     // OrganizerContactInfo (string): Contact details for the event organizer.
-	ExternalLink         string    `json:"external_link"`                              // ExternalLink (string): Link to an external site related to the event (e.g., event registration page or official website).
-	IsFeatured           bool      `json:"is_featured" gorm:"default:false"`           // IsFeatured (bool): Indicates whether this event is featured or highlighted on the platform.
-	EventType            string    `json:"event_type"`                                 // EventType (string): The type or category of the event (e.g., webinar, in-person, hybrid).
+	ID                   uint64         `json:"id" gorm:"primaryKey"`                                                                           // ID (uint): The unique identifier for the event, serves as the primary key.
+	CreatedBy            string         `json:"createdBy" gorm:"createdBy;not null"`                                                            // CreatedBy (string): The user ID of the event creator, linking to the User entity.
+	User                 User           `json:"-" gorm:"foreignKey:CreatedBy" validate:"-"`                                                     // User (User): The user object associated with the event creator.
+	Title                string         `json:"title" gorm:"not null"`                                                                          // Title (string): The title of the event, required for easy identification.
+	Description          string         `json:"description"`                                                                                    // Description (string): A brief explanation of what the event is about.
+	Location             string         `json:"location"`                                                                                       // Location (string): The physical or virtual location where the event will take place.
+	Images               StringList     `json:"images" gorm:"type:json" swaggertype:"array,string" validate:"max=20,dive,http_url"`             // Images ([]string): Array of image URLs associated with the event (e.g., event posters).
+	StartTime            *time.Time     `json:"start_time" gorm:"default:null"`                                                                 // StartTime (*time.Time): When the event is scheduled to begin, stored in UTC. Null while unscheduled.
+	EndTime              *time.Time     `json:"end_time" gorm:"default:null"`                                                                   // EndTime (*time.Time): When the event is scheduled to end, stored in UTC. The day after the last one for all-day events.
+	TimeZone             string         `json:"time_zone" gorm:"type:varchar(64);not null;default:'UTC'" example:"Europe/Paris"`                // TimeZone (string): The IANA time zone the event takes place in, UTC by default.
+	AllDay               bool           `json:"all_day" gorm:"default:false"`                                                                   // AllDay (bool): Whether the event lasts whole days, its times are then midnights in its time zone.
+	CreatedAt            time.Time      `json:"created_at" gorm:"autoCreateTime"`                                                               // CreatedAt (time.Time): The timestamp when the event was created, automatically set.
+	UpdatedAt            time.Time      `json:"updated_at" gorm:"autoUpdateTime"`                                                               // UpdatedAt (time.Time): The timestamp when the event was last updated, automatically set.
+	UpdatedBy            string         `json:"updated_by"`                                                                                     // UpdatedBy (string): The user ID of the person who last updated the event.
+	Status               string         `json:"status" gorm:"default:'draft'"`                                                                  // Status (string): The lifecycle state of the event: draft, scheduled, published, live, completed, cancelled or postponed.
+	StatusReason         string         `json:"status_reason"`                                                                                  // StatusReason (string): Why the event was cancelled or postponed, given with the last status change.
+	MaxAttendees         uint           `json:"max_attendees"`                                                                                  // MaxAttendees (uint): The maximum number of attendees allowed.
+	AttendeesCount       uint           `json:"attendees_count"`                                                                                // AttendeesCount (uint): The number of attendees with a seat, maintained by registrations.
+	IsPublic             bool           `json:"is_public" gorm:"default:true"`                                                                  // IsPublic (bool): Whether the event is public or private. Defaults to public.
+	RSVPRequired         bool           `json:"rsvp_required" gorm:"default:false"`                                                             // RSVPRequired (bool): Whether an RSVP is required to attend the event.
+	Tags                 StringList     `json:"tags" gorm:"type:json" swaggertype:"array,string" validate:"max=20"`                             // Tags ([]string): For categorizing events using tags like "conference", "workshop", etc. Lower case letters, digits and dashes.
+	OrganizerContactInfo string         `json:"organizer_contact_info"`                                                                         // OrganizerContactInfo (string): Contact details for the event organizer.
+	ExternalLink         string         `json:"external_link"`                                                                                  // ExternalLink (string): Link to an external site related to the event (e.g., event registration page or official website).
+	IsFeatured           bool           `json:"is_featured" gorm:"default:false"`                                                               // IsFeatured (bool): Indicates whether this event is featured or highlighted on the platform.
+	EventType            string         `json:"event_type"`                                                                                     // EventType (string): The type or category of the event (e.g., webinar, in-person, hybrid).
+	OrgID                string         `json:"org_id" gorm:"type:varchar(255);index"`                                                          // OrgID (string): The organization (tenant) owning the event, empty for personal accounts.
+	DeletedAt            gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string"`                                                   // DeletedAt (gorm.DeletedAt): When the event was moved to the trash, null while it is live.
+	DeletedBy            string         `json:"deleted_by" gorm:"type:varchar(255)"`                                                            // DeletedBy (string): The user ID of the person who moved the event to the trash.
+	RRule                string         `json:"rrule" gorm:"column:rrule;type:varchar(255);not null;default:''" example:"FREQ=WEEKLY;BYDAY=MO"` // RRule (string): The RFC 5545 recurrence rule of the event, starting at its start time. Empty when it doesn't recur.
+	ExDates              TimeList       `json:"exdates" gorm:"column:exdates;type:json" swaggertype:"array,string"`                             // ExDates ([]time.Time): The starts of the occurrences removed from the series.
+	SeriesID             *uint64        `json:"series_id" gorm:"index"`                                                                         // SeriesID (*uint64): The recurring event whose occurrence this event overrides, null otherwise.
+	RecurrenceID         *time.Time     `json:"recurrence_id"`                                                                                  // RecurrenceID (*time.Time): The start of the occurrence this event overrides, given by the rule of the series.
+	Version              uint64         `json:"version" gorm:"not null;default:1"`                                                              // Version (uint64): Incremented by every change, it is the ETag of the event.
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Occurrence is one instance of an event: the event itself when it doesn't
+// recur, or one of the series its recurrence rule expands to
+type Occurrence struct {
+	SeriesID     uint64     `json:"series_id" example:"42"`                       // SeriesID (uint64): The recurring event, or the event itself when it doesn't recur.
+	RecurrenceID time.Time  `json:"recurrence_id" example:"2024-11-04T09:00:00Z"` // RecurrenceID (time.Time): The start of the occurrence given by the rule, which identifies it even when it was moved.
+	StartTime    time.Time  `json:"start_time" example:"2024-11-04T09:00:00Z"`    // StartTime (time.Time): When the occurrence begins.
+	EndTime      *time.Time `json:"end_time" example:"2024-11-04T09:15:00Z"`      // EndTime (*time.Time): When the occurrence ends, null when the event has no end.
+	Event        Event      `json:"event"`                                        // Event (Event): The series, or the event overriding this occurrence.
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type Organization struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(255)" validate:"required,min=1"` // ID (string): The identity provider's organization ID (Clerk org_id).
+	Name      string    `json:"name" gorm:"type:varchar(255);not null"`                           // Name (string): Display name of the organization, the org slug when nothing better is known.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`                                 // CreatedAt (time.Time): The timestamp when the organization was first seen.
+}
+
+// Membership links a user to an organization
+type Membership struct {
+	UserUid      string       `json:"user_uid" gorm:"primaryKey;type:varchar(255)"`      // UserUid (string): The member, linking to the User entity.
+	User         User         `json:"-" gorm:"foreignKey:UserUid" validate:"-"`          // User (User): The user object of the member.
+	OrgID        string       `json:"org_id" gorm:"primaryKey;type:varchar(255)"`        // OrgID (string): The organization, linking to the Organization entity.
+	Organization Organization `json:"-" gorm:"foreignKey:OrgID" validate:"-"`            // Organization (Organization): The organization object.
+	Role         string       `json:"role" gorm:"type:varchar(100);not null;default:''"` // Role (string): The role inside the organization as reported by the provider (e.g. org:admin), informational: authorization uses User.Role.
+	CreatedAt    time.Time    `json:"created_at" gorm:"autoCreateTime"`                  // CreatedAt (time.Time): The timestamp when the membership was first seen.
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// StringList is a list of strings stored as a JSON array, it works with JSON
+// columns and plain text ones
+type StringList []string
+
+// Value stores an empty list for nil so the column always holds an array
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]string(l))
+	return string(raw), err
+}
+
+func (l *StringList) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("can't scan %T into StringList", value)
+	}
+	if len(raw) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(raw, (*[]string)(l))
+}
+
+// MarshalJSON answers [] rather than null for empty lists
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
+
+// tagPattern is what a tag is made of: lower case letters, digits and inner dashes
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// MaxTagLength is the longest tag allowed
+const MaxTagLength = 32
+
+// ValidTag tells whether tag can tag an event, e.g. "workshop" or "open-source"
+func ValidTag(tag string) bool {
+	return len(tag) <= MaxTagLength && tagPattern.MatchString(tag)
+}
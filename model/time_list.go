@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// TimeList is a list of times stored as a JSON array of RFC 3339 strings, like
+// StringList
+type TimeList []time.Time
+
+// Value stores an empty list for nil so the column always holds an array
+func (l TimeList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]time.Time(l))
+	return string(raw), err
+}
+
+func (l *TimeList) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("can't scan %T into TimeList", value)
+	}
+	if len(raw) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(raw, (*[]time.Time)(l))
+}
+
+// MarshalJSON answers [] rather than null for empty lists
+func (l TimeList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]time.Time(l))
+}
+
+// Contains tells whether the list has the instant t, in any time zone
+func (l TimeList) Contains(t time.Time) bool {
+	return slices.ContainsFunc(l, t.Equal)
+}
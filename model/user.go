@@ -1,7 +1,12 @@
 package model
 
+import "gorm.io/gorm"
+
 type User struct {
-	Uid      string `json:"uid" form:"uid" gorm:"primaryKey;type:varchar(255)" validate:"required,min=1"`
-	Username string `json:"username" form:"username" gorm:"type:varchar(255);not null;unique" validate:"required,min=1"`
-	Role     string `json:"role" form:"role" gorm:"type:varchar(50);not null;default:'user'" validate:"oneof=user admin"`
+	Uid       string         `json:"uid" form:"uid" gorm:"primaryKey;type:varchar(255)" validate:"required,min=1"`
+	Username  string         `json:"username" form:"username" gorm:"type:varchar(255);not null;unique" validate:"required,min=1"`
+	Role      string         `json:"role" form:"role" gorm:"type:varchar(50);not null;default:'user'" validate:"oneof=user admin superadmin"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" form:"-" gorm:"index" swaggertype:"string"`
+	DeletedBy string         `json:"deleted_by" form:"-" gorm:"type:varchar(255)"`
+	Version   uint64         `json:"version" form:"-" gorm:"not null;default:1"`
 }
@@ -0,0 +1,9 @@
+package model
+
+import "strconv"
+
+// ETag is the entity tag of a version of a record, as sent in the ETag header
+// and expected in If-Match
+func ETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
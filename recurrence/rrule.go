@@ -0,0 +1,488 @@
+// Package recurrence expands the recurrence rules of RFC 5545 (iCalendar), the
+// RRULE of recurring events
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of a Rule
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+var frequencies = []string{Daily, Weekly, Monthly, Yearly}
+
+// ErrInvalidRule is wrapped by the errors of Parse
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// maxEmptyPeriods ends the rules that stopped producing occurrences, such as
+// the 30th of February. Sparse rules, like the 29th of February, stay far below.
+const maxEmptyPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Weekday is a BYDAY value: a day of the week, or its Nth one in the month or
+// the year when N isn't 0, counted from the end when N is negative
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w Weekday) String() string {
+	day := strings.ToUpper(w.Day.String()[:2])
+	if w.N == 0 {
+		return day
+	}
+	return strconv.Itoa(w.N) + day
+}
+
+// Rule is a recurrence rule, its occurrences keep the wall clock time of the
+// first one in its location. It supports FREQ (DAILY, WEEKLY, MONTHLY or
+// YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS and WKST.
+type Rule struct {
+	Freq     string
+	Interval int
+	// Count and Until end the rule, at most one of them is set
+	Count      int
+	Until      time.Time
+	ByDay      []Weekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	// untilDate is set when UNTIL is a date, the last day of the rule in the
+	// location of its occurrences
+	untilDate bool
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidRule}, args...)...)
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10", with or
+// without its RRULE: prefix
+func Parse(text string) (*Rule, error) {
+	text = strings.TrimSpace(text)
+	if len(text) >= 6 && strings.EqualFold(text[:6], "RRULE:") {
+		text = text[6:]
+	}
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(text, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, invalid("malformed part %q", part)
+		}
+		if seen[name] {
+			return nil, invalid("%s is given twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = value
+			if !slices.Contains(frequencies, value) {
+				err = errors.New("must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			r.Interval, err = parseInt(value, 1, 1000)
+		case "COUNT":
+			r.Count, err = parseInt(value, 1, 10000)
+		case "UNTIL":
+			r.Until, r.untilDate, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseWeekdays(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, 31)
+		case "BYMONTH":
+			var months []int
+			if months, err = parseInts(value, 12); err == nil {
+				for _, month := range months {
+					if month < 0 {
+						err = errors.New("months are 1 to 12")
+					}
+					r.ByMonth = append(r.ByMonth, time.Month(month))
+				}
+				slices.Sort(r.ByMonth)
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(value, 366)
+		case "WKST":
+			var ok bool
+			if r.WeekStart, ok = weekdays[value]; !ok {
+				err = fmt.Errorf("unknown day %q", value)
+			}
+		default:
+			return nil, invalid("%s isn't supported", name)
+		}
+		if err != nil {
+			return nil, invalid("%s: %v", name, err)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, invalid("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, invalid("COUNT and UNTIL can't be given together")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return nil, invalid("BYSETPOS needs another BY part")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return nil, invalid("BYMONTHDAY doesn't apply to WEEKLY rules")
+	}
+	for _, day := range r.ByDay {
+		switch {
+		case day.N != 0 && r.Freq != Monthly && r.Freq != Yearly:
+			return nil, invalid("BYDAY %s: numbered days only apply to MONTHLY and YEARLY rules", day)
+		case day.N != 0 && r.Freq == Monthly && (day.N > 5 || day.N < -5):
+			return nil, invalid("BYDAY %s: a month has at most 5 of each day", day)
+		}
+	}
+	return r, nil
+}
+
+// parseInt reads a number from min to max
+func parseInt(value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%q isn't a number from %d to %d", value, min, max)
+	}
+	return n, nil
+}
+
+// parseInts reads a list of numbers from 1 to max or -max to -1
+func parseInts(value string, max int) ([]int, error) {
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n > max || n < -max {
+			return nil, fmt.Errorf("%q isn't a number from 1 to %d or -%d to -1", item, max, max)
+		}
+		if !slices.Contains(list, n) {
+			list = append(list, n)
+		}
+	}
+	return list, nil
+}
+
+// parseUntil reads a UTC time (20241231T235959Z) or a date (20241231)
+func parseUntil(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q isn't a UTC time (20060102T150405Z) nor a date (20060102)", value)
+}
+
+// parseWeekdays reads days such as MO, 1MO or -1FR
+func parseWeekdays(value string) ([]Weekday, error) {
+	var days []Weekday
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("unknown day %q", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, fmt.Errorf("unknown day %q", item)
+			}
+		}
+		days = append(days, Weekday{N: n, Day: day})
+	}
+	return days, nil
+}
+
+// String returns the rule in its canonical form
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.untilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	join := func(name string, n int, item func(i int) string) {
+		if n == 0 {
+			return
+		}
+		items := make([]string, n)
+		for i := range items {
+			items[i] = item(i)
+		}
+		parts = append(parts, name+"="+strings.Join(items, ","))
+	}
+	join("BYMONTH", len(r.ByMonth), func(i int) string { return strconv.Itoa(int(r.ByMonth[i])) })
+	join("BYMONTHDAY", len(r.ByMonthDay), func(i int) string { return strconv.Itoa(r.ByMonthDay[i]) })
+	join("BYDAY", len(r.ByDay), func(i int) string { return r.ByDay[i].String() })
+	join("BYSETPOS", len(r.BySetPos), func(i int) string { return strconv.Itoa(r.BySetPos[i]) })
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(r.WeekStart.String()[:2]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// EndBefore makes the rule end with the last occurrence before t
+func (r *Rule) EndBefore(t time.Time) {
+	r.Count = 0
+	r.Until = t.UTC().Add(-time.Second)
+	r.untilDate = false
+}
+
+// Each calls fn with the occurrences of the rule from dtstart, in order, until
+// fn returns false or the rule ends
+func (r *Rule) Each(dtstart time.Time, fn func(t time.Time) bool) {
+	r.each(dtstart, 0, fn)
+}
+
+// each is Each from the first period of the rule, periods before it are skipped
+func (r *Rule) each(dtstart time.Time, first int, fn func(t time.Time) bool) {
+	loc := dtstart.Location()
+	until := r.Until
+	if r.untilDate {
+		until = time.Date(until.Year(), until.Month(), until.Day(), 23, 59, 59, 999999999, loc)
+	}
+
+	count, empty := 0, 0
+	for period := first; empty < maxEmptyPeriods; period++ {
+		days := r.days(dtstart, period)
+		if len(days) > 0 && days[0].Year() > 9999 {
+			return
+		}
+		found := false
+		for _, day := range days {
+			t := time.Date(day.Year(), day.Month(), day.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), loc)
+			if t.Before(dtstart) {
+				continue
+			}
+			if !until.IsZero() && t.After(until) {
+				return
+			}
+			found = true
+			count++
+			if !fn(t) || (r.Count > 0 && count >= r.Count) {
+				return
+			}
+		}
+		if found {
+			empty = 0
+		} else {
+			empty++
+		}
+	}
+}
+
+// periodOf returns a period of the rule from dtstart that starts before t,
+// close to it. The occurrences before t can only be skipped without a COUNT,
+// which counts them: it returns 0 then.
+func (r *Rule) periodOf(dtstart, t time.Time) int {
+	if r.Count > 0 || !t.After(dtstart) {
+		return 0
+	}
+	t = t.In(dtstart.Location())
+	start, end := date(dtstart.Year(), dtstart.Month(), dtstart.Day()), date(t.Year(), t.Month(), t.Day())
+	// Durations can't span the years Unix times do
+	days := int((end.Unix() - start.Unix()) / (24 * 60 * 60))
+	var units int
+	switch r.Freq {
+	case Daily:
+		units = days
+	case Weekly:
+		units = days / 7
+	case Monthly:
+		units = (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+	case Yearly:
+		units = end.Year() - start.Year()
+	}
+	// The period before, whose days may be shifted by WKST, BYDAY or BYSETPOS
+	return max(units/r.Interval-1, 0)
+}
+
+// Between returns the occurrences of the rule from dtstart that start in
+// [from, to), at most limit of them when limit isn't 0
+func (r *Rule) Between(dtstart, from, to time.Time, limit int) []time.Time {
+	var times []time.Time
+	r.each(dtstart, r.periodOf(dtstart, from), func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			times = append(times, t)
+		}
+		return limit == 0 || len(times) < limit
+	})
+	return times
+}
+
+// Includes tells whether t is an occurrence of the rule from dtstart
+func (r *Rule) Includes(dtstart, t time.Time) bool {
+	return len(r.Between(dtstart, t, t.Add(time.Nanosecond), 1)) == 1
+}
+
+// date is a day, at midnight UTC to add days without time zone changes
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// days lists the days of the period-th period of the rule from dtstart
+func (r *Rule) days(dtstart time.Time, period int) []time.Time {
+	start := date(dtstart.Year(), dtstart.Month(), dtstart.Day())
+	n := period * r.Interval
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, n)
+		if r.inMonths(day) && r.onMonthDays(day) && r.onWeekdays(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		week := start.AddDate(0, 0, 7*n-offset)
+		for i := 0; i < 7; i++ {
+			day := week.AddDate(0, 0, i)
+			onDay := day.Weekday() == start.Weekday()
+			if len(r.ByDay) > 0 {
+				onDay = r.onWeekdays(day)
+			}
+			if onDay && r.inMonths(day) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		month := date(start.Year(), start.Month()+time.Month(n), 1)
+		if r.inMonths(month) {
+			days = r.monthDays(month, start)
+		}
+	case Yearly:
+		year := start.Year() + n
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, month := range r.ByMonth {
+				days = append(days, r.monthDays(date(year, month, 1), start)...)
+			}
+		case len(r.ByMonthDay) > 0:
+			for month := time.January; month <= time.December; month++ {
+				days = append(days, r.monthDays(date(year, month, 1), start)...)
+			}
+		case len(r.ByDay) > 0:
+			first := date(year, time.January, 1)
+			length := int(first.AddDate(1, 0, 0).Sub(first) / (24 * time.Hour))
+			for i := 0; i < length; i++ {
+				if day := first.AddDate(0, 0, i); r.onNthWeekdays(day, i, length-1-i) {
+					days = append(days, day)
+				}
+			}
+		default:
+			// Skipped on the years without the day, such as the 29th of February
+			if day := date(year, start.Month(), start.Day()); day.Month() == start.Month() {
+				days = append(days, day)
+			}
+		}
+	}
+	return r.setPositions(days)
+}
+
+// monthDays lists the days of the month starting on first matched by
+// BYMONTHDAY and BYDAY, or the day of the month of start when neither is given
+func (r *Rule) monthDays(first, start time.Time) []time.Time {
+	length := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	for i := 0; i < length; i++ {
+		day := first.AddDate(0, 0, i)
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			if day.Day() == start.Day() {
+				days = append(days, day)
+			}
+			continue
+		}
+		if r.onMonthDays(day) && (len(r.ByDay) == 0 || r.onNthWeekdays(day, i, length-1-i)) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func (r *Rule) inMonths(day time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, day.Month())
+}
+
+func (r *Rule) onMonthDays(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := date(day.Year(), day.Month()+1, 0).Day()
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay == day.Day() || monthDay < 0 && length+monthDay+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) onWeekdays(day time.Time) bool {
+	return len(r.ByDay) == 0 || slices.ContainsFunc(r.ByDay, func(w Weekday) bool { return w.Day == day.Weekday() })
+}
+
+// onNthWeekdays matches the numbered days of BYDAY, before and after are the
+// number of days of the period before and after day
+func (r *Rule) onNthWeekdays(day time.Time, before, after int) bool {
+	for _, w := range r.ByDay {
+		if w.Day != day.Weekday() {
+			continue
+		}
+		if w.N == 0 || w.N > 0 && before/7 == w.N-1 || w.N < 0 && after/7 == -w.N-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// setPositions keeps the days of a period at the positions of BYSETPOS
+func (r *Rule) setPositions(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+	var kept []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(days) + pos
+		}
+		if i >= 0 && i < len(days) && !slices.ContainsFunc(kept, days[i].Equal) {
+			kept = append(kept, days[i])
+		}
+	}
+	slices.SortFunc(kept, time.Time.Compare)
+	return kept
+}
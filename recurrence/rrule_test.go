@@ -0,0 +1,139 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expand returns the first occurrences of rule from dtstart, as local dates and times
+func expand(t *testing.T, rule string, dtstart time.Time, limit int) []string {
+	r, err := Parse(rule)
+	if !assert.NoError(t, err, rule) {
+		return nil
+	}
+	times := []string{}
+	r.Each(dtstart, func(at time.Time) bool {
+		times = append(times, at.Format("2006-01-02 15:04 Mon"))
+		return len(times) < limit
+	})
+	return times
+}
+
+func TestParse(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20241231T000000Z",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYDAY=6FR",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"COUNT=3",
+	} {
+		_, err := Parse(rule)
+		assert.ErrorIs(t, err, ErrInvalidRule, rule)
+	}
+
+	r, err := Parse("RRULE:freq=monthly;byday=-1fr,1MO;bymonth=12,6;until=20241231")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;UNTIL=20241231;BYMONTH=6,12;BYDAY=-1FR,1MO", r.String())
+
+	r.EndBefore(time.Date(2024, 11, 4, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, "FREQ=MONTHLY;UNTIL=20241104T085959Z;BYMONTH=6,12;BYDAY=-1FR,1MO", r.String())
+}
+
+func TestEach(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	monday := time.Date(2024, 10, 21, 9, 30, 0, 0, paris)
+
+	// Standups keep their local time across the change to winter time
+	assert.Equal(t, []string{
+		"2024-10-24 09:30 Thu", "2024-10-25 09:30 Fri", "2024-10-28 09:30 Mon", "2024-10-29 09:30 Tue",
+	}, expand(t, "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", monday, 10)[3:7])
+	times := (&Rule{Freq: Daily, Interval: 7}).Between(monday, monday, monday.AddDate(0, 0, 8), 0)
+	if assert.Len(t, times, 2) {
+		assert.Equal(t, 7*24*time.Hour+time.Hour, times[1].Sub(times[0]))
+	}
+
+	assert.Equal(t, []string{"2024-10-21 09:30 Mon", "2024-11-04 09:30 Mon", "2024-11-18 09:30 Mon"},
+		expand(t, "FREQ=WEEKLY;INTERVAL=2", monday, 10)[:3])
+	assert.Equal(t, []string{"2024-10-21 09:30 Mon", "2024-10-23 09:30 Wed", "2024-10-25 09:30 Fri"},
+		expand(t, "FREQ=DAILY;INTERVAL=2;COUNT=3", monday, 10))
+	assert.Equal(t, []string{"2024-10-21 09:30 Mon", "2024-10-22 09:30 Tue"},
+		expand(t, "FREQ=DAILY;UNTIL=20241022T073000Z", monday, 10))
+	assert.Equal(t, []string{"2024-10-21 09:30 Mon", "2024-10-22 09:30 Tue"},
+		expand(t, "FREQ=DAILY;UNTIL=20241022", monday, 10))
+
+	// Meetups on the last Friday of the month, the first one after the start
+	assert.Equal(t, []string{"2024-10-25 09:30 Fri", "2024-11-29 09:30 Fri", "2024-12-27 09:30 Fri"},
+		expand(t, "FREQ=MONTHLY;BYDAY=-1FR", monday, 3))
+	assert.Equal(t, []string{"2024-10-31 09:30 Thu", "2024-11-29 09:30 Fri", "2024-12-31 09:30 Tue"},
+		expand(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", monday, 3))
+	assert.Equal(t, []string{"2024-10-31 09:30 Thu", "2024-11-30 09:30 Sat", "2024-12-31 09:30 Tue"},
+		expand(t, "FREQ=MONTHLY;BYMONTHDAY=-1", monday, 3))
+
+	// Months without the day are skipped
+	jan31 := time.Date(2025, 1, 31, 18, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2025-01-31 18:00 Fri", "2025-03-31 18:00 Mon", "2025-05-31 18:00 Sat"},
+		expand(t, "FREQ=MONTHLY", jan31, 3))
+	leap := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2024-02-29 12:00 Thu", "2028-02-29 12:00 Tue"}, expand(t, "FREQ=YEARLY", leap, 2))
+	assert.Equal(t, []string{"2024-11-28 12:00 Thu", "2025-11-27 12:00 Thu"},
+		expand(t, "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", leap, 2))
+	assert.Equal(t, []string{"2024-12-30 12:00 Mon", "2025-12-29 12:00 Mon"},
+		expand(t, "FREQ=YEARLY;BYDAY=-1MO", leap, 2))
+
+	// A rule that never occurs ends
+	assert.Empty(t, expand(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", leap, 1))
+
+	r, _ := Parse("FREQ=WEEKLY;BYDAY=MO")
+	assert.True(t, r.Includes(monday, monday.AddDate(0, 0, 14)))
+	assert.False(t, r.Includes(monday, monday.AddDate(0, 0, 15)))
+	assert.False(t, r.Includes(monday, monday.AddDate(0, 0, -7)))
+}
+
+func TestBetweenSkipsPeriods(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	dtstart := time.Date(2024, 10, 21, 9, 30, 0, 0, paris)
+	from, to := time.Date(2031, 3, 28, 0, 0, 0, 0, time.UTC), time.Date(2031, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// The periods skipped can't change the occurrences in the window
+	for _, text := range []string{
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,TU;WKST=SU",
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-1",
+		"FREQ=YEARLY;BYMONTH=4;BYDAY=1MO",
+		"FREQ=DAILY;UNTIL=20310415",
+	} {
+		r, err := Parse(text)
+		assert.NoError(t, err)
+		var walked []time.Time
+		r.Each(dtstart, func(t time.Time) bool {
+			if !t.Before(from) && t.Before(to) {
+				walked = append(walked, t)
+			}
+			return t.Before(to)
+		})
+		assert.NotEmpty(t, walked, text)
+		assert.Equal(t, walked, r.Between(dtstart, from, to, 0), text)
+	}
+
+	// Far windows are reached at once
+	r, _ := Parse("FREQ=DAILY")
+	far := time.Date(9998, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := r.Between(dtstart, far, far.AddDate(0, 0, 2), 0)
+	if assert.Len(t, times, 2) {
+		assert.Equal(t, "9998-01-01 09:30", times[0].Format("2006-01-02 15:04"))
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"gotempl/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APITokenRepository stores the personal access tokens, or only those of one
+// organization when it was obtained through ForOrg
+type APITokenRepository interface {
+	// ForOrg returns a repository limited to the organization's tokens, the
+	// tokens it creates belong to the organization. An empty orgID limits it to
+	// the tokens without an organization.
+	ForOrg(orgID string) APITokenRepository
+	Create(ctx context.Context, token *model.APIToken) error
+	// GetAll returns every token, the latest first
+	GetAll(ctx context.Context) ([]model.APIToken, error)
+	GetByID(ctx context.Context, id uint64) (*model.APIToken, error)
+	GetByHash(ctx context.Context, hash string) (*model.APIToken, error)
+	// Revoke returns gorm.ErrRecordNotFound when there is no such active token
+	Revoke(ctx context.Context, id uint64, at time.Time) error
+	TouchLastUsed(ctx context.Context, id uint64, at time.Time) error
+}
+
+// GormAPITokenRepository is the APITokenRepository of a gorm database
+type GormAPITokenRepository struct {
+	DB *gorm.DB
+
+	orgID  string
+	scoped bool
+}
+
+func NewAPITokenRepository(db *gorm.DB) *GormAPITokenRepository {
+	return &GormAPITokenRepository{DB: db}
+}
+
+func (r *GormAPITokenRepository) ForOrg(orgID string) APITokenRepository {
+	return &GormAPITokenRepository{DB: r.DB, orgID: orgID, scoped: true}
+}
+
+func (r *GormAPITokenRepository) query(ctx context.Context) *gorm.DB {
+	if !r.scoped {
+		return conn(ctx, r.DB)
+	}
+	return conn(ctx, r.DB).Where("org_id = ?", r.orgID)
+}
+
+func (r *GormAPITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	if r.scoped {
+		token.OrgID = r.orgID
+	}
+	return conn(ctx, r.DB).Create(token).Error
+}
+
+func (r *GormAPITokenRepository) GetAll(ctx context.Context) ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	err := r.query(ctx).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *GormAPITokenRepository) GetByID(ctx context.Context, id uint64) (*model.APIToken, error) {
+	var token model.APIToken
+	err := r.query(ctx).First(&token, "id = ?", id).Error
+	return &token, err
+}
+
+func (r *GormAPITokenRepository) GetByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	var token model.APIToken
+	err := r.query(ctx).First(&token, "token_hash = ?", hash).Error
+	return &token, err
+}
+
+func (r *GormAPITokenRepository) Revoke(ctx context.Context, id uint64, at time.Time) error {
+	result := r.query(ctx).Model(&model.APIToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	return rowsAffected(result)
+}
+
+func (r *GormAPITokenRepository) TouchLastUsed(ctx context.Context, id uint64, at time.Time) error {
+	return r.query(ctx).Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"gotempl/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttendanceRepository stores the registrations to events
+type AttendanceRepository interface {
+	Transactor
+
+	Get(ctx context.Context, eventID uint64, userUid string) (*model.Attendance, error)
+	// List returns the registrations to the event, those with a seat first,
+	// then the waitlist in order
+	List(ctx context.Context, eventID uint64) ([]model.Attendance, error)
+	// NextWaitlisted returns the first registration of the waitlist, locked
+	// until the end of the transaction
+	NextWaitlisted(ctx context.Context, eventID uint64) (*model.Attendance, error)
+	// Create returns gorm.ErrDuplicatedKey when the user is already registered
+	Create(ctx context.Context, attendance *model.Attendance) error
+	// SetStatus returns gorm.ErrRecordNotFound when there is no such registration
+	SetStatus(ctx context.Context, id uint64, status string) error
+	// Delete returns gorm.ErrRecordNotFound when there is no such registration
+	Delete(ctx context.Context, id uint64) error
+}
+
+// GormAttendanceRepository is the AttendanceRepository of a gorm database
+type GormAttendanceRepository struct {
+	*GormTransactor
+}
+
+func NewAttendanceRepository(db *gorm.DB) *GormAttendanceRepository {
+	return &GormAttendanceRepository{NewTransactor(db)}
+}
+
+func (r *GormAttendanceRepository) Get(ctx context.Context, eventID uint64, userUid string) (*model.Attendance, error) {
+	var attendance model.Attendance
+	if err := conn(ctx, r.DB).First(&attendance, "event_id = ? AND user_uid = ?", eventID, userUid).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+func (r *GormAttendanceRepository) List(ctx context.Context, eventID uint64) ([]model.Attendance, error) {
+	attendances := []model.Attendance{}
+	err := conn(ctx, r.DB).Where("event_id = ?", eventID).
+		Order(gorm.Expr("CASE WHEN status = ? THEN 0 ELSE 1 END", model.AttendanceGoing)).
+		Order("id").
+		Find(&attendances).Error
+	return attendances, err
+}
+
+func (r *GormAttendanceRepository) NextWaitlisted(ctx context.Context, eventID uint64) (*model.Attendance, error) {
+	var attendance model.Attendance
+	// A locking read sees the promotions committed since the transaction began
+	err := conn(ctx, r.DB).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("event_id = ? AND status = ?", eventID, model.AttendanceWaitlisted).Order("id").First(&attendance).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+func (r *GormAttendanceRepository) Create(ctx context.Context, attendance *model.Attendance) error {
+	return translate(r.DB, conn(ctx, r.DB).Create(attendance).Error)
+}
+
+func (r *GormAttendanceRepository) SetStatus(ctx context.Context, id uint64, status string) error {
+	result := conn(ctx, r.DB).Model(&model.Attendance{}).Where("id = ?", id).Update("status", status)
+	return rowsAffected(result)
+}
+
+func (r *GormAttendanceRepository) Delete(ctx context.Context, id uint64) error {
+	return rowsAffected(conn(ctx, r.DB).Delete(&model.Attendance{}, id))
+}
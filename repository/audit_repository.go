@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"gotempl/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditSorts are the columns audit entries can be sorted by
+var AuditSorts = map[string]SortColumn{
+	"id":         {Field: "ID"},
+	"created_at": {Field: "CreatedAt"},
+}
+
+// AuditFilter narrows the audit log, empty fields don't filter
+type AuditFilter struct {
+	Entity    string     `form:"entity"`
+	EntityID  string     `form:"id"`
+	Action    string     `form:"action"`
+	Actor     string     `form:"actor"`
+	RequestID string     `form:"request_id"`
+	Since     *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     *time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func (f AuditFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Entity != "" {
+		query = query.Where("entity = ?", f.Entity)
+	}
+	if f.EntityID != "" {
+		query = query.Where("entity_id = ?", f.EntityID)
+	}
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.Actor != "" {
+		query = query.Where("actor = ?", f.Actor)
+	}
+	if f.RequestID != "" {
+		query = query.Where("request_id = ?", f.RequestID)
+	}
+	if f.Since != nil {
+		query = query.Where("created_at >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		query = query.Where("created_at < ?", *f.Until)
+	}
+	return query
+}
+
+func (f AuditFilter) match(record any) bool {
+	entry := record.(*model.AuditEntry)
+	return (f.Entity == "" || entry.Entity == f.Entity) &&
+		(f.EntityID == "" || entry.EntityID == f.EntityID) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.RequestID == "" || entry.RequestID == f.RequestID) &&
+		(f.Since == nil || !entry.CreatedAt.Before(*f.Since)) &&
+		(f.Until == nil || entry.CreatedAt.Before(*f.Until))
+}
+
+// AuditRepository reads and appends to the audit log, entries are never changed
+type AuditRepository interface {
+	// ForOrg returns a repository limited to the entries of the organization's records
+	ForOrg(orgID string) AuditRepository
+	Create(ctx context.Context, entry *model.AuditEntry) error
+	// List returns a page of the entries matching the filter, by default the latest first
+	List(ctx context.Context, filter AuditFilter, opts ListOptions) (*Page[model.AuditEntry], error)
+}
+
+// GormAuditRepository is the AuditRepository of a gorm database
+type GormAuditRepository struct {
+	DB *gorm.DB
+
+	orgID  string
+	scoped bool
+}
+
+func NewAuditRepository(db *gorm.DB) *GormAuditRepository {
+	return &GormAuditRepository{DB: db}
+}
+
+func (r *GormAuditRepository) ForOrg(orgID string) AuditRepository {
+	return &GormAuditRepository{DB: r.DB, orgID: orgID, scoped: true}
+}
+
+func (r *GormAuditRepository) query(ctx context.Context) *gorm.DB {
+	if !r.scoped {
+		return conn(ctx, r.DB)
+	}
+	return conn(ctx, r.DB).Where("org_id = ?", r.orgID)
+}
+
+func (r *GormAuditRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	return conn(ctx, r.DB).Create(entry).Error
+}
+
+func (r *GormAuditRepository) List(ctx context.Context, filter AuditFilter, opts ListOptions) (*Page[model.AuditEntry], error) {
+	return paginate[model.AuditEntry](filter.apply(r.query(ctx)), opts, AuditSorts, "-id")
+}
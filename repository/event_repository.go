@@ -1,42 +1,193 @@
 package repository
 
 import (
+	"cmp"
+	"context"
 	"gotempl/model"
+	"slices"
+	"time"
 
 	"gorm.io/gorm"
 )
 
-type EventRepository struct {
-	DB *gorm.DB
+// EventSorts are the columns events can be sorted by
+var EventSorts = map[string]SortColumn{
+	"id":         {Field: "ID"},
+	"start_time": {Field: "StartTime", Nullable: true},
+	"end_time":   {Field: "EndTime", Nullable: true},
+	"created_at": {Field: "CreatedAt"},
+	"updated_at": {Field: "UpdatedAt"},
+	"title":      {Field: "Title"},
 }
 
-func NewEventRepository(db *gorm.DB) *EventRepository {
-	return &EventRepository{DB: db}
+// EventFilter narrows event lists, empty fields don't filter
+type EventFilter struct {
+	Status      string     `form:"status"`
+	EventType   string     `form:"event_type"`
+	CreatedBy   string     `form:"created_by"`
+	IsPublic    *bool      `form:"is_public"`
+	IsFeatured  *bool      `form:"is_featured"`
+	StartAfter  *time.Time `form:"start_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	StartBefore *time.Time `form:"start_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Tag         string     `form:"tag"`
+	Recurring   *bool      `form:"recurring"`
+	// SeriesID lists the events overriding occurrences of the series, the
+	// other lists leave them out
+	SeriesID *uint64 `form:"series_id"`
 }
 
-func (r *EventRepository) Create(event *model.Event) error {
-	return r.DB.Create(event).Error
+func (f EventFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.EventType != "" {
+		query = query.Where("event_type = ?", f.EventType)
+	}
+	if f.CreatedBy != "" {
+		query = query.Where("created_by = ?", f.CreatedBy)
+	}
+	if f.IsPublic != nil {
+		query = query.Where("is_public = ?", *f.IsPublic)
+	}
+	if f.IsFeatured != nil {
+		query = query.Where("is_featured = ?", *f.IsFeatured)
+	}
+	// Times are stored in UTC, SQLite compares them as text
+	if f.StartAfter != nil {
+		query = query.Where("start_time >= ?", f.StartAfter.UTC())
+	}
+	if f.StartBefore != nil {
+		query = query.Where("start_time < ?", f.StartBefore.UTC())
+	}
+	if f.Tag != "" {
+		// Valid tags need no escaping in JSON nor in LIKE, matching the quoted
+		// tag works on every database
+		if !model.ValidTag(f.Tag) {
+			return query.Where("1 = 0")
+		}
+		column := "tags"
+		if query.Dialector.Name() == "postgres" {
+			// json has no LIKE there
+			column = "CAST(tags AS TEXT)"
+		}
+		query = query.Where(column+" LIKE ?", `%"`+f.Tag+`"%`)
+	}
+	if f.Recurring != nil {
+		if *f.Recurring {
+			query = query.Where("rrule <> ''")
+		} else {
+			query = query.Where("rrule = ''")
+		}
+	}
+	if f.SeriesID != nil {
+		query = query.Where("series_id = ?", *f.SeriesID)
+	} else {
+		query = query.Where("series_id IS NULL")
+	}
+	return query
 }
 
-func (r *EventRepository) GetAll() ([]model.Event, error) {
-	var events []model.Event
-	err := r.DB.Find(&events).Error
-	return events, err
+func (f EventFilter) match(record any) bool {
+	event := record.(*model.Event)
+	return (f.Status == "" || event.Status == f.Status) &&
+		(f.EventType == "" || event.EventType == f.EventType) &&
+		(f.CreatedBy == "" || event.CreatedBy == f.CreatedBy) &&
+		(f.IsPublic == nil || event.IsPublic == *f.IsPublic) &&
+		(f.IsFeatured == nil || event.IsFeatured == *f.IsFeatured) &&
+		(f.StartAfter == nil || event.StartTime != nil && !event.StartTime.Before(*f.StartAfter)) &&
+		(f.StartBefore == nil || event.StartTime != nil && event.StartTime.Before(*f.StartBefore)) &&
+		(f.Tag == "" || slices.Contains(event.Tags, f.Tag)) &&
+		(f.Recurring == nil || (event.RRule != "") == *f.Recurring) &&
+		(f.SeriesID == nil && event.SeriesID == nil || f.SeriesID != nil && event.SeriesID != nil && *event.SeriesID == *f.SeriesID)
+}
+
+// EventRepository stores every event, or only those of one organization when
+// it was obtained through ForOrg
+type EventRepository interface {
+	Repository[model.Event, uint64]
+	// ForOrg returns a repository limited to the organization's events. An
+	// empty orgID limits it to events that don't belong to any organization.
+	ForOrg(orgID string) EventRepository
+	// AddAttendee takes a seat of the live event, it tells whether one was
+	// left. A MaxAttendees of 0 doesn't limit the seats.
+	AddAttendee(ctx context.Context, id uint64) (bool, error)
+	// RemoveAttendee gives a seat of the event back
+	RemoveAttendee(ctx context.Context, id uint64) error
+	// TagCounts returns how many live events have each tag, the most used first
+	TagCounts(ctx context.Context) ([]TagCount, error)
 }
 
-func (r *EventRepository) GetByID(id uint64) (*model.Event, error) {
-	var event model.Event
-	result := r.DB.First(&event, "id = ?", id)
-	if result.Error != nil {
-		return nil, result.Error
+// TagCount is how many events have a tag
+type TagCount struct {
+	Tag   string `json:"tag" example:"workshop"`
+	Count int64  `json:"count" example:"12"`
+}
+
+// sortTagCounts lists counts, the most used first and then by tag
+func sortTagCounts(counts map[string]int64) []TagCount {
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
 	}
-	return &event, nil
+	slices.SortFunc(tags, func(a, b TagCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Tag, b.Tag))
+	})
+	return tags
+}
+
+// GormEventRepository is the EventRepository of a gorm database
+type GormEventRepository struct {
+	*GormRepository[model.Event, uint64]
 }
 
-func (r *EventRepository) Update(event *model.Event) error {
-	return r.DB.Save(event).Error
+func NewEventRepository(db *gorm.DB) *GormEventRepository {
+	return &GormEventRepository{NewRepository[model.Event, uint64](db, EventSorts, "id")}
 }
 
-func (r *EventRepository) Delete(id uint64) error {
-	return r.DB.Delete(&model.Event{}, "id = ?", id).Error
+func (r *GormEventRepository) ForOrg(orgID string) EventRepository {
+	return &GormEventRepository{r.Scope(
+		func(query *gorm.DB) *gorm.DB { return query.Where("org_id = ?", orgID) },
+		func(event *model.Event) { event.OrgID = orgID },
+	)}
+}
+
+// Seats are counted with conditional updates rather than read and written, so
+// concurrent registrations can't overbook an event. They bump the version of
+// the event as it is part of its representation.
+
+func (r *GormEventRepository) AddAttendee(ctx context.Context, id uint64) (bool, error) {
+	result := r.byKey(ctx, id).
+		Where("deleted_at IS NULL").
+		Where("max_attendees = 0 OR attendees_count < max_attendees").
+		UpdateColumns(map[string]any{
+			"attendees_count": gorm.Expr("attendees_count + 1"),
+			"version":         nextVersion,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *GormEventRepository) RemoveAttendee(ctx context.Context, id uint64) error {
+	return r.byKey(ctx, id).Where("attendees_count > 0").UpdateColumns(map[string]any{
+		"attendees_count": gorm.Expr("attendees_count - 1"),
+		"version":         nextVersion,
+	}).Error
+}
+
+// TagCounts reads the tags in batches, JSON arrays can't be grouped by the same
+// SQL on every database
+func (r *GormEventRepository) TagCounts(ctx context.Context) ([]TagCount, error) {
+	counts := map[string]int64{}
+	var events []model.Event
+	err := r.query(ctx).Select("id", "tags").FindInBatches(&events, 500, func(tx *gorm.DB, batch int) error {
+		for _, event := range events {
+			for _, tag := range event.Tags {
+				counts[tag]++
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return sortTagCounts(counts), nil
 }
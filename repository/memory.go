@@ -0,0 +1,843 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"gotempl/model"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The Memory repositories keep their records in memory, for fast service
+// tests. They behave like their gorm counterparts but for transactions:
+// WithinTx neither isolates nor rolls back anything.
+
+// MemoryTransactor runs fn directly
+type MemoryTransactor struct{}
+
+func (MemoryTransactor) WithinTx(ctx context.Context, fn func(tx context.Context) error) error {
+	return fn(ctx)
+}
+
+// memoryTable is shared by a repository and its scoped copies
+type memoryTable[T any, ID comparable] struct {
+	mu      sync.Mutex
+	records map[ID]T
+	lastID  uint64
+}
+
+// MemoryRepository is a Repository in memory
+type MemoryRepository[T any, ID comparable] struct {
+	MemoryTransactor
+	fields[T, ID]
+
+	table       *memoryTable[T, ID]
+	sorts       map[string]SortColumn
+	defaultSort string
+	// in and assign limit the repository to some records, like GormRepository.Scope
+	in     func(record *T) bool
+	assign func(record *T)
+}
+
+// NewMemoryRepository returns an empty repository of T, key is the Go name of
+// its primary key. Integer keys left at zero are assigned on creation.
+func NewMemoryRepository[T any, ID comparable](key string, sorts map[string]SortColumn, defaultSort string) *MemoryRepository[T, ID] {
+	return &MemoryRepository[T, ID]{
+		fields:      fields[T, ID]{key: key},
+		table:       &memoryTable[T, ID]{records: map[ID]T{}},
+		sorts:       sorts,
+		defaultSort: defaultSort,
+	}
+}
+
+// Scope returns a repository limited to the records in matches, see GormRepository.Scope
+func (r *MemoryRepository[T, ID]) Scope(in func(record *T) bool, assign func(record *T)) *MemoryRepository[T, ID] {
+	scoped := *r
+	scoped.in = in
+	scoped.assign = assign
+	return &scoped
+}
+
+// get returns a copy of the record of the scope, trashed or not
+func (r *MemoryRepository[T, ID]) get(id ID) (*T, bool) {
+	record, ok := r.table.records[id]
+	if !ok || (r.in != nil && !r.in(&record)) {
+		return nil, false
+	}
+	return &record, true
+}
+
+func trashed[T any](record *T) bool {
+	return field(record, "DeletedAt").Interface().(gorm.DeletedAt).Valid
+}
+
+// matching returns copies of the records of the scope matching keep
+func (r *MemoryRepository[T, ID]) matching(keep func(record *T) bool) []T {
+	records := []T{}
+	for id := range r.table.records {
+		if record, ok := r.get(id); ok && keep(record) {
+			records = append(records, *record)
+		}
+	}
+	return records
+}
+
+func (r *MemoryRepository[T, ID]) Create(ctx context.Context, record *T) error {
+	if r.assign != nil {
+		r.assign(record)
+	}
+	r.reset(record)
+	touch(record, "CreatedAt", "UpdatedAt")
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	if key := field(record, r.key); key.IsZero() && key.CanUint() {
+		r.table.lastID++
+		key.SetUint(r.table.lastID)
+	} else if key.CanUint() {
+		r.table.lastID = max(r.table.lastID, key.Uint())
+	}
+	id := r.Key(record)
+	if _, ok := r.table.records[id]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	r.table.records[id] = *record
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) GetAll(ctx context.Context) ([]T, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	return r.matching(func(record *T) bool { return !trashed(record) }), nil
+}
+
+func (r *MemoryRepository[T, ID]) List(ctx context.Context, filter Filter, opts ListOptions) (*Page[T], error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	records := r.matching(func(record *T) bool { return !trashed(record) && filter.match(record) })
+	return paginateMemory(records, r.key, opts, r.sorts, r.defaultSort)
+}
+
+func (r *MemoryRepository[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	if record, ok := r.get(id); ok && !trashed(record) {
+		return record, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryRepository[T, ID]) GetByIDWithTrashed(ctx context.Context, id ID) (*T, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	if record, ok := r.get(id); ok {
+		return record, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryRepository[T, ID]) Update(ctx context.Context, record *T) error {
+	if r.assign != nil {
+		r.assign(record)
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	current, ok := r.get(r.Key(record))
+	if !ok || trashed(current) {
+		return gorm.ErrRecordNotFound
+	}
+	if r.Version(current) != r.Version(record) {
+		return ErrVersionConflict
+	}
+	field(record, "Version").SetUint(r.Version(record) + 1)
+	touch(record, "UpdatedAt")
+	r.table.records[r.Key(record)] = *record
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) Delete(ctx context.Context, id ID, version uint64, deletedBy string) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	record, ok := r.get(id)
+	if !ok || trashed(record) {
+		return gorm.ErrRecordNotFound
+	}
+	if version != 0 && r.Version(record) != version {
+		return ErrVersionConflict
+	}
+	field(record, "DeletedAt").Set(reflect.ValueOf(gorm.DeletedAt{Time: time.Now(), Valid: true}))
+	field(record, "DeletedBy").SetString(deletedBy)
+	field(record, "Version").SetUint(r.Version(record) + 1)
+	r.table.records[id] = *record
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) ListTrash(ctx context.Context, opts ListOptions) (*Page[T], error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	records := r.matching(trashed[T])
+	return paginateMemory(records, r.key, opts, trashSorts(r.sorts), "-deleted_at")
+}
+
+func (r *MemoryRepository[T, ID]) Restore(ctx context.Context, id ID) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	record, ok := r.get(id)
+	if !ok || !trashed(record) {
+		return gorm.ErrRecordNotFound
+	}
+	field(record, "DeletedAt").Set(reflect.ValueOf(gorm.DeletedAt{}))
+	field(record, "DeletedBy").SetString("")
+	field(record, "Version").SetUint(r.Version(record) + 1)
+	r.table.records[id] = *record
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) Purge(ctx context.Context, id ID) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	record, ok := r.get(id)
+	if !ok || !trashed(record) {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.table.records, id)
+	return nil
+}
+
+// touch sets the time fields the database would, when the record has them
+func touch[T any](record *T, names ...string) {
+	now := time.Now()
+	for _, name := range names {
+		if f := field(record, name); f.IsValid() && f.Type() == reflect.TypeOf(now) {
+			if name != "CreatedAt" || f.IsZero() {
+				f.Set(reflect.ValueOf(now))
+			}
+		}
+	}
+}
+
+// paginateMemory is paginate for records in memory, key is the Go name of
+// their primary key
+func paginateMemory[T any](records []T, key string, opts ListOptions, sorts map[string]SortColumn, defaultSort string) (*Page[T], error) {
+	column, desc, limit, err := order(opts, sorts, defaultSort)
+	if err != nil {
+		return nil, err
+	}
+	var zero T
+	sortType, ok := reflect.TypeOf(zero).FieldByName(column.Field)
+	keyType, _ := reflect.TypeOf(zero).FieldByName(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can't be sorted by %s", ErrInvalidListOptions, zero, column.Field)
+	}
+
+	// compare orders by the sort value, then by the primary key
+	compare := func(value, id any, record *T) int {
+		c := compareValues(value, field(record, column.Field).Interface())
+		if c == 0 {
+			c = compareValues(id, field(record, key).Interface())
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(records, func(a, b T) int {
+		return compare(field(&a, column.Field).Interface(), field(&a, key).Interface(), &b)
+	})
+
+	page := &Page[T]{Items: records, Total: int64(len(records))}
+	if opts.Cursor != "" {
+		value, id, err := decodeCursor(opts.Cursor, sortType.Type, keyType.Type)
+		if err != nil {
+			return nil, err
+		}
+		start := slices.IndexFunc(records, func(record T) bool { return compare(value, id, &record) < 0 })
+		if start < 0 {
+			start = len(records)
+		}
+		page.Items = records[start:]
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := &page.Items[limit-1]
+		page.NextCursor, err = encodeCursor(field(last, column.Field).Interface(), field(last, key).Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// compareValues compares two values of the same column type
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case *time.Time:
+		// nil is the zero time, as NULL in the SQL sorts
+		var at, bt time.Time
+		if a != nil {
+			at = *a
+		}
+		if b := b.(*time.Time); b != nil {
+			bt = *b
+		}
+		return at.Compare(bt)
+	case gorm.DeletedAt:
+		return a.Time.Compare(b.(gorm.DeletedAt).Time)
+	case string:
+		return cmp.Compare(a, b.(string))
+	case bool:
+		return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case av.CanInt():
+		return cmp.Compare(av.Int(), bv.Int())
+	case av.CanUint():
+		return cmp.Compare(av.Uint(), bv.Uint())
+	case av.CanFloat():
+		return cmp.Compare(av.Float(), bv.Float())
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// MemoryEventRepository is an EventRepository in memory
+type MemoryEventRepository struct {
+	*MemoryRepository[model.Event, uint64]
+}
+
+func NewMemoryEventRepository() *MemoryEventRepository {
+	return &MemoryEventRepository{NewMemoryRepository[model.Event, uint64]("ID", EventSorts, "id")}
+}
+
+func (r *MemoryEventRepository) ForOrg(orgID string) EventRepository {
+	return &MemoryEventRepository{r.Scope(
+		func(event *model.Event) bool { return event.OrgID == orgID },
+		func(event *model.Event) { event.OrgID = orgID },
+	)}
+}
+
+func (r *MemoryEventRepository) AddAttendee(ctx context.Context, id uint64) (bool, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	event, ok := r.get(id)
+	if !ok || trashed(event) || (event.MaxAttendees > 0 && event.AttendeesCount >= event.MaxAttendees) {
+		return false, nil
+	}
+	event.AttendeesCount++
+	event.Version++
+	r.table.records[id] = *event
+	return true, nil
+}
+
+func (r *MemoryEventRepository) RemoveAttendee(ctx context.Context, id uint64) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	if event, ok := r.get(id); ok && event.AttendeesCount > 0 {
+		event.AttendeesCount--
+		event.Version++
+		r.table.records[id] = *event
+	}
+	return nil
+}
+
+func (r *MemoryEventRepository) TagCounts(ctx context.Context) ([]TagCount, error) {
+	events, err := r.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, event := range events {
+		for _, tag := range event.Tags {
+			counts[tag]++
+		}
+	}
+	return sortTagCounts(counts), nil
+}
+
+// MemoryUserRepository is a UserRepository in memory, the memberships its
+// ForOrg relies on are those of the MemoryOrganizationRepository built on it
+type MemoryUserRepository struct {
+	*MemoryRepository[model.User, string]
+
+	orgs   *MemoryOrganizationRepository
+	orgID  string
+	scoped bool
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{MemoryRepository: NewMemoryRepository[model.User, string]("Uid", UserSorts, "username")}
+}
+
+func (r *MemoryUserRepository) ForOrg(orgID string) UserRepository {
+	in := func(user *model.User) bool {
+		var orgIDs []string
+		if r.orgs != nil {
+			orgIDs = r.orgs.orgsOf(user.Uid)
+		}
+		if orgID == "" {
+			return len(orgIDs) == 0
+		}
+		return slices.Contains(orgIDs, orgID)
+	}
+	return &MemoryUserRepository{MemoryRepository: r.Scope(in, nil), orgs: r.orgs, orgID: orgID, scoped: true}
+}
+
+// Create adds the user to the organization of the repository
+func (r *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	if err := r.MemoryRepository.Create(ctx, user); err != nil || !r.scoped || r.orgID == "" || r.orgs == nil {
+		return err
+	}
+	return r.orgs.UpsertMembership(ctx, &model.Membership{UserUid: user.Uid, OrgID: r.orgID})
+}
+
+func (r *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := r.GetByUsernameWithTrashed(ctx, username)
+	if err != nil || trashed(user) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *MemoryUserRepository) GetByUsernameWithTrashed(ctx context.Context, username string) (*model.User, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+	for uid := range r.table.records {
+		if user, ok := r.get(uid); ok && user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryUserRepository) OrgIDs(ctx context.Context, uid string) ([]string, error) {
+	if r.orgs == nil {
+		return []string{}, nil
+	}
+	orgIDs := r.orgs.orgsOf(uid)
+	slices.Sort(orgIDs)
+	return orgIDs, nil
+}
+
+// MemoryAuditRepository is an AuditRepository in memory
+type MemoryAuditRepository struct {
+	log *memoryLog
+
+	orgID  string
+	scoped bool
+}
+
+type memoryLog struct {
+	mu      sync.Mutex
+	entries []model.AuditEntry
+}
+
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{log: &memoryLog{}}
+}
+
+func (r *MemoryAuditRepository) ForOrg(orgID string) AuditRepository {
+	return &MemoryAuditRepository{log: r.log, orgID: orgID, scoped: true}
+}
+
+func (r *MemoryAuditRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	r.log.mu.Lock()
+	defer r.log.mu.Unlock()
+	entry.ID = uint64(len(r.log.entries) + 1)
+	touch(entry, "CreatedAt")
+	r.log.entries = append(r.log.entries, *entry)
+	return nil
+}
+
+func (r *MemoryAuditRepository) List(ctx context.Context, filter AuditFilter, opts ListOptions) (*Page[model.AuditEntry], error) {
+	r.log.mu.Lock()
+	defer r.log.mu.Unlock()
+	entries := []model.AuditEntry{}
+	for _, entry := range r.log.entries {
+		if (!r.scoped || entry.OrgID == r.orgID) && filter.match(&entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return paginateMemory(entries, "ID", opts, AuditSorts, "-id")
+}
+
+// MemoryAPITokenRepository is an APITokenRepository in memory
+type MemoryAPITokenRepository struct {
+	store *memoryTokens
+
+	orgID  string
+	scoped bool
+}
+
+type memoryTokens struct {
+	mu     sync.Mutex
+	tokens []model.APIToken
+}
+
+func NewMemoryAPITokenRepository() *MemoryAPITokenRepository {
+	return &MemoryAPITokenRepository{store: &memoryTokens{}}
+}
+
+func (r *MemoryAPITokenRepository) ForOrg(orgID string) APITokenRepository {
+	return &MemoryAPITokenRepository{store: r.store, orgID: orgID, scoped: true}
+}
+
+func (r *MemoryAPITokenRepository) inScope(token *model.APIToken) bool {
+	return !r.scoped || token.OrgID == r.orgID
+}
+
+func (r *MemoryAPITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if r.scoped {
+		token.OrgID = r.orgID
+	}
+	token.ID = uint64(len(r.store.tokens) + 1)
+	touch(token, "CreatedAt")
+	r.store.tokens = append(r.store.tokens, *token)
+	return nil
+}
+
+func (r *MemoryAPITokenRepository) GetAll(ctx context.Context) ([]model.APIToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	tokens := []model.APIToken{}
+	for i := len(r.store.tokens) - 1; i >= 0; i-- {
+		if r.inScope(&r.store.tokens[i]) {
+			tokens = append(tokens, r.store.tokens[i])
+		}
+	}
+	return tokens, nil
+}
+
+func (r *MemoryAPITokenRepository) find(match func(token *model.APIToken) bool) (*model.APIToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.tokens {
+		if r.inScope(&r.store.tokens[i]) && match(&r.store.tokens[i]) {
+			token := r.store.tokens[i]
+			return &token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAPITokenRepository) GetByID(ctx context.Context, id uint64) (*model.APIToken, error) {
+	return r.find(func(token *model.APIToken) bool { return token.ID == id })
+}
+
+func (r *MemoryAPITokenRepository) GetByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	return r.find(func(token *model.APIToken) bool { return token.TokenHash == hash })
+}
+
+func (r *MemoryAPITokenRepository) Revoke(ctx context.Context, id uint64, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.tokens {
+		token := &r.store.tokens[i]
+		if token.ID == id && token.RevokedAt == nil && r.inScope(token) {
+			token.RevokedAt = &at
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAPITokenRepository) TouchLastUsed(ctx context.Context, id uint64, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.tokens {
+		if r.store.tokens[i].ID == id && r.inScope(&r.store.tokens[i]) {
+			r.store.tokens[i].LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+// MemoryOrganizationRepository is an OrganizationRepository in memory, its
+// members are looked up in Users
+type MemoryOrganizationRepository struct {
+	MemoryTransactor
+	Users UserRepository
+
+	mu          sync.Mutex
+	orgs        map[string]model.Organization
+	memberships map[[2]string]model.Membership
+}
+
+func NewMemoryOrganizationRepository(users UserRepository) *MemoryOrganizationRepository {
+	r := &MemoryOrganizationRepository{
+		Users:       users,
+		orgs:        map[string]model.Organization{},
+		memberships: map[[2]string]model.Membership{},
+	}
+	if users, ok := users.(*MemoryUserRepository); ok {
+		users.orgs = r
+	}
+	return r
+}
+
+// orgsOf returns the organizations of the user
+func (r *MemoryOrganizationRepository) orgsOf(uid string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var orgIDs []string
+	for key := range r.memberships {
+		if key[0] == uid {
+			orgIDs = append(orgIDs, key[1])
+		}
+	}
+	return orgIDs
+}
+
+func (r *MemoryOrganizationRepository) GetAll(ctx context.Context) ([]model.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	orgs := []model.Organization{}
+	for _, org := range r.orgs {
+		orgs = append(orgs, org)
+	}
+	slices.SortFunc(orgs, func(a, b model.Organization) int { return cmp.Compare(a.Name, b.Name) })
+	return orgs, nil
+}
+
+func (r *MemoryOrganizationRepository) GetByID(ctx context.Context, id string) (*model.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	org, ok := r.orgs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &org, nil
+}
+
+func (r *MemoryOrganizationRepository) Upsert(ctx context.Context, org *model.Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.orgs[org.ID]; ok {
+		existing.Name = org.Name
+		*org = existing
+	}
+	touch(org, "CreatedAt")
+	r.orgs[org.ID] = *org
+	return nil
+}
+
+func (r *MemoryOrganizationRepository) UpsertMembership(ctx context.Context, membership *model.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memberships[[2]string{membership.UserUid, membership.OrgID}] = *membership
+	return nil
+}
+
+func (r *MemoryOrganizationRepository) GetMembers(ctx context.Context, orgID string) ([]model.User, error) {
+	r.mu.Lock()
+	var uids []string
+	for key := range r.memberships {
+		if key[1] == orgID {
+			uids = append(uids, key[0])
+		}
+	}
+	r.mu.Unlock()
+
+	users := []model.User{}
+	for _, uid := range uids {
+		user, err := r.Users.GetByID(ctx, uid)
+		if err != nil {
+			continue
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+// MemoryAttendanceRepository is an AttendanceRepository in memory
+type MemoryAttendanceRepository struct {
+	MemoryTransactor
+
+	mu          sync.Mutex
+	attendances []model.Attendance
+	lastID      uint64
+}
+
+func NewMemoryAttendanceRepository() *MemoryAttendanceRepository {
+	return &MemoryAttendanceRepository{}
+}
+
+// find returns the index of the first registration matching, or -1
+func (r *MemoryAttendanceRepository) find(match func(attendance *model.Attendance) bool) int {
+	return slices.IndexFunc(r.attendances, func(attendance model.Attendance) bool { return match(&attendance) })
+}
+
+func (r *MemoryAttendanceRepository) Get(ctx context.Context, eventID uint64, userUid string) (*model.Attendance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(func(attendance *model.Attendance) bool {
+		return attendance.EventID == eventID && attendance.UserUid == userUid
+	})
+	if i < 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	attendance := r.attendances[i]
+	return &attendance, nil
+}
+
+func (r *MemoryAttendanceRepository) List(ctx context.Context, eventID uint64) ([]model.Attendance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attendances := []model.Attendance{}
+	for _, status := range []string{model.AttendanceGoing, model.AttendanceWaitlisted} {
+		for _, attendance := range r.attendances {
+			if attendance.EventID == eventID && attendance.Status == status {
+				attendances = append(attendances, attendance)
+			}
+		}
+	}
+	return attendances, nil
+}
+
+func (r *MemoryAttendanceRepository) NextWaitlisted(ctx context.Context, eventID uint64) (*model.Attendance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(func(attendance *model.Attendance) bool {
+		return attendance.EventID == eventID && attendance.Status == model.AttendanceWaitlisted
+	})
+	if i < 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	attendance := r.attendances[i]
+	return &attendance, nil
+}
+
+func (r *MemoryAttendanceRepository) Create(ctx context.Context, attendance *model.Attendance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.find(func(other *model.Attendance) bool {
+		return other.EventID == attendance.EventID && other.UserUid == attendance.UserUid
+	}) >= 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	r.lastID++
+	attendance.ID = r.lastID
+	touch(attendance, "CreatedAt", "UpdatedAt")
+	r.attendances = append(r.attendances, *attendance)
+	return nil
+}
+
+func (r *MemoryAttendanceRepository) SetStatus(ctx context.Context, id uint64, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(func(attendance *model.Attendance) bool { return attendance.ID == id })
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	r.attendances[i].Status = status
+	touch(&r.attendances[i], "UpdatedAt")
+	return nil
+}
+
+func (r *MemoryAttendanceRepository) Delete(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(func(attendance *model.Attendance) bool { return attendance.ID == id })
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	r.attendances = slices.Delete(r.attendances, i, i+1)
+	return nil
+}
+
+// MemorySearchRepository is a SearchRepository in memory, it ranks events
+// like LikeSearchRepository
+type MemorySearchRepository struct {
+	index *memoryIndex
+
+	orgID  string
+	scoped bool
+}
+
+type memoryIndex struct {
+	mu     sync.Mutex
+	events map[uint64]model.Event
+}
+
+func NewMemorySearchRepository() *MemorySearchRepository {
+	return &MemorySearchRepository{index: &memoryIndex{events: map[uint64]model.Event{}}}
+}
+
+func (r *MemorySearchRepository) ForOrg(orgID string) SearchRepository {
+	return &MemorySearchRepository{index: r.index, orgID: orgID, scoped: true}
+}
+
+func (r *MemorySearchRepository) Index(ctx context.Context, event *model.Event) error {
+	r.index.mu.Lock()
+	defer r.index.mu.Unlock()
+	r.index.events[event.ID] = *event
+	return nil
+}
+
+func (r *MemorySearchRepository) Remove(ctx context.Context, id uint64) error {
+	r.index.mu.Lock()
+	defer r.index.mu.Unlock()
+	if event, ok := r.index.events[id]; ok && (!r.scoped || event.OrgID == r.orgID) {
+		delete(r.index.events, id)
+	}
+	return nil
+}
+
+func (r *MemorySearchRepository) Search(ctx context.Context, query string, limit int) (*Page[SearchResult], error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	r.index.mu.Lock()
+	defer r.index.mu.Unlock()
+	results := []SearchResult{}
+	for _, event := range r.index.events {
+		if r.scoped && event.OrgID != r.orgID {
+			continue
+		}
+		fields := searchText(&event)
+		text := strings.ToLower(strings.Join(fields, " "))
+		if !allTerms(text, terms) {
+			continue
+		}
+		rank := 0.0
+		for i, field := range fields {
+			for _, term := range terms {
+				rank += likeWeights[i] * float64(strings.Count(strings.ToLower(field), term))
+			}
+		}
+		results = append(results, SearchResult{Event: event, Rank: rank, Snippet: snippet(fields, terms)})
+	}
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return cmp.Or(cmp.Compare(b.Rank, a.Rank), cmp.Compare(a.Event.ID, b.Event.ID))
+	})
+
+	page := &Page[SearchResult]{Items: results, Total: int64(len(results))}
+	if len(page.Items) > searchLimit(limit) {
+		page.Items = page.Items[:searchLimit(limit)]
+	}
+	return page, nil
+}
+
+// allTerms tells whether every term starts a word of text
+func allTerms(text string, terms []string) bool {
+	words := searchTerms(text)
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+			return false
+		}
+	}
+	return true
+}
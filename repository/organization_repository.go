@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"gotempl/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationRepository stores the organizations and their memberships
+type OrganizationRepository interface {
+	Transactor
+
+	// GetAll returns every organization by name
+	GetAll(ctx context.Context) ([]model.Organization, error)
+	GetByID(ctx context.Context, id string) (*model.Organization, error)
+	// Upsert creates the organization or renames it
+	Upsert(ctx context.Context, org *model.Organization) error
+	// UpsertMembership creates the membership or updates the member's role
+	UpsertMembership(ctx context.Context, membership *model.Membership) error
+	GetMembers(ctx context.Context, orgID string) ([]model.User, error)
+}
+
+// GormOrganizationRepository is the OrganizationRepository of a gorm database
+type GormOrganizationRepository struct {
+	*GormTransactor
+}
+
+func NewOrganizationRepository(db *gorm.DB) *GormOrganizationRepository {
+	return &GormOrganizationRepository{NewTransactor(db)}
+}
+
+func (r *GormOrganizationRepository) GetAll(ctx context.Context) ([]model.Organization, error) {
+	var orgs []model.Organization
+	err := conn(ctx, r.DB).Order("name").Find(&orgs).Error
+	return orgs, err
+}
+
+func (r *GormOrganizationRepository) GetByID(ctx context.Context, id string) (*model.Organization, error) {
+	var org model.Organization
+	err := conn(ctx, r.DB).First(&org, "id = ?", id).Error
+	return &org, err
+}
+
+func (r *GormOrganizationRepository) Upsert(ctx context.Context, org *model.Organization) error {
+	return conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name"}),
+	}).Create(org).Error
+}
+
+func (r *GormOrganizationRepository) UpsertMembership(ctx context.Context, membership *model.Membership) error {
+	return conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_uid"}, {Name: "org_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(membership).Error
+}
+
+func (r *GormOrganizationRepository) GetMembers(ctx context.Context, orgID string) ([]model.User, error) {
+	var users []model.User
+	err := conn(ctx, r.DB).Joins("JOIN memberships ON memberships.user_uid = users.uid").
+		Where("memberships.org_id = ?", orgID).
+		Find(&users).Error
+	return users, err
+}
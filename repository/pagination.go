@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidListOptions is returned for unknown sort columns and malformed cursors
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// ListOptions selects a page of a list. Pages are read with keyset pagination:
+// Cursor is the NextCursor of the previous page, and must be used with the same
+// sort and filters.
+type ListOptions struct {
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"`
+	// Sort is a whitelisted column, prefixed with - for descending order
+	Sort string `form:"sort"`
+}
+
+// Page is one page of a list with the total number of matching rows
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+// SortColumn is a column lists can be ordered by
+type SortColumn struct {
+	Field string
+	// Nullable columns are ordered with NULL as the zero value of the field, or
+	// of the type it points to
+	Nullable bool
+}
+
+// trashSorts adds the deletion time to the sorts of live rows
+func trashSorts(sorts map[string]SortColumn) map[string]SortColumn {
+	trash := maps.Clone(sorts)
+	trash["deleted_at"] = SortColumn{Field: "DeletedAt"}
+	return trash
+}
+
+// rowsAffected turns writes that matched no row into gorm.ErrRecordNotFound
+func rowsAffected(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// order resolves the sort and the page size of opts. sorts maps the accepted
+// sort names to columns, defaultSort is used when opts.Sort is empty.
+func order(opts ListOptions, sorts map[string]SortColumn, defaultSort string) (column SortColumn, desc bool, limit int, err error) {
+	sortName := opts.Sort
+	if sortName == "" {
+		sortName = defaultSort
+	}
+	desc = strings.HasPrefix(sortName, "-")
+	column, ok := sorts[strings.TrimPrefix(sortName, "-")]
+	if !ok {
+		return column, desc, 0, fmt.Errorf("%w: unknown sort %q", ErrInvalidListOptions, sortName)
+	}
+
+	limit = opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	return column, desc, limit, nil
+}
+
+// paginate reads the page of query described by opts, see order
+func paginate[T any](query *gorm.DB, opts ListOptions, sorts map[string]SortColumn, defaultSort string) (*Page[T], error) {
+	column, desc, limit, err := order(opts, sorts, defaultSort)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	sortField := stmt.Schema.LookUpField(column.Field)
+	keyField := stmt.Schema.PrioritizedPrimaryField
+	if sortField == nil || keyField == nil {
+		return nil, fmt.Errorf("%w: %s can't be sorted by %s", ErrInvalidListOptions, stmt.Schema.Name, column.Field)
+	}
+
+	page := &Page[T]{Items: []T{}}
+	if err := query.Session(&gorm.Session{}).Model(new(T)).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	valueType := sortField.FieldType
+	if valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+	sortExpr := clause.Expr{SQL: "?", Vars: []any{clause.Column{Table: clause.CurrentTable, Name: sortField.DBName}}}
+	if column.Nullable {
+		sortExpr = clause.Expr{SQL: "COALESCE(?, ?)", Vars: []any{
+			clause.Column{Table: clause.CurrentTable, Name: sortField.DBName},
+			reflect.Zero(valueType).Interface(),
+		}}
+	}
+	keyColumn := clause.Column{Table: clause.CurrentTable, Name: keyField.DBName}
+
+	op := ">"
+	direction := "ASC"
+	if desc {
+		op = "<"
+		direction = "DESC"
+	}
+
+	query = query.Session(&gorm.Session{})
+	if opts.Cursor != "" {
+		value, key, err := decodeCursor(opts.Cursor, valueType, keyField.FieldType)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(
+			fmt.Sprintf("(? %[1]s ? OR (? = ? AND ? %[1]s ?))", op),
+			sortExpr, value, sortExpr, value, keyColumn, key,
+		)
+	}
+
+	err = query.
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "? " + direction + ", ? " + direction, Vars: []any{sortExpr, keyColumn}}}).
+		Limit(limit + 1).
+		Find(&page.Items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := reflect.ValueOf(&page.Items[limit-1]).Elem()
+		value, _ := sortField.ValueOf(context.Background(), last)
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer {
+			value = reflect.Zero(valueType).Interface()
+			if !v.IsNil() {
+				value = v.Elem().Interface()
+			}
+		}
+		key, _ := keyField.ValueOf(context.Background(), last)
+		page.NextCursor, err = encodeCursor(value, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// A cursor is the sort value and the primary key of the last row of a page
+func encodeCursor(value, key any) (string, error) {
+	raw, err := json.Marshal([]any{value, key})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(cursor string, valueType, keyType reflect.Type) (any, any, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, invalid
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil || len(parts) != 2 {
+		return nil, nil, invalid
+	}
+
+	value := reflect.New(valueType)
+	key := reflect.New(keyType)
+	if json.Unmarshal(parts[0], value.Interface()) != nil || json.Unmarshal(parts[1], key.Interface()) != nil {
+		return nil, nil, invalid
+	}
+	return value.Elem().Interface(), key.Elem().Interface(), nil
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Filter narrows a list, the filters of each model implement it
+type Filter interface {
+	apply(query *gorm.DB) *gorm.DB
+	// match is apply for records in memory
+	match(record any) bool
+}
+
+// NoFilter lists every row
+type NoFilter struct{}
+
+func (NoFilter) apply(query *gorm.DB) *gorm.DB {
+	return query
+}
+
+func (NoFilter) match(record any) bool {
+	return true
+}
+
+// Repository stores the records of model T, whose primary key is of type ID.
+// Records are versioned and go to the trash before they are purged: T must
+// have a Version uint64, a DeletedAt gorm.DeletedAt and a DeletedBy string field.
+type Repository[T any, ID comparable] interface {
+	Transactor
+
+	Create(ctx context.Context, record *T) error
+	GetAll(ctx context.Context) ([]T, error)
+	// List returns a page of the live records matching the filter
+	List(ctx context.Context, filter Filter, opts ListOptions) (*Page[T], error)
+	GetByID(ctx context.Context, id ID) (*T, error)
+	// GetByIDWithTrashed returns the record even when it is in the trash
+	GetByIDWithTrashed(ctx context.Context, id ID) (*T, error)
+	// Update saves the record if it is still at its version and bumps the
+	// version. It returns ErrVersionConflict when the record changed in the
+	// meantime.
+	Update(ctx context.Context, record *T) error
+	// Delete moves the record to the trash, it returns gorm.ErrRecordNotFound
+	// when there is no such live record and ErrVersionConflict when it is no
+	// longer at version. A version of 0 deletes any version.
+	Delete(ctx context.Context, id ID, version uint64, deletedBy string) error
+	// ListTrash returns a page of the trashed records, by default the last deleted first
+	ListTrash(ctx context.Context, opts ListOptions) (*Page[T], error)
+	// Restore takes the record out of the trash
+	Restore(ctx context.Context, id ID) error
+	// Purge permanently deletes a trashed record
+	Purge(ctx context.Context, id ID) error
+
+	// Key returns the primary key of the record
+	Key(record *T) ID
+	// Version returns the version of the record
+	Version(record *T) uint64
+	// Keep copies the fields only the repository changes, the primary key, the
+	// version and the trash fields, from existing to the record replacing it
+	Keep(record, existing *T)
+}
+
+// fields reads and writes the fields of records the repositories manage
+type fields[T any, ID comparable] struct {
+	// key is the Go name of the primary key
+	key string
+}
+
+func (f fields[T, ID]) Key(record *T) ID {
+	return field(record, f.key).Interface().(ID)
+}
+
+func (f fields[T, ID]) Version(record *T) uint64 {
+	return field(record, "Version").Uint()
+}
+
+func (f fields[T, ID]) Keep(record, existing *T) {
+	for _, name := range []string{f.key, "Version", "DeletedAt", "DeletedBy"} {
+		field(record, name).Set(field(existing, name))
+	}
+}
+
+// reset prepares a record to be created: live, at the first version
+func (f fields[T, ID]) reset(record *T) {
+	field(record, "DeletedAt").Set(reflect.ValueOf(gorm.DeletedAt{}))
+	field(record, "DeletedBy").SetString("")
+	field(record, "Version").SetUint(1)
+}
+
+func field[T any](record *T, name string) reflect.Value {
+	return reflect.ValueOf(record).Elem().FieldByName(name)
+}
+
+// GormRepository is the Repository of a gorm database
+type GormRepository[T any, ID comparable] struct {
+	fields[T, ID]
+	DB *gorm.DB
+
+	keyColumn   string
+	sorts       map[string]SortColumn
+	defaultSort string
+
+	// where limits the rows read and written, assign is applied to the records
+	// being created or updated, both are set by Scope
+	where  func(query *gorm.DB) *gorm.DB
+	assign func(record *T)
+}
+
+// NewRepository returns the repository of T. sorts are the columns lists can be
+// sorted by and defaultSort the one used when a list doesn't ask for any.
+func NewRepository[T any, ID comparable](db *gorm.DB, sorts map[string]SortColumn, defaultSort string) *GormRepository[T, ID] {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		// Models are known at compile time, this is a programming error
+		panic(fmt.Sprintf("repository: no primary key for %T: %v", new(T), err))
+	}
+	key := stmt.Schema.PrioritizedPrimaryField
+	return &GormRepository[T, ID]{
+		fields:      fields[T, ID]{key: key.Name},
+		DB:          db,
+		keyColumn:   key.DBName,
+		sorts:       sorts,
+		defaultSort: defaultSort,
+	}
+}
+
+// Scope returns a repository limited to the rows matching where. assign, if
+// any, is applied to every record it creates or updates so they can't be
+// written outside of the scope.
+func (r *GormRepository[T, ID]) Scope(where func(query *gorm.DB) *gorm.DB, assign func(record *T)) *GormRepository[T, ID] {
+	scoped := *r
+	scoped.where = where
+	scoped.assign = assign
+	return &scoped
+}
+
+func (r *GormRepository[T, ID]) WithinTx(ctx context.Context, fn func(tx context.Context) error) error {
+	return NewTransactor(r.DB).WithinTx(ctx, fn)
+}
+
+func (r *GormRepository[T, ID]) query(ctx context.Context) *gorm.DB {
+	if r.where == nil {
+		return conn(ctx, r.DB)
+	}
+	return r.where(conn(ctx, r.DB))
+}
+
+// byKey is the query of the record with the id, trashed or not
+func (r *GormRepository[T, ID]) byKey(ctx context.Context, id ID) *gorm.DB {
+	return r.query(ctx).Unscoped().Model(new(T)).Where(clause.Eq{Column: clause.Column{Name: r.keyColumn}, Value: id})
+}
+
+func (r *GormRepository[T, ID]) scope(record *T) {
+	if r.assign != nil {
+		r.assign(record)
+	}
+}
+
+func (r *GormRepository[T, ID]) Create(ctx context.Context, record *T) error {
+	r.scope(record)
+	r.reset(record)
+	return conn(ctx, r.DB).Create(record).Error
+}
+
+func (r *GormRepository[T, ID]) GetAll(ctx context.Context) ([]T, error) {
+	var records []T
+	err := r.query(ctx).Find(&records).Error
+	return records, err
+}
+
+func (r *GormRepository[T, ID]) List(ctx context.Context, filter Filter, opts ListOptions) (*Page[T], error) {
+	return paginate[T](filter.apply(r.query(ctx)), opts, r.sorts, r.defaultSort)
+}
+
+func (r *GormRepository[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	var record T
+	if err := r.query(ctx).Where(clause.Eq{Column: clause.Column{Name: r.keyColumn}, Value: id}).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *GormRepository[T, ID]) GetByIDWithTrashed(ctx context.Context, id ID) (*T, error) {
+	var record T
+	if err := r.byKey(ctx, id).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Update must be given a record loaded through the same repository, so it
+// can't be moved out of its scope
+func (r *GormRepository[T, ID]) Update(ctx context.Context, record *T) error {
+	r.scope(record)
+	version := r.Version(record)
+	field(record, "Version").SetUint(version + 1)
+	result := r.query(ctx).Model(record).Select("*").Omit(clause.Associations).Where("version = ?", version).Updates(record)
+	if err := versioned(result, r.byKey(ctx, r.Key(record)).Where("deleted_at IS NULL")); err != nil {
+		field(record, "Version").SetUint(version)
+		return err
+	}
+	return nil
+}
+
+func (r *GormRepository[T, ID]) Delete(ctx context.Context, id ID, version uint64, deletedBy string) error {
+	query := r.byKey(ctx, id).Where("deleted_at IS NULL")
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.UpdateColumns(map[string]any{
+		"deleted_at": time.Now().UTC(),
+		"deleted_by": deletedBy,
+		"version":    nextVersion,
+	})
+	return versioned(result, r.byKey(ctx, id).Where("deleted_at IS NULL"))
+}
+
+func (r *GormRepository[T, ID]) ListTrash(ctx context.Context, opts ListOptions) (*Page[T], error) {
+	query := r.query(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	return paginate[T](query, opts, trashSorts(r.sorts), "-deleted_at")
+}
+
+func (r *GormRepository[T, ID]) Restore(ctx context.Context, id ID) error {
+	result := r.byKey(ctx, id).Where("deleted_at IS NOT NULL").UpdateColumns(map[string]any{
+		"deleted_at": nil,
+		"deleted_by": "",
+		"version":    nextVersion,
+	})
+	return rowsAffected(result)
+}
+
+func (r *GormRepository[T, ID]) Purge(ctx context.Context, id ID) error {
+	result := r.byKey(ctx, id).Where("deleted_at IS NOT NULL").Delete(new(T))
+	return rowsAffected(result)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"gotempl/model"
+	"slices"
+	"strings"
+)
+
+// likeCandidates caps the rows ranked by LikeSearchRepository
+const likeCandidates = 1000
+
+// LikeSearchRepository searches the events table with LIKE and ranks the
+// matches in Go. It needs no index and works on every database, but reads every
+// row; it is used when the database has no full-text index.
+type LikeSearchRepository struct {
+	searchScope
+}
+
+func (r *LikeSearchRepository) ForOrg(orgID string) SearchRepository {
+	return &LikeSearchRepository{searchScope: r.forOrg(orgID)}
+}
+
+// Index does nothing, the events table is searched directly
+func (r *LikeSearchRepository) Index(ctx context.Context, event *model.Event) error {
+	return nil
+}
+
+// Remove does nothing, the events table is searched directly
+func (r *LikeSearchRepository) Remove(ctx context.Context, id uint64) error {
+	return nil
+}
+
+// Field weights, matches in the title count the most
+var likeWeights = []float64{5, 1, 2, 3, 1}
+
+func (r *LikeSearchRepository) Search(ctx context.Context, query string, limit int) (*Page[SearchResult], error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	q := r.where(conn(ctx, r.DB), "org_id")
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		q = q.Where("(LOWER(title) LIKE ? OR LOWER(description) LIKE ? OR LOWER(location) LIKE ? OR LOWER(tags) LIKE ? OR LOWER(organizer_contact_info) LIKE ?)",
+			pattern, pattern, pattern, pattern, pattern)
+	}
+
+	var events []model.Event
+	if err := q.Order("id DESC").Limit(likeCandidates).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(events))
+	for _, event := range events {
+		fields := searchText(&event)
+		rank := 0.0
+		for i, field := range fields {
+			field = strings.ToLower(field)
+			for _, term := range terms {
+				rank += likeWeights[i] * float64(strings.Count(field, term))
+			}
+		}
+		results = append(results, SearchResult{Event: event, Rank: rank, Snippet: snippet(fields, terms)})
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+
+	page := &Page[SearchResult]{Items: results, Total: int64(len(results))}
+	if len(page.Items) > searchLimit(limit) {
+		page.Items = page.Items[:searchLimit(limit)]
+	}
+	return page, nil
+}
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(limit, MaxPageSize)
+}
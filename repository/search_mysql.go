@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"gotempl/model"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// searchDocument is a row of the MySQL event_search table, which carries the
+// FULLTEXT index (JSON columns like tags can't be indexed in place)
+type searchDocument struct {
+	EventID              uint64 `gorm:"primaryKey;autoIncrement:false"`
+	OrgID                string
+	Title                string
+	Description          string
+	Location             string
+	Tags                 string
+	OrganizerContactInfo string
+}
+
+func (searchDocument) TableName() string {
+	return SearchTable
+}
+
+const mysqlMatch = "MATCH(title, description, location, tags, organizer_contact_info) AGAINST (? IN BOOLEAN MODE)"
+
+// MySQLSearchRepository searches the FULLTEXT index of event_search
+type MySQLSearchRepository struct {
+	searchScope
+}
+
+func (r *MySQLSearchRepository) ForOrg(orgID string) SearchRepository {
+	return &MySQLSearchRepository{searchScope: r.forOrg(orgID)}
+}
+
+func (r *MySQLSearchRepository) Index(ctx context.Context, event *model.Event) error {
+	return conn(ctx, r.DB).Clauses(clause.OnConflict{UpdateAll: true}).Create(&searchDocument{
+		EventID:              event.ID,
+		OrgID:                event.OrgID,
+		Title:                event.Title,
+		Description:          event.Description,
+		Location:             event.Location,
+		Tags:                 strings.Join(event.Tags, " "),
+		OrganizerContactInfo: event.OrganizerContactInfo,
+	}).Error
+}
+
+func (r *MySQLSearchRepository) Remove(ctx context.Context, id uint64) error {
+	return r.where(conn(ctx, r.DB), "org_id").Delete(&searchDocument{}, "event_id = ?", id).Error
+}
+
+func (r *MySQLSearchRepository) Search(ctx context.Context, query string, limit int) (*Page[SearchResult], error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	// Every term must match, as a prefix
+	required := make([]string, len(terms))
+	for i, term := range terms {
+		required[i] = "+" + term + "*"
+	}
+	match := strings.Join(required, " ")
+
+	page := &Page[SearchResult]{Items: []SearchResult{}}
+	count := r.where(conn(ctx, r.DB).Model(&searchDocument{}), "org_id").Where(mysqlMatch, match)
+	if err := count.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var hits []struct {
+		EventID uint64
+		Rank    float64
+	}
+	err := r.where(conn(ctx, r.DB).Model(&searchDocument{}), "org_id").
+		Select("event_id, "+mysqlMatch+" AS `rank`", match).
+		Where(mysqlMatch, match).
+		Order("`rank` DESC, event_id").
+		Limit(searchLimit(limit)).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.EventID
+	}
+	events, err := loadEvents(r.where(conn(ctx, r.DB), "org_id"), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range hits {
+		event, ok := events[hit.EventID]
+		if !ok {
+			continue
+		}
+		page.Items = append(page.Items, SearchResult{Event: event, Rank: hit.Rank, Snippet: snippet(searchText(&event), terms)})
+	}
+	return page, nil
+}
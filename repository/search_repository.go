@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gotempl/model"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// SearchTable is the full-text index of events, created by the migrations
+// when the database supports it
+const SearchTable = "event_search"
+
+// ErrEmptySearch is returned for queries without any searchable word
+var ErrEmptySearch = errors.New("search query is empty")
+
+// SearchResult is an event matching a search, best matches have the highest rank
+type SearchResult struct {
+	Event model.Event `json:"event"`
+	Rank  float64     `json:"rank"`
+	// Snippet is HTML: an escaped excerpt of the event with the matches in <mark>
+	Snippet string `json:"snippet"`
+}
+
+// SearchRepository searches the text fields of events: title, description,
+// location, tags and organizer contact info
+type SearchRepository interface {
+	// ForOrg returns a repository limited to the organization's events, like
+	// EventRepository.ForOrg
+	ForOrg(orgID string) SearchRepository
+	// Index adds the event to the index or refreshes it
+	Index(ctx context.Context, event *model.Event) error
+	// Remove drops the event from the index
+	Remove(ctx context.Context, id uint64) error
+	// Search returns the best matches of all the words of query
+	Search(ctx context.Context, query string, limit int) (*Page[SearchResult], error)
+}
+
+// NewSearchRepository returns the search of the database's native full-text
+// facility when its index exists, or a slower LIKE based search otherwise
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	if db.Migrator().HasTable(SearchTable) {
+		switch db.Dialector.Name() {
+		case "mysql":
+			return &MySQLSearchRepository{searchScope: searchScope{DB: db}}
+		case "sqlite":
+			return &SQLiteSearchRepository{searchScope: searchScope{DB: db}}
+		}
+	}
+	return &LikeSearchRepository{searchScope: searchScope{DB: db}}
+}
+
+type searchScope struct {
+	DB *gorm.DB
+
+	orgID  string
+	scoped bool
+}
+
+func (s searchScope) forOrg(orgID string) searchScope {
+	return searchScope{DB: s.DB, orgID: orgID, scoped: true}
+}
+
+// where limits query to the organization, column is the org_id column of query
+func (s searchScope) where(query *gorm.DB, column string) *gorm.DB {
+	if !s.scoped {
+		return query
+	}
+	return query.Where(column+" = ?", s.orgID)
+}
+
+// searchTerms splits a query into lower case words, dropping the punctuation
+// that full-text engines treat as operators
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return slices.Compact(terms)
+}
+
+func searchText(event *model.Event) []string {
+	return []string{event.Title, event.Description, event.Location, strings.Join(event.Tags, " "), event.OrganizerContactInfo}
+}
+
+// loadEvents returns the events with the given IDs, keyed by ID
+func loadEvents(db *gorm.DB, ids []uint64) (map[uint64]model.Event, error) {
+	var events []model.Event
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Find(&events).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byID := make(map[uint64]model.Event, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+	return byID, nil
+}
+
+const snippetWords = 16
+
+// snippet returns an excerpt of the first field matching one of the terms,
+// escaped for HTML with the matching words in <mark>
+func snippet(fields []string, terms []string) string {
+	var words []string
+	start := -1
+	for _, field := range fields {
+		words = strings.Fields(field)
+		start = slices.IndexFunc(words, func(word string) bool {
+			return matchesTerm(word, terms)
+		})
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		words = strings.Fields(fields[0])
+		start = 0
+	}
+
+	// Keep a few words of context before the first match
+	from := max(0, start-snippetWords/4)
+	to := min(len(words), from+snippetWords)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	for i, word := range words[from:to] {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		if matchesTerm(word, terms) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+	}
+	if to < len(words) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// matchesTerm tells whether a word starts with one of the terms
+func matchesTerm(word string, terms []string) bool {
+	for _, term := range searchTerms(word) {
+		for _, t := range terms {
+			if strings.HasPrefix(term, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
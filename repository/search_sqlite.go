@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"gotempl/model"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SQLiteSearchRepository searches the FTS5 table event_search, whose rowid is
+// the event ID. FTS5 is only compiled in with the sqlite_fts5 build tag.
+type SQLiteSearchRepository struct {
+	searchScope
+}
+
+func (r *SQLiteSearchRepository) ForOrg(orgID string) SearchRepository {
+	return &SQLiteSearchRepository{searchScope: r.forOrg(orgID)}
+}
+
+func (r *SQLiteSearchRepository) Index(ctx context.Context, event *model.Event) error {
+	return conn(ctx, r.DB).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM event_search WHERE rowid = ?", event.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO event_search (rowid, title, description, location, tags, organizer_contact_info, org_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			event.ID, event.Title, event.Description, event.Location, strings.Join(event.Tags, " "), event.OrganizerContactInfo, event.OrgID).Error
+	})
+}
+
+func (r *SQLiteSearchRepository) Remove(ctx context.Context, id uint64) error {
+	if r.scoped {
+		return conn(ctx, r.DB).Exec("DELETE FROM event_search WHERE rowid = ? AND org_id = ?", id, r.orgID).Error
+	}
+	return conn(ctx, r.DB).Exec("DELETE FROM event_search WHERE rowid = ?", id).Error
+}
+
+// snippet() marks the matches with these, they are replaced once the text is escaped
+const (
+	ftsMarkStart = "\x01"
+	ftsMarkEnd   = "\x02"
+)
+
+func (r *SQLiteSearchRepository) Search(ctx context.Context, query string, limit int) (*Page[SearchResult], error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	// Every term must match, as a prefix
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"*`
+	}
+	match := strings.Join(quoted, " ")
+
+	page := &Page[SearchResult]{Items: []SearchResult{}}
+	count := r.where(conn(ctx, r.DB).Table(SearchTable), "org_id").Where("event_search MATCH ?", match)
+	if err := count.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var hits []struct {
+		ID      uint64
+		Score   float64
+		Snippet string
+	}
+	err := r.where(conn(ctx, r.DB).Table(SearchTable), "org_id").
+		Select("rowid AS id, -bm25(event_search, 5.0, 1.0, 2.0, 3.0, 1.0) AS score, snippet(event_search, -1, ?, ?, '…', 16) AS snippet", ftsMarkStart, ftsMarkEnd).
+		Where("event_search MATCH ?", match).
+		Order("score DESC").
+		Limit(searchLimit(limit)).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	events, err := loadEvents(r.where(conn(ctx, r.DB), "org_id"), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range hits {
+		event, ok := events[hit.ID]
+		if !ok {
+			continue
+		}
+		text := html.EscapeString(hit.Snippet)
+		text = strings.ReplaceAll(text, ftsMarkStart, "<mark>")
+		text = strings.ReplaceAll(text, ftsMarkEnd, "</mark>")
+		page.Items = append(page.Items, SearchResult{Event: event, Rank: hit.Score, Snippet: text})
+	}
+	return page, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transactor runs functions in a database transaction
+type Transactor interface {
+	// WithinTx calls fn with a context carrying a transaction, used by every
+	// repository of the same database it is passed to. The transaction is
+	// committed when fn returns nil and rolled back otherwise; nested calls
+	// use a savepoint.
+	WithinTx(ctx context.Context, fn func(tx context.Context) error) error
+}
+
+type txKey struct{}
+
+// GormTransactor runs transactions on a gorm database
+type GormTransactor struct {
+	DB *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{DB: db}
+}
+
+func (t *GormTransactor) WithinTx(ctx context.Context, fn func(tx context.Context) error) error {
+	return conn(ctx, t.DB).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction of ctx, or db, bound to ctx so queries stop
+// when it is cancelled
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// translate maps the constraint errors of the driver to gorm's, such as
+// gorm.ErrDuplicatedKey
+func translate(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		return translator.Translate(err)
+	}
+	return err
+}
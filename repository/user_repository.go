@@ -1,45 +1,105 @@
 package repository
 
 import (
+	"context"
 	"gotempl/model"
 
 	"gorm.io/gorm"
 )
 
-type UserRepository struct {
-	DB *gorm.DB
+// UserSorts are the columns users can be sorted by
+var UserSorts = map[string]SortColumn{
+	"uid":      {Field: "Uid"},
+	"username": {Field: "Username"},
+	"role":     {Field: "Role"},
 }
 
-func NewUserRepository(db *gorm.DB) *UserRepository {
-	return &UserRepository{DB: db}
+// UserFilter narrows user lists, empty fields don't filter
+type UserFilter struct {
+	Role string `form:"role"`
 }
 
-func (r *UserRepository) Create(user *model.User) error {
-	return r.DB.Create(user).Error
+func (f UserFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Role != "" {
+		query = query.Where("role = ?", f.Role)
+	}
+	return query
 }
 
-func (r *UserRepository) GetAll() ([]model.User, error) {
-	var users []model.User
-	err := r.DB.Find(&users).Error
-	return users, err
+func (f UserFilter) match(record any) bool {
+	return f.Role == "" || record.(*model.User).Role == f.Role
 }
 
-func (r *UserRepository) GetByID(id string) (*model.User, error) {
-	var user model.User
-	err := r.DB.First(&user, "uid = ?", id).Error
-	return &user, err
+// UserRepository stores every user, or only the members of one organization
+// when it was obtained through ForOrg
+type UserRepository interface {
+	Repository[model.User, string]
+	// ForOrg returns a repository limited to the organization's members, the
+	// users it creates join the organization. An empty orgID limits it to the
+	// users that don't belong to any organization.
+	ForOrg(orgID string) UserRepository
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	// GetByUsernameWithTrashed returns the user even when it is in the trash
+	GetByUsernameWithTrashed(ctx context.Context, username string) (*model.User, error)
+	// OrgIDs returns the organizations the user is a member of, in order
+	OrgIDs(ctx context.Context, uid string) ([]string, error)
+}
+
+// GormUserRepository is the UserRepository of a gorm database
+type GormUserRepository struct {
+	*GormRepository[model.User, string]
+
+	orgID  string
+	scoped bool
+}
+
+func NewUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{GormRepository: NewRepository[model.User, string](db, UserSorts, "username")}
 }
 
-func (r *UserRepository) Update(user *model.User) error {
-	return r.DB.Save(user).Error
+func (r *GormUserRepository) ForOrg(orgID string) UserRepository {
+	where := func(query *gorm.DB) *gorm.DB {
+		return query.Where("uid NOT IN (SELECT user_uid FROM memberships)")
+	}
+	if orgID != "" {
+		where = func(query *gorm.DB) *gorm.DB {
+			return query.Where("uid IN (SELECT user_uid FROM memberships WHERE org_id = ?)", orgID)
+		}
+	}
+	return &GormUserRepository{GormRepository: r.Scope(where, nil), orgID: orgID, scoped: true}
+}
+
+// Create adds the user to the organization of the repository in the same transaction
+func (r *GormUserRepository) Create(ctx context.Context, user *model.User) error {
+	if !r.scoped || r.orgID == "" {
+		return r.GormRepository.Create(ctx, user)
+	}
+	return r.WithinTx(ctx, func(ctx context.Context) error {
+		if err := r.GormRepository.Create(ctx, user); err != nil {
+			return err
+		}
+		return conn(ctx, r.DB).Create(&model.Membership{UserUid: user.Uid, OrgID: r.orgID}).Error
+	})
+}
+
+func (r *GormUserRepository) GetByUsernameWithTrashed(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	if err := r.query(ctx).Unscoped().Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-func (r *UserRepository) Delete(id string) error {
-	return r.DB.Delete(&model.User{}, "uid = ?", id).Error
+func (r *GormUserRepository) OrgIDs(ctx context.Context, uid string) ([]string, error) {
+	orgIDs := []string{}
+	err := conn(ctx, r.DB).Model(&model.Membership{}).Where("user_uid = ?", uid).Order("org_id").Pluck("org_id", &orgIDs).Error
+	return orgIDs, err
 }
 
-func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
+func (r *GormUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := r.DB.Where("username = ?", username).First(&user).Error
-	return &user, err
+	if err := r.query(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned when a record changed since the version being
+// written was read
+var ErrVersionConflict = errors.New("the record was changed by someone else")
+
+// nextVersion is the SQL bumping the version of the rows being updated
+var nextVersion = gorm.Expr("version + 1")
+
+// versioned turns writes of a version that matched no row into
+// ErrVersionConflict when the record still exists (exists counts it), and into
+// gorm.ErrRecordNotFound otherwise
+func versioned(result *gorm.DB, exists *gorm.DB) error {
+	err := rowsAffected(result)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var count int64
+	if err := exists.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVersionConflict
+	}
+	return gorm.ErrRecordNotFound
+}
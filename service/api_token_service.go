@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gotempl/model"
+	"gotempl/repository"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Only record usage once per interval to avoid a write on every request
+const lastUsedResolution = time.Minute
+
+var ErrInvalidAPIToken = errors.New("invalid API token")
+
+type APITokenService struct {
+	repo     repository.APITokenRepository
+	users    repository.UserRepository
+	validate *validator.Validate
+}
+
+func NewAPITokenService(repo repository.APITokenRepository, users repository.UserRepository) *APITokenService {
+	return &APITokenService{
+		repo:     repo,
+		users:    users,
+		validate: validator.New(),
+	}
+}
+
+// ForOrg returns a service limited to the organization's tokens, see APITokenRepository.ForOrg
+func (s *APITokenService) ForOrg(orgID string) *APITokenService {
+	scoped := *s
+	scoped.repo = s.repo.ForOrg(orgID)
+	return &scoped
+}
+
+// CreateToken mints a new token and returns its secret value. Only a hash is
+// stored, so the secret cannot be shown again.
+func (s *APITokenService) CreateToken(ctx context.Context, token *model.APIToken, scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return "", errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(model.Scopes, scope) {
+			return "", fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	token.Scopes = strings.Join(scopes, " ")
+
+	if token.ExpiresAt != nil {
+		if !token.ExpiresAt.After(time.Now()) {
+			return "", errors.New("expiry must be in the future")
+		}
+		expiresAt := token.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	if _, err := s.users.GetByID(ctx, token.OwnerUid); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("owner %q does not exist", token.OwnerUid)
+		}
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	secret := model.APITokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	token.TokenHash = hashAPIToken(secret)
+	token.Prefix = secret[:len(model.APITokenPrefix)+6]
+
+	if err := s.validate.Struct(token); err != nil {
+		return "", err
+	}
+
+	if err := s.repo.Create(ctx, token); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// VerifyToken returns the active token matching the secret and records its use
+func (s *APITokenService) VerifyToken(ctx context.Context, secret string) (*model.APIToken, error) {
+	token, err := s.repo.GetByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !token.Active(now) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		if err := s.repo.TouchLastUsed(ctx, token.ID, now); err != nil {
+			log.Error("Error:", err)
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+func (s *APITokenService) GetAllTokens(ctx context.Context) ([]model.APIToken, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *APITokenService) RevokeToken(ctx context.Context, id uint64) error {
+	return s.repo.Revoke(ctx, id, time.Now().UTC())
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
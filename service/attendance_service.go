@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gotempl/model"
+	"gotempl/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyRegistered is returned when a user registers twice to an event
+var ErrAlreadyRegistered = errors.New("already registered to the event")
+
+// ErrRegistrationClosed is returned when registering to an event that isn't
+// published: not announced yet, started, over or cancelled
+var ErrRegistrationClosed = errors.New("registrations to the event are closed")
+
+// AttendanceService registers users to events. The attendees count of an event
+// is only changed here, in the transaction registering or cancelling.
+type AttendanceService struct {
+	events repository.EventRepository
+	repo   repository.AttendanceRepository
+}
+
+func NewAttendanceService(events repository.EventRepository, repo repository.AttendanceRepository) *AttendanceService {
+	return &AttendanceService{events: events, repo: repo}
+}
+
+// ForOrg returns a service limited to the organization's events
+func (s *AttendanceService) ForOrg(orgID string) *AttendanceService {
+	return &AttendanceService{events: s.events.ForOrg(orgID), repo: s.repo}
+}
+
+// Register gives the user a seat of the published event, or puts them on its
+// waitlist when it is full. Events take no registrations in any other status.
+func (s *AttendanceService) Register(ctx context.Context, eventID uint64, userUid string) (*model.Attendance, error) {
+	attendance := &model.Attendance{EventID: eventID, UserUid: userUid, Status: model.AttendanceWaitlisted}
+	err := s.repo.WithinTx(ctx, func(ctx context.Context) error {
+		event, err := s.events.GetByID(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if event.Status != model.EventPublished {
+			return ErrRegistrationClosed
+		}
+		if _, err := s.repo.Get(ctx, eventID, userUid); err == nil {
+			return ErrAlreadyRegistered
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		seated, err := s.events.AddAttendee(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if seated {
+			attendance.Status = model.AttendanceGoing
+		}
+		// A concurrent registration of the same user may have won the race
+		err = s.repo.Create(ctx, attendance)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrAlreadyRegistered
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attendance, nil
+}
+
+// Cancel removes the user's registration to the event, unless it is trashed. The
+// seat it frees goes to the first user of the waitlist, who is returned, if any.
+func (s *AttendanceService) Cancel(ctx context.Context, eventID uint64, userUid string) (*model.Attendance, error) {
+	var promoted *model.Attendance
+	err := s.repo.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.events.GetByID(ctx, eventID); err != nil {
+			return err
+		}
+		attendance, err := s.repo.Get(ctx, eventID, userUid)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.Delete(ctx, attendance.ID); err != nil {
+			return err
+		}
+		if attendance.Status != model.AttendanceGoing {
+			return nil
+		}
+
+		if err := s.events.RemoveAttendee(ctx, eventID); err != nil {
+			return err
+		}
+		promoted, err = s.promote(ctx, eventID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// promote gives a free seat of the event to the first user of the waitlist
+func (s *AttendanceService) promote(ctx context.Context, eventID uint64) (*model.Attendance, error) {
+	next, err := s.repo.NextWaitlisted(ctx, eventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The capacity may have been lowered below the attendees count
+	seated, err := s.events.AddAttendee(ctx, eventID)
+	if err != nil || !seated {
+		return nil, err
+	}
+	if err := s.repo.SetStatus(ctx, next.ID, model.AttendanceGoing); err != nil {
+		return nil, err
+	}
+	next.Status = model.AttendanceGoing
+	return next, nil
+}
+
+// PromoteWaitlist gives the free seats of the event, after its capacity was
+// raised, to the waitlist in order. It returns the users promoted. The
+// waitlist of cancelled and completed events stays as it is.
+func (s *AttendanceService) PromoteWaitlist(ctx context.Context, eventID uint64) ([]model.Attendance, error) {
+	var promoted []model.Attendance
+	err := s.repo.WithinTx(ctx, func(ctx context.Context) error {
+		event, err := s.events.GetByID(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if event.Status == model.EventCancelled || event.Status == model.EventCompleted {
+			return nil
+		}
+		for {
+			next, err := s.promote(ctx, eventID)
+			if err != nil || next == nil {
+				return err
+			}
+			promoted = append(promoted, *next)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// ListAttendees returns the registrations to the live event, those with a seat
+// first, then the waitlist in order
+func (s *AttendanceService) ListAttendees(ctx context.Context, eventID uint64) ([]model.Attendance, error) {
+	if _, err := s.events.GetByID(ctx, eventID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, eventID)
+}
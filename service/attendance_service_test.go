@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gotempl/model"
+	"gotempl/repository"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAttendanceWaitlist(t *testing.T) {
+	ctx := context.Background()
+	events := repository.NewMemoryEventRepository()
+	attendance := NewAttendanceService(events, repository.NewMemoryAttendanceRepository())
+	acme := events.ForOrg("org_acme")
+	assert.NoError(t, acme.Create(ctx, &model.Event{Title: "Talk", MaxAttendees: 1, Status: model.EventPublished}))
+	assert.NoError(t, acme.Create(ctx, &model.Event{Title: "Draft"}))
+
+	// Only published events take registrations
+	_, err := attendance.Register(ctx, 2, "alice")
+	assert.ErrorIs(t, err, ErrRegistrationClosed)
+
+	_, err = attendance.ForOrg("org_other").Register(ctx, 1, "alice")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	alice, err := attendance.Register(ctx, 1, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, model.AttendanceGoing, alice.Status)
+	bob, err := attendance.Register(ctx, 1, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, model.AttendanceWaitlisted, bob.Status)
+	_, err = attendance.Register(ctx, 1, "bob")
+	assert.ErrorIs(t, err, ErrAlreadyRegistered)
+
+	// Raising the capacity promotes the waitlist, lowering it keeps the seats taken
+	eventService := NewEventService(events, repository.NewMemorySearchRepository())
+	eventService.Attendance = attendance
+	event, _ := acme.GetByID(ctx, 1)
+	event.MaxAttendees = 2
+	assert.NoError(t, eventService.Update(ctx, event))
+	assert.Equal(t, uint(2), event.AttendeesCount)
+	carol, err := attendance.Register(ctx, 1, "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, model.AttendanceWaitlisted, carol.Status)
+	event.MaxAttendees = 1
+	assert.NoError(t, eventService.Update(ctx, event))
+	assert.Equal(t, uint(2), event.AttendeesCount)
+
+	promoted, err := attendance.Cancel(ctx, 1, "alice")
+	assert.NoError(t, err)
+	assert.Nil(t, promoted)
+	promoted, err = attendance.Cancel(ctx, 1, "bob")
+	assert.NoError(t, err)
+	if assert.NotNil(t, promoted) {
+		assert.Equal(t, "carol", promoted.UserUid)
+	}
+
+	event, _ = acme.GetByID(ctx, 1)
+	assert.Equal(t, uint(1), event.AttendeesCount)
+	list, err := attendance.ListAttendees(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	// Registrations to trashed events can't be cancelled
+	assert.NoError(t, acme.Delete(ctx, 1, 0, "author-uid"))
+	_, err = attendance.Cancel(ctx, 1, "carol")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+// racingAttendance doesn't see the registrations, like concurrent requests
+// that all check before any of them is saved
+type racingAttendance struct {
+	repository.AttendanceRepository
+}
+
+func (racingAttendance) Get(ctx context.Context, eventID uint64, userUid string) (*model.Attendance, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestAttendanceDuplicateRegistration(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Event{}, &model.Attendance{}))
+	assert.NoError(t, db.Create(&model.Event{Title: "Concert", CreatedBy: "author-uid", Status: model.EventPublished}).Error)
+
+	attendance := NewAttendanceService(repository.NewEventRepository(db), racingAttendance{repository.NewAttendanceRepository(db)})
+	_, err = attendance.Register(context.Background(), 1, "alice")
+	assert.NoError(t, err)
+	_, err = attendance.Register(context.Background(), 1, "alice")
+	assert.ErrorIs(t, err, ErrAlreadyRegistered)
+
+	var count int64
+	assert.NoError(t, db.Model(&model.Attendance{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	var event model.Event
+	assert.NoError(t, db.First(&event, 1).Error)
+	assert.Equal(t, uint(1), event.AttendeesCount)
+}
+
+func TestAttendanceCapacityUnderConcurrency(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	// One connection shares the in-memory database between the goroutines
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Event{}, &model.Attendance{}))
+	assert.NoError(t, db.Create(&model.Event{Title: "Concert", CreatedBy: "author-uid", MaxAttendees: 5, Status: model.EventPublished}).Error)
+
+	attendance := NewAttendanceService(repository.NewEventRepository(db), repository.NewAttendanceRepository(db))
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := attendance.Register(context.Background(), 1, fmt.Sprint("user-", i))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	var event model.Event
+	assert.NoError(t, db.First(&event, 1).Error)
+	assert.Equal(t, uint(5), event.AttendeesCount)
+	var going int64
+	assert.NoError(t, db.Model(&model.Attendance{}).Where("status = ?", model.AttendanceGoing).Count(&going).Error)
+	assert.Equal(t, int64(5), going)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"gotempl/model"
+	"gotempl/repository"
+	"reflect"
+)
+
+// Actor is who makes a change, as recorded in the audit log
+type Actor struct {
+	// Subject is the user ID of the caller
+	Subject   string
+	RequestID string
+	IP        string
+}
+
+// auditIgnored are fields whose changes are not worth an audit entry
+var auditIgnored = []string{"updated_at", "version"}
+
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// ForOrg returns a service that only lists the entries of the organization's records
+func (s *AuditService) ForOrg(orgID string) *AuditService {
+	return &AuditService{repo: s.repo.ForOrg(orgID)}
+}
+
+func (s *AuditService) ListEntries(ctx context.Context, filter repository.AuditFilter, opts repository.ListOptions) (*repository.Page[model.AuditEntry], error) {
+	return s.repo.List(ctx, filter, opts)
+}
+
+// Record appends a change of the record to the log. before is nil for
+// creations and after is nil for purges; updates that change nothing are not
+// recorded. A nil service records nothing. Services record in the transaction
+// of the change, so a change whose entry can't be written is rolled back.
+func (s *AuditService) Record(ctx context.Context, actor Actor, entity, entityID, orgID, action string, before, after any) error {
+	if s == nil {
+		return nil
+	}
+
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 && action == model.AuditUpdate {
+		return nil
+	}
+
+	return s.repo.Create(ctx, &model.AuditEntry{
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Actor:     actor.Subject,
+		OrgID:     orgID,
+		RequestID: actor.RequestID,
+		IP:        actor.IP,
+		Changes:   changes,
+	})
+}
+
+// diff compares the JSON fields of two versions of a record
+func diff(before, after any) (model.AuditChanges, error) {
+	old, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := model.AuditChanges{}
+	for name, value := range updated {
+		if previous, ok := old[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[name] = model.AuditChange{Before: old[name], After: value}
+		}
+	}
+	for name, value := range old {
+		if _, ok := updated[name]; !ok {
+			changes[name] = model.AuditChange{Before: value}
+		}
+	}
+	for _, name := range auditIgnored {
+		delete(changes, name)
+	}
+	return changes, nil
+}
+
+func jsonFields(value any) (map[string]any, error) {
+	fields := map[string]any{}
+	if v := reflect.ValueOf(value); !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return fields, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(raw, &fields)
+}
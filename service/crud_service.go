@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotempl/model"
+	"gotempl/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalid is wrapped by the errors of records that fail validation
+var ErrInvalid = errors.New("invalid record")
+
+// ErrForbidden is wrapped by the errors of the changes a Guard hook refuses
+var ErrForbidden = errors.New("access denied")
+
+// Hooks customize a CRUDService, each of them is optional
+type Hooks[T any, ID comparable] struct {
+	// Validate checks a record, after its validate tags, before it is created or updated
+	Validate func(record *T) error
+	// Change checks what a creation (existing is nil) or an update changes,
+	// after Validate. It may fill the fields the record leaves empty.
+	Change func(record, existing *T) error
+	// Saved is called after a record is created, updated or restored
+	Saved func(ctx context.Context, record *T) error
+	// Guard checks that the stored record may be changed, before it is
+	// updated, deleted, restored or purged
+	Guard func(ctx context.Context, record *T) error
+	// Removed is called after a record is moved to the trash
+	Removed func(ctx context.Context, id ID) error
+	// OrgsOf are the organizations the changes of a record are recorded under in
+	// the audit log, one entry each. Without any the entry has no organization.
+	OrgsOf func(ctx context.Context, record *T) ([]string, error)
+}
+
+// CRUDService validates and audits the changes to the records of a Repository.
+// Each change, its audit entry and its hooks are written in one transaction.
+type CRUDService[T any, ID comparable] struct {
+	Hooks Hooks[T, ID]
+	// Audit records the changes made through the service, optional
+	Audit *AuditService
+
+	repo     repository.Repository[T, ID]
+	entity   string
+	validate *validator.Validate
+	actor    Actor
+}
+
+// NewCRUDService returns the service of the records of repo, entity is their
+// name in the audit log
+func NewCRUDService[T any, ID comparable](repo repository.Repository[T, ID], entity string) *CRUDService[T, ID] {
+	return &CRUDService[T, ID]{
+		repo:     repo,
+		entity:   entity,
+		validate: validator.New(),
+	}
+}
+
+// WithRepo returns a service working on repo, usually a scoped copy of its repository
+func (s *CRUDService[T, ID]) WithRepo(repo repository.Repository[T, ID]) *CRUDService[T, ID] {
+	scoped := *s
+	scoped.repo = repo
+	return &scoped
+}
+
+// As returns a service whose changes are recorded in the audit log as made by actor
+func (s *CRUDService[T, ID]) As(actor Actor) *CRUDService[T, ID] {
+	acting := *s
+	acting.actor = actor
+	return &acting
+}
+
+// Repo returns the repository of the service
+func (s *CRUDService[T, ID]) Repo() repository.Repository[T, ID] {
+	return s.repo
+}
+
+// WithinTx calls fn in a transaction of the repository of the service, see
+// repository.Transactor
+func (s *CRUDService[T, ID]) WithinTx(ctx context.Context, fn func(tx context.Context) error) error {
+	return s.repo.WithinTx(ctx, fn)
+}
+
+func (s *CRUDService[T, ID]) check(record, existing *T) error {
+	if err := s.validate.Struct(record); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if s.Hooks.Validate != nil {
+		if err := s.Hooks.Validate(record); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+	if s.Hooks.Change != nil {
+		if err := s.Hooks.Change(record, existing); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+	return nil
+}
+
+func (s *CRUDService[T, ID]) audit(ctx context.Context, id ID, action string, before, after *T) error {
+	if s.Audit == nil {
+		return nil
+	}
+
+	var orgIDs []string
+	if record := after; s.Hooks.OrgsOf != nil {
+		if record == nil {
+			record = before
+		}
+		var err error
+		if orgIDs, err = s.Hooks.OrgsOf(ctx, record); err != nil {
+			return err
+		}
+	}
+	if len(orgIDs) == 0 {
+		orgIDs = []string{""}
+	}
+
+	for _, orgID := range orgIDs {
+		if err := s.Audit.Record(ctx, s.actor, s.entity, fmt.Sprint(id), orgID, action, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CRUDService[T, ID]) guard(ctx context.Context, record *T) error {
+	if s.Hooks.Guard == nil {
+		return nil
+	}
+	return s.Hooks.Guard(ctx, record)
+}
+
+func (s *CRUDService[T, ID]) saved(ctx context.Context, record *T) error {
+	if s.Hooks.Saved == nil {
+		return nil
+	}
+	return s.Hooks.Saved(ctx, record)
+}
+
+func (s *CRUDService[T, ID]) Create(ctx context.Context, record *T) error {
+	if err := s.check(record, nil); err != nil {
+		return err
+	}
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, record); err != nil {
+			return err
+		}
+		if err := s.audit(ctx, s.repo.Key(record), model.AuditCreate, nil, record); err != nil {
+			return err
+		}
+		return s.saved(ctx, record)
+	})
+}
+
+func (s *CRUDService[T, ID]) Get(ctx context.Context, id ID) (*T, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *CRUDService[T, ID]) GetAll(ctx context.Context) ([]T, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *CRUDService[T, ID]) List(ctx context.Context, filter repository.Filter, opts repository.ListOptions) (*repository.Page[T], error) {
+	return s.repo.List(ctx, filter, opts)
+}
+
+// Update saves the record, see Repository.Update
+func (s *CRUDService[T, ID]) Update(ctx context.Context, record *T) error {
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetByID(ctx, s.repo.Key(record))
+		if err != nil {
+			return err
+		}
+		if err := s.guard(ctx, before); err != nil {
+			return err
+		}
+		if err := s.check(record, before); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, record); err != nil {
+			return err
+		}
+		if err := s.audit(ctx, s.repo.Key(record), model.AuditUpdate, before, record); err != nil {
+			return err
+		}
+		return s.saved(ctx, record)
+	})
+}
+
+// Delete moves the record to the trash. A version of 0 deletes any version,
+// see Repository.Delete.
+func (s *CRUDService[T, ID]) Delete(ctx context.Context, id ID, version uint64, deletedBy string) error {
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.guard(ctx, before); err != nil {
+			return err
+		}
+		if err := s.repo.Delete(ctx, id, version, deletedBy); err != nil {
+			return err
+		}
+		after, err := s.repo.GetByIDWithTrashed(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.audit(ctx, id, model.AuditDelete, before, after); err != nil {
+			return err
+		}
+		if s.Hooks.Removed == nil {
+			return nil
+		}
+		return s.Hooks.Removed(ctx, id)
+	})
+}
+
+func (s *CRUDService[T, ID]) ListTrash(ctx context.Context, opts repository.ListOptions) (*repository.Page[T], error) {
+	return s.repo.ListTrash(ctx, opts)
+}
+
+// Restore takes the record out of the trash
+func (s *CRUDService[T, ID]) Restore(ctx context.Context, id ID) (*T, error) {
+	var record *T
+	err := s.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetByIDWithTrashed(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.guard(ctx, before); err != nil {
+			return err
+		}
+		if err := s.repo.Restore(ctx, id); err != nil {
+			return err
+		}
+		if record, err = s.repo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if err := s.audit(ctx, id, model.AuditRestore, before, record); err != nil {
+			return err
+		}
+		return s.saved(ctx, record)
+	})
+	return record, err
+}
+
+// Purge permanently deletes a trashed record
+func (s *CRUDService[T, ID]) Purge(ctx context.Context, id ID) error {
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetByIDWithTrashed(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.guard(ctx, before); err != nil {
+			return err
+		}
+		if err := s.repo.Purge(ctx, id); err != nil {
+			return err
+		}
+		return s.audit(ctx, id, model.AuditPurge, before, nil)
+	})
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotempl/model"
+	"gotempl/repository"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidTransition is returned when a lifecycle action doesn't apply to
+// the current status of an event
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// Event lifecycle actions
+const (
+	ActionSchedule = "schedule"
+	ActionPublish  = "publish"
+	ActionStart    = "start"
+	ActionComplete = "complete"
+	ActionPostpone = "postpone"
+	ActionCancel   = "cancel"
+)
+
+// EventTransition is a lifecycle action, moving events from one of From to To
+type EventTransition struct {
+	From []string
+	To   string
+	// Announced transitions need a title and a start time
+	Announced bool
+	// NeedsReason transitions must say why they are made
+	NeedsReason bool
+}
+
+// EventTransitions is the event lifecycle: draft → scheduled or published →
+// live → completed, with cancelled and postponed on the side
+var EventTransitions = map[string]EventTransition{
+	ActionSchedule: {From: []string{model.EventDraft, model.EventPostponed}, To: model.EventScheduled, Announced: true},
+	ActionPublish:  {From: []string{model.EventDraft, model.EventScheduled, model.EventPostponed}, To: model.EventPublished, Announced: true},
+	ActionStart:    {From: []string{model.EventScheduled, model.EventPublished}, To: model.EventLive},
+	ActionComplete: {From: []string{model.EventLive}, To: model.EventCompleted},
+	ActionPostpone: {From: []string{model.EventScheduled, model.EventPublished}, To: model.EventPostponed},
+	ActionCancel: {
+		From:        []string{model.EventDraft, model.EventScheduled, model.EventPublished, model.EventLive, model.EventPostponed},
+		To:          model.EventCancelled,
+		NeedsReason: true,
+	},
+}
+
+// check tells whether the transition applies to the event
+func (t EventTransition) check(action string, event *model.Event, reason string) error {
+	if !slices.Contains(t.From, event.Status) {
+		return fmt.Errorf("%w: can't %s a %s event", ErrInvalidTransition, action, event.Status)
+	}
+	if t.NeedsReason && strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("%w: a reason is required to %s an event", ErrInvalid, action)
+	}
+	if t.Announced {
+		if event.Title == "" || event.StartTime == nil {
+			return fmt.Errorf("%w: can't %s an event without a title and a start time", ErrInvalid, action)
+		}
+	}
+	return nil
+}
+
+// keepStatus rejects the status changes made by creations and updates, they
+// must go through Transition. An empty status is the default one.
+func keepStatus(event, existing *model.Event) error {
+	if existing == nil {
+		if event.Status != "" && event.Status != model.EventDraft {
+			return errors.New("events are created as drafts, their status is changed by the lifecycle actions")
+		}
+		event.Status = model.EventDraft
+		event.StatusReason = ""
+		return nil
+	}
+	if event.Status == "" {
+		event.Status = existing.Status
+	}
+	if event.Status != existing.Status {
+		return errors.New("the status is changed by the lifecycle actions, not by edits")
+	}
+	event.StatusReason = existing.StatusReason
+	return nil
+}
+
+// Transition applies the lifecycle action to the event for updatedBy, the
+// reason is kept with its new status. A version of 0 changes any version, see
+// Update.
+func (s *EventService) Transition(ctx context.Context, id, version uint64, action, reason, updatedBy string) (*model.Event, error) {
+	transition, ok := EventTransitions[action]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidTransition, action)
+	}
+
+	// Only the lifecycle may change the status
+	lifecycle := *s.CRUDService
+	lifecycle.Hooks.Change = nil
+
+	var event *model.Event
+	err := s.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if event, err = s.Get(ctx, id); err != nil {
+			return err
+		}
+		if version != 0 && event.Version != version {
+			return repository.ErrVersionConflict
+		}
+		if err := transition.check(action, event, reason); err != nil {
+			return err
+		}
+		event.Status = transition.To
+		event.StatusReason = strings.TrimSpace(reason)
+		event.UpdatedBy = updatedBy
+		return lifecycle.Update(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
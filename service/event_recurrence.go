@@ -0,0 +1,564 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"gotempl/model"
+	"gotempl/recurrence"
+	"gotempl/repository"
+	"slices"
+	"time"
+)
+
+// ErrNoOccurrence is returned for the times that aren't an occurrence of a series
+var ErrNoOccurrence = errors.New("no such occurrence")
+
+// MaxOccurrences is the most occurrences an expansion returns, longer windows
+// must be split
+const MaxOccurrences = 1000
+
+// MaxWindow is the longest window occurrences are expanded in, and
+// MaxYearsAhead how many years after the start of a series a window may start
+const (
+	MaxWindow     = 366 * 24 * time.Hour
+	MaxYearsAhead = 100
+)
+
+// Edit scopes of the occurrences of a series, as in calendar applications
+const (
+	EditThis      = "this"
+	EditFollowing = "following"
+	EditAll       = "all"
+)
+
+// validateRecurrence checks the rule of a series, which starts with its first
+// occurrence, and stores it in its canonical form with its exceptions in UTC.
+// Events overriding an occurrence don't recur themselves.
+func validateRecurrence(event *model.Event) error {
+	if event.SeriesID != nil || event.RecurrenceID != nil {
+		if event.SeriesID == nil || event.RecurrenceID == nil {
+			return errors.New("series_id and recurrence_id are set together")
+		}
+		if event.RRule != "" || len(event.ExDates) > 0 {
+			return errors.New("an occurrence of a series can't have an rrule or exdates")
+		}
+		rid := event.RecurrenceID.UTC()
+		event.RecurrenceID = &rid
+		return nil
+	}
+	if event.RRule == "" {
+		if len(event.ExDates) > 0 {
+			return errors.New("exdates need an rrule")
+		}
+		return nil
+	}
+
+	if event.StartTime == nil {
+		return errors.New("start_time is required with an rrule")
+	}
+	dtstart, rule, err := seriesRule(event)
+	if err != nil {
+		return err
+	}
+	if !rule.Includes(dtstart, dtstart) {
+		return errors.New("start_time must be the first occurrence of the rrule")
+	}
+	event.RRule = rule.String()
+	event.ExDates = normalizeExDates(event.ExDates)
+	return nil
+}
+
+// seriesRule returns the first occurrence of a series, in its time zone, and its rule
+func seriesRule(event *model.Event) (time.Time, *recurrence.Rule, error) {
+	loc, err := time.LoadLocation(event.TimeZone)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	rule, err := recurrence.Parse(event.RRule)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return event.StartTime.In(loc), rule, nil
+}
+
+// normalizeExDates lists the times once, in UTC and in order
+func normalizeExDates(times model.TimeList) model.TimeList {
+	if len(times) == 0 {
+		return nil
+	}
+	exdates := make(model.TimeList, len(times))
+	for i, t := range times {
+		exdates[i] = t.UTC()
+	}
+	slices.SortFunc(exdates, time.Time.Compare)
+	return slices.CompactFunc(exdates, time.Time.Equal)
+}
+
+// splitExDates returns the exceptions before t and those from t on
+func splitExDates(times model.TimeList, t time.Time) (model.TimeList, model.TimeList) {
+	i, _ := slices.BinarySearchFunc(times, t, time.Time.Compare)
+	return slices.Clone(times[:i]), slices.Clone(times[i:])
+}
+
+// endAt returns the end of the occurrence of the event starting at start: as
+// long as the event, or as many days for all-day events
+func endAt(event *model.Event, start time.Time) *time.Time {
+	if event.EndTime == nil || event.StartTime == nil {
+		return nil
+	}
+	end := start.Add(event.EndTime.Sub(*event.StartTime))
+	if event.AllDay {
+		loc, err := time.LoadLocation(event.TimeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		end = start.In(loc).AddDate(0, 0, day(event.EndTime.In(loc))-day(event.StartTime.In(loc)))
+	}
+	end = end.UTC()
+	return &end
+}
+
+// day numbers the day of t in its time zone
+func day(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// shiftExDates moves the exceptions of the event by shift, unless they were edited
+func shiftExDates(event *model.Event, exdates model.TimeList, shift time.Duration) {
+	if !slices.EqualFunc(event.ExDates, exdates, time.Time.Equal) {
+		return
+	}
+	event.ExDates = make(model.TimeList, len(exdates))
+	for i, t := range exdates {
+		event.ExDates[i] = t.Add(shift)
+	}
+}
+
+// occurrence returns the occurrence of the event starting at start
+func occurrence(event *model.Event, seriesID uint64, rid, start time.Time) model.Occurrence {
+	return model.Occurrence{
+		SeriesID:     seriesID,
+		RecurrenceID: rid.UTC(),
+		StartTime:    start.UTC(),
+		EndTime:      endAt(event, start),
+		Event:        *event,
+	}
+}
+
+// each calls fn with the live events matching the filter, page by page
+func (s *EventService) each(ctx context.Context, filter repository.EventFilter, fn func(event *model.Event) error) error {
+	opts := repository.ListOptions{Limit: repository.MaxPageSize}
+	for {
+		page, err := s.List(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+		for i := range page.Items {
+			if err := fn(&page.Items[i]); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// overrides returns the events overriding occurrences of the series
+func (s *EventService) overrides(ctx context.Context, seriesID uint64) ([]model.Event, error) {
+	var events []model.Event
+	err := s.each(ctx, repository.EventFilter{SeriesID: &seriesID}, func(event *model.Event) error {
+		events = append(events, *event)
+		return nil
+	})
+	return events, err
+}
+
+// expand returns the occurrences of the event starting in [from, to): the
+// event itself when it doesn't recur, otherwise those of its rule but the
+// exceptions, replaced by the events overriding them wherever they moved
+func (s *EventService) expand(ctx context.Context, event *model.Event, from, to time.Time) ([]model.Occurrence, error) {
+	if event.StartTime == nil {
+		return nil, nil
+	}
+	if event.RRule == "" {
+		if event.StartTime.Before(from) || !event.StartTime.Before(to) {
+			return nil, nil
+		}
+		seriesID, rid := event.ID, *event.StartTime
+		if event.SeriesID != nil {
+			seriesID, rid = *event.SeriesID, *event.RecurrenceID
+		}
+		return []model.Occurrence{occurrence(event, seriesID, rid, *event.StartTime)}, nil
+	}
+
+	dtstart, rule, err := seriesRule(event)
+	if err != nil {
+		return nil, err
+	}
+	if from.After(dtstart.AddDate(MaxYearsAhead, 0, 0)) {
+		return nil, fmt.Errorf("%w: the window can't start more than %d years after the series", ErrInvalid, MaxYearsAhead)
+	}
+	overrides, err := s.overrides(ctx, event.ID)
+	if err != nil {
+		return nil, err
+	}
+	overridden := model.TimeList{}
+	var occurrences []model.Occurrence
+	for i := range overrides {
+		override := &overrides[i]
+		overridden = append(overridden, *override.RecurrenceID)
+		if override.StartTime != nil && !override.StartTime.Before(from) && override.StartTime.Before(to) {
+			occurrences = append(occurrences, occurrence(override, event.ID, *override.RecurrenceID, *override.StartTime))
+		}
+	}
+	for _, t := range rule.Between(dtstart, from, to, MaxOccurrences+1) {
+		if !event.ExDates.Contains(t) && !overridden.Contains(t) {
+			occurrences = append(occurrences, occurrence(event, event.ID, t, t))
+		}
+	}
+	return occurrences, nil
+}
+
+// sortOccurrences orders occurrences by start, and fails when there are too many
+func sortOccurrences(occurrences []model.Occurrence) ([]model.Occurrence, error) {
+	if len(occurrences) > MaxOccurrences {
+		return nil, fmt.Errorf("%w: more than %d occurrences, narrow the window", ErrInvalid, MaxOccurrences)
+	}
+	slices.SortFunc(occurrences, func(a, b model.Occurrence) int {
+		return cmp.Or(a.StartTime.Compare(b.StartTime), cmp.Compare(a.SeriesID, b.SeriesID))
+	})
+	if occurrences == nil {
+		occurrences = []model.Occurrence{}
+	}
+	return occurrences, nil
+}
+
+// checkWindow bounds the work of an expansion
+func checkWindow(from, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("%w: to must be after from", ErrInvalid)
+	}
+	if to.Sub(from) > MaxWindow {
+		return fmt.Errorf("%w: the window can't be longer than %d days", ErrInvalid, MaxWindow/(24*time.Hour))
+	}
+	return nil
+}
+
+// Occurrences returns the occurrences of the event starting in [from, to), in order
+func (s *EventService) Occurrences(ctx context.Context, id uint64, from, to time.Time) ([]model.Occurrence, error) {
+	if err := checkWindow(from, to); err != nil {
+		return nil, err
+	}
+	event, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	occurrences, err := s.expand(ctx, event, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return sortOccurrences(occurrences)
+}
+
+// ListOccurrences returns the occurrences of the events matching the filter
+// that start in [from, to), in order. from and to replace the start filters
+// of the filter, the occurrences of a series follow its filters.
+func (s *EventService) ListOccurrences(ctx context.Context, filter repository.EventFilter, from, to time.Time) ([]model.Occurrence, error) {
+	if err := checkWindow(from, to); err != nil {
+		return nil, err
+	}
+	filter.SeriesID = nil
+	var occurrences []model.Occurrence
+	collect := func(event *model.Event) error {
+		expanded, err := s.expand(ctx, event, from, to)
+		if err != nil {
+			return err
+		}
+		occurrences = append(occurrences, expanded...)
+		if len(occurrences) > MaxOccurrences {
+			return fmt.Errorf("%w: more than %d occurrences, narrow the window", ErrInvalid, MaxOccurrences)
+		}
+		return nil
+	}
+
+	recurring := filter.Recurring
+	no, yes := false, true
+	if recurring == nil || !*recurring {
+		singles := filter
+		singles.Recurring, singles.StartAfter, singles.StartBefore = &no, &from, &to
+		if err := s.each(ctx, singles, collect); err != nil {
+			return nil, err
+		}
+	}
+	if recurring == nil || *recurring {
+		// A series may have occurrences in the window when it starts before its end
+		series := filter
+		series.Recurring, series.StartAfter, series.StartBefore = &yes, nil, &to
+		if err := s.each(ctx, series, collect); err != nil {
+			return nil, err
+		}
+	}
+	return sortOccurrences(occurrences)
+}
+
+// series loads the recurring event at version, a version of 0 loads any, and
+// checks rid is one of its occurrences
+func (s *EventService) series(ctx context.Context, id, version uint64, rid time.Time) (*model.Event, error) {
+	series, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && series.Version != version {
+		return nil, repository.ErrVersionConflict
+	}
+	if series.RRule == "" {
+		return nil, fmt.Errorf("%w: the event doesn't recur", ErrInvalid)
+	}
+	dtstart, rule, err := seriesRule(series)
+	if err != nil {
+		return nil, err
+	}
+	if !rule.Includes(dtstart, rid) || series.ExDates.Contains(rid) {
+		return nil, ErrNoOccurrence
+	}
+	return series, nil
+}
+
+// override returns the event overriding the occurrence rid of the series, nil
+// when there is none
+func (s *EventService) override(ctx context.Context, seriesID uint64, rid time.Time) (*model.Event, error) {
+	overrides, err := s.overrides(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		if overrides[i].RecurrenceID.Equal(rid) {
+			return &overrides[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// fork copies the series for an event starting at start: an override of one
+// of its occurrences, or the series of the following ones
+func fork(series *model.Event, start time.Time) model.Event {
+	event := *series
+	event.ID, event.Version, event.AttendeesCount = 0, 0, 0
+	event.CreatedAt, event.UpdatedAt = time.Time{}, time.Time{}
+	event.Images, event.Tags = slices.Clone(series.Images), slices.Clone(series.Tags)
+	event.ExDates = nil
+	start = start.UTC()
+	event.StartTime, event.EndTime = &start, endAt(series, start)
+	return event
+}
+
+// createFork creates a copy of a series with its status, which edit may not change
+func (s *EventService) createFork(ctx context.Context, event *model.Event, status string, edit func(event *model.Event) error) error {
+	if err := edit(event); err != nil {
+		return err
+	}
+	if event.Status != status {
+		return fmt.Errorf("%w: the status is changed by the lifecycle actions, not by edits", ErrInvalid)
+	}
+	// keepStatus would make it a draft
+	lifecycle := *s.CRUDService
+	lifecycle.Hooks.Change = nil
+	return lifecycle.Create(ctx, event)
+}
+
+// EditOccurrence changes the occurrence rid of the recurring event with edit,
+// which is given the event to change. The scope is EditThis, to change the
+// occurrence alone through the event overriding it, EditFollowing, to change
+// it and the following ones in a new series, or EditAll, to change the series.
+// It returns the event changed. A version of 0 changes any version of the
+// series, see Update.
+func (s *EventService) EditOccurrence(ctx context.Context, id, version uint64, rid time.Time, scope string, edit func(event *model.Event) error) (*model.Event, error) {
+	var changed *model.Event
+	err := s.WithinTx(ctx, func(ctx context.Context) error {
+		series, err := s.series(ctx, id, version, rid)
+		if err != nil {
+			return err
+		}
+		if scope == EditFollowing && rid.Equal(*series.StartTime) {
+			scope = EditAll
+		}
+		switch scope {
+		case EditThis:
+			changed, err = s.editThis(ctx, series, rid, edit)
+		case EditFollowing:
+			changed, err = s.editFollowing(ctx, series, rid, edit)
+		case EditAll:
+			changed, err = s.editAll(ctx, series, edit)
+		default:
+			err = fmt.Errorf("%w: unknown scope %q", ErrInvalid, scope)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (s *EventService) editThis(ctx context.Context, series *model.Event, rid time.Time, edit func(event *model.Event) error) (*model.Event, error) {
+	override, err := s.override(ctx, series.ID, rid)
+	if err != nil {
+		return nil, err
+	}
+	rid = rid.UTC()
+	if override != nil {
+		if err := edit(override); err != nil {
+			return nil, err
+		}
+		override.SeriesID, override.RecurrenceID = &series.ID, &rid
+		return override, s.Update(ctx, override)
+	}
+
+	event := fork(series, rid)
+	event.RRule = ""
+	err = s.createFork(ctx, &event, series.Status, func(event *model.Event) error {
+		err := edit(event)
+		event.SeriesID, event.RecurrenceID = &series.ID, &rid
+		return err
+	})
+	return &event, err
+}
+
+// editFollowing ends the series before rid and continues it in a new series,
+// which takes the exceptions and the overrides from rid on
+func (s *EventService) editFollowing(ctx context.Context, series *model.Event, rid time.Time, edit func(event *model.Event) error) (*model.Event, error) {
+	dtstart, rule, err := seriesRule(series)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.overrides(ctx, series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := fork(series, rid)
+	before, after := splitExDates(series.ExDates, rid)
+	if rule.Count > 0 {
+		rest := *rule
+		rest.Count -= len(rule.Between(dtstart, dtstart, rid, 0))
+		next.RRule = rest.String()
+	}
+	rule.EndBefore(rid)
+	series.RRule, series.ExDates = rule.String(), before
+	if err := s.Update(ctx, series); err != nil {
+		return nil, err
+	}
+
+	next.ExDates = after
+	err = s.createFork(ctx, &next, series.Status, func(event *model.Event) error {
+		err := edit(event)
+		event.SeriesID, event.RecurrenceID = nil, nil
+		if event.StartTime != nil {
+			shiftExDates(event, after, event.StartTime.Sub(rid))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shift := next.StartTime.Sub(rid)
+	for i := range overrides {
+		override := &overrides[i]
+		if override.RecurrenceID.Before(rid) {
+			continue
+		}
+		moved := override.RecurrenceID.Add(shift)
+		override.SeriesID, override.RecurrenceID = &next.ID, &moved
+		if err := s.Update(ctx, override); err != nil {
+			return nil, err
+		}
+	}
+	return &next, nil
+}
+
+// editAll changes the series, its exceptions and overrides move with its start
+func (s *EventService) editAll(ctx context.Context, series *model.Event, edit func(event *model.Event) error) (*model.Event, error) {
+	start, exdates := *series.StartTime, slices.Clone(series.ExDates)
+	if err := edit(series); err != nil {
+		return nil, err
+	}
+	if series.StartTime == nil || series.StartTime.Equal(start) {
+		return series, s.Update(ctx, series)
+	}
+
+	shift := series.StartTime.Sub(start)
+	shiftExDates(series, exdates, shift)
+	if err := s.Update(ctx, series); err != nil {
+		return nil, err
+	}
+	overrides, err := s.overrides(ctx, series.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		moved := overrides[i].RecurrenceID.Add(shift)
+		overrides[i].RecurrenceID = &moved
+		if err := s.Update(ctx, &overrides[i]); err != nil {
+			return nil, err
+		}
+	}
+	return series, nil
+}
+
+// DeleteOccurrence removes the occurrence rid of the recurring event: alone
+// (EditThis), with the following ones (EditFollowing) or with the whole series
+// (EditAll). The events overriding removed occurrences go to the trash. A
+// version of 0 changes any version of the series, see Delete.
+func (s *EventService) DeleteOccurrence(ctx context.Context, id, version uint64, rid time.Time, scope, deletedBy string) error {
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		series, err := s.series(ctx, id, version, rid)
+		if err != nil {
+			return err
+		}
+		if scope == EditFollowing && rid.Equal(*series.StartTime) {
+			scope = EditAll
+		}
+
+		// removed tells whether the override of an occurrence goes to the trash
+		var removed func(rid time.Time) bool
+		switch scope {
+		case EditThis:
+			series.ExDates = normalizeExDates(append(series.ExDates, rid))
+			removed = rid.Equal
+		case EditFollowing:
+			_, rule, err := seriesRule(series)
+			if err != nil {
+				return err
+			}
+			rule.EndBefore(rid)
+			series.RRule = rule.String()
+			series.ExDates, _ = splitExDates(series.ExDates, rid)
+			removed = func(t time.Time) bool { return !t.Before(rid) }
+		case EditAll:
+			removed = func(time.Time) bool { return true }
+		default:
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalid, scope)
+		}
+
+		overrides, err := s.overrides(ctx, series.ID)
+		if err != nil {
+			return err
+		}
+		for _, override := range overrides {
+			if removed(*override.RecurrenceID) {
+				if err := s.Delete(ctx, override.ID, 0, deletedBy); err != nil {
+					return err
+				}
+			}
+		}
+		if scope == EditAll {
+			return s.Delete(ctx, series.ID, series.Version, deletedBy)
+		}
+		return s.Update(ctx, series)
+	})
+}
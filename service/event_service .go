@@ -1,64 +1,157 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"gotempl/model"
 	"gotempl/repository"
-
-	"github.com/go-playground/validator/v10"
-	log "github.com/sirupsen/logrus"
+	"slices"
+	"time"
 )
 
 type EventService struct {
-	repo     *repository.EventRepository
-	validate *validator.Validate
-}
+	*CRUDService[model.Event, uint64]
+	// Attendance promotes the waitlist when the capacity of an event is raised, optional
+	Attendance *AttendanceService
 
-func NewEventService(repo *repository.EventRepository) *EventService {
-	return &EventService{
-		repo:     repo,
-		validate: validator.New(),
-	}
+	events repository.EventRepository
+	search repository.SearchRepository
 }
 
-func (s *EventService) CreateEvent(event *model.Event) error {
-	if err := s.validate.Struct(event); err != nil {
-		return err
+func NewEventService(repo repository.EventRepository, search repository.SearchRepository) *EventService {
+	s := &EventService{
+		CRUDService: NewCRUDService[model.Event, uint64](repo, model.AuditEntityEvent),
+		events:      repo,
+		search:      search,
 	}
-
-	if event.Title == "" {
-		return errors.New("id and eventname are required")
+	s.Hooks = Hooks[model.Event, uint64]{
+		Validate: func(event *model.Event) error {
+			if event.Title == "" {
+				return errors.New("title is required")
+			}
+			if err := validateTags(event.Tags); err != nil {
+				return err
+			}
+			if err := validateSchedule(event); err != nil {
+				return err
+			}
+			return validateRecurrence(event)
+		},
+		Change: keepStatus,
+		// The index is shared by every organization
+		Saved: func(ctx context.Context, event *model.Event) error {
+			if err := search.Index(ctx, event); err != nil {
+				return err
+			}
+			return s.promoteWaitlist(ctx, event)
+		},
+		Removed: search.Remove,
+		OrgsOf: func(ctx context.Context, event *model.Event) ([]string, error) {
+			return []string{event.OrgID}, nil
+		},
 	}
+	return s
+}
 
-	return s.repo.Create(event)
+// ForOrg returns a service whose reads and writes are limited to the organization's events
+func (s *EventService) ForOrg(orgID string) *EventService {
+	scoped := *s
+	scoped.events = s.events.ForOrg(orgID)
+	scoped.search = s.search.ForOrg(orgID)
+	scoped.CRUDService = s.CRUDService.WithRepo(scoped.events)
+	return &scoped
 }
 
-func (s *EventService) GetEvent(id uint64) (*model.Event, error) {
-	return s.repo.GetByID(id)
+// As returns a service whose changes are recorded in the audit log as made by actor
+func (s *EventService) As(actor Actor) *EventService {
+	acting := *s
+	acting.CRUDService = s.CRUDService.As(actor)
+	return &acting
 }
 
-func (s *EventService) GetAllEvents() ([]model.Event, error) {
-	return s.repo.GetAll()
+// promoteWaitlist gives the seats left by a capacity raise to the waitlist,
+// the event is reloaded as they change its count and version
+func (s *EventService) promoteWaitlist(ctx context.Context, event *model.Event) error {
+	if s.Attendance == nil || event.MaxAttendees > 0 && event.AttendeesCount >= event.MaxAttendees {
+		return nil
+	}
+	promoted, err := s.Attendance.PromoteWaitlist(ctx, event.ID)
+	if err != nil || len(promoted) == 0 {
+		return err
+	}
+	current, err := s.events.GetByID(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	*event = *current
+	return nil
 }
 
-func (s *EventService) GetEventByID(id uint64) (*model.Event, error) {
-	return s.repo.GetByID(id)
+// SearchEvents returns the events best matching the words of query
+func (s *EventService) SearchEvents(ctx context.Context, query string, limit int) (*repository.Page[repository.SearchResult], error) {
+	return s.search.Search(ctx, query, limit)
 }
 
-func (s *EventService) UpdateEvent(event *model.Event) error {
-	if err := s.validate.Struct(event); err != nil {
-		log.Error("Error:", err)
-		//fmt.Println("Error:", err)
-		return err
+// validateTags checks the tags are well formed and listed once
+func validateTags(tags model.StringList) error {
+	for i, tag := range tags {
+		if !model.ValidTag(tag) {
+			return fmt.Errorf("tag %q must be at most %d lower case letters, digits and inner dashes", tag, model.MaxTagLength)
+		}
+		if slices.Contains(tags[:i], tag) {
+			return fmt.Errorf("tag %q is listed twice", tag)
+		}
 	}
-	return s.repo.Update(event)
+	return nil
 }
 
-func (s *EventService) DeleteEvent(id uint64) error {
-	return s.repo.Delete(id)
+// TagCounts returns how many events have each tag, the most used first
+func (s *EventService) TagCounts(ctx context.Context) ([]repository.TagCount, error) {
+	return s.events.TagCounts(ctx)
 }
 
-// Additional method to match the handler
-func (s *EventService) GetAllEvent() ([]model.Event, error) {
-	return s.repo.GetAll()
+// validateSchedule checks the times of the event in its time zone, UTC when
+// empty, and stores them in UTC. All-day events last whole days, from midnight
+// to midnight, one day when they have no end.
+func validateSchedule(event *model.Event) error {
+	if event.TimeZone == "" {
+		event.TimeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(event.TimeZone)
+	if err != nil || event.TimeZone == "Local" {
+		return fmt.Errorf("unknown time zone %q", event.TimeZone)
+	}
+	if event.StartTime == nil {
+		if event.EndTime != nil || event.AllDay {
+			return errors.New("start_time is required with an end_time or all_day")
+		}
+		return nil
+	}
+
+	if event.AllDay {
+		if event.EndTime == nil {
+			end := event.StartTime.In(loc).AddDate(0, 0, 1)
+			event.EndTime = &end
+		}
+		for _, t := range []*time.Time{event.StartTime, event.EndTime} {
+			if local := t.In(loc); !local.Equal(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)) {
+				return fmt.Errorf("all-day events start and end at midnight in %s", event.TimeZone)
+			}
+		}
+		if !event.EndTime.After(*event.StartTime) {
+			return errors.New("all-day events last at least one day")
+		}
+	}
+	if event.EndTime != nil && event.EndTime.Before(*event.StartTime) {
+		return errors.New("end_time must not be before start_time")
+	}
+
+	start := event.StartTime.UTC()
+	event.StartTime = &start
+	if event.EndTime != nil {
+		end := event.EndTime.UTC()
+		event.EndTime = &end
+	}
+	return nil
 }
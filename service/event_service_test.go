@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gotempl/model"
+	"gotempl/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupEventService() (*EventService, *repository.MemoryAuditRepository) {
+	audit := repository.NewMemoryAuditRepository()
+	events := NewEventService(repository.NewMemoryEventRepository(), repository.NewMemorySearchRepository())
+	events.Audit = NewAuditService(audit)
+	return events, audit
+}
+
+func TestEventService(t *testing.T) {
+	ctx := context.Background()
+	events, audit := setupEventService()
+	acme := events.ForOrg("org_acme").As(Actor{Subject: "editor-uid"})
+
+	assert.ErrorIs(t, acme.Create(ctx, &model.Event{CreatedBy: "editor-uid"}), ErrInvalid)
+
+	for _, title := range []string{"Go meetup", "Rust meetup", "Board meeting"} {
+		assert.NoError(t, acme.Create(ctx, &model.Event{Title: title, CreatedBy: "editor-uid"}))
+	}
+	personal := &model.Event{Title: "Go meetup at home", CreatedBy: "other-uid"}
+	assert.NoError(t, events.Create(ctx, personal))
+
+	event, err := acme.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "org_acme", event.OrgID)
+	assert.Equal(t, uint64(1), event.Version)
+	_, err = acme.Get(ctx, personal.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	page, err := acme.List(ctx, repository.EventFilter{}, repository.ListOptions{Limit: 2, Sort: "-title"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, "Rust meetup", page.Items[0].Title)
+	assert.Equal(t, "Go meetup", page.Items[1].Title)
+	page, err = acme.List(ctx, repository.EventFilter{}, repository.ListOptions{Limit: 2, Sort: "-title", Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "Board meeting", page.Items[0].Title)
+	assert.Empty(t, page.NextCursor)
+
+	results, err := acme.SearchEvents(ctx, "meetup", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), results.Total)
+
+	event.Title = "Go meetup #2"
+	assert.NoError(t, acme.Update(ctx, event))
+	assert.Equal(t, uint64(2), event.Version)
+	event.Version = 1
+	assert.ErrorIs(t, acme.Update(ctx, event), repository.ErrVersionConflict)
+
+	assert.NoError(t, acme.Delete(ctx, 2, 0, "editor-uid"))
+	results, err = acme.SearchEvents(ctx, "meetup", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), results.Total)
+
+	trash, err := acme.ListTrash(ctx, repository.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, trash.Items, 1)
+	assert.Equal(t, "editor-uid", trash.Items[0].DeletedBy)
+
+	restored, err := acme.Restore(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), restored.Version)
+
+	entries, err := NewAuditService(audit).ForOrg("org_acme").ListEntries(ctx, repository.AuditFilter{EntityID: "2"}, repository.ListOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, entries.Items, 3) {
+		assert.Equal(t, model.AuditRestore, entries.Items[0].Action)
+		assert.Equal(t, "editor-uid", entries.Items[0].Actor)
+	}
+}
+
+func TestCRUDServiceRollsBack(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Event{}, &model.AuditEntry{}))
+
+	events := NewCRUDService[model.Event, uint64](repository.NewEventRepository(db), model.AuditEntityEvent)
+	events.Audit = NewAuditService(repository.NewAuditRepository(db))
+	events.Hooks.Saved = func(ctx context.Context, event *model.Event) error {
+		return errors.New("index unavailable")
+	}
+
+	assert.Error(t, events.Create(context.Background(), &model.Event{Title: "Lost", CreatedBy: "editor-uid"}))
+
+	var count int64
+	assert.NoError(t, db.Model(&model.Event{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.NoError(t, db.Model(&model.AuditEntry{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestCRUDServiceRollsBackUnauditedChanges(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	// Without an audit table the entries can't be written
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Event{}))
+
+	events := NewCRUDService[model.Event, uint64](repository.NewEventRepository(db), model.AuditEntityEvent)
+	events.Audit = NewAuditService(repository.NewAuditRepository(db))
+
+	assert.Error(t, events.Create(context.Background(), &model.Event{Title: "Unaudited", CreatedBy: "editor-uid"}))
+
+	var count int64
+	assert.NoError(t, db.Model(&model.Event{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestEventTransition(t *testing.T) {
+	ctx := context.Background()
+	events, audit := setupEventService()
+	start := time.Date(2024, 11, 5, 18, 0, 0, 0, time.UTC)
+
+	event := &model.Event{Title: "Launch"}
+	assert.NoError(t, events.Create(ctx, event))
+	_, err := events.Transition(ctx, event.ID, 0, ActionSchedule, "", "publisher-uid")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	end := start.Add(time.Hour)
+	event.StartTime, event.EndTime = &start, &end
+	assert.NoError(t, events.Update(ctx, event))
+	_, err = events.Transition(ctx, event.ID, 1, ActionSchedule, "", "publisher-uid")
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	_, err = events.Transition(ctx, event.ID, 0, "archive", "", "publisher-uid")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	for _, action := range []string{ActionSchedule, ActionPublish, ActionStart, ActionComplete} {
+		event, err = events.Transition(ctx, event.ID, event.Version, action, "", "publisher-uid")
+		assert.NoError(t, err, action)
+		assert.Equal(t, EventTransitions[action].To, event.Status)
+		assert.Equal(t, "publisher-uid", event.UpdatedBy)
+	}
+	_, err = events.Transition(ctx, event.ID, 0, ActionCancel, "Too late", "publisher-uid")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	event.Status = model.EventDraft
+	assert.ErrorIs(t, events.Update(ctx, event), ErrInvalid)
+
+	entries, err := NewAuditService(audit).ListEntries(ctx, repository.AuditFilter{Action: model.AuditUpdate}, repository.ListOptions{})
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, entries.Items) {
+		assert.Equal(t, model.AuditChange{Before: model.EventLive, After: model.EventCompleted}, entries.Items[0].Changes["status"])
+	}
+}
+
+func TestEventSchedule(t *testing.T) {
+	ctx := context.Background()
+	events, _ := setupEventService()
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	at := func(day, hour int, loc *time.Location) *time.Time {
+		t := time.Date(2024, 11, day, hour, 0, 0, 0, loc)
+		return &t
+	}
+
+	for name, event := range map[string]*model.Event{
+		"unknown zone":      {Title: "E", TimeZone: "Mars/Olympus"},
+		"end without start": {Title: "E", EndTime: at(5, 18, time.UTC)},
+		"end before start":  {Title: "E", StartTime: at(5, 18, time.UTC), EndTime: at(5, 17, time.UTC)},
+		"all day not at 0h": {Title: "E", AllDay: true, TimeZone: "Europe/Paris", StartTime: at(5, 0, time.UTC)},
+		"all day zero days": {Title: "E", AllDay: true, StartTime: at(5, 0, time.UTC), EndTime: at(5, 0, time.UTC)},
+		"all day no start":  {Title: "E", AllDay: true},
+		"local is no zone":  {Title: "E", TimeZone: "Local"},
+	} {
+		assert.ErrorIs(t, events.Create(ctx, event), ErrInvalid, name)
+	}
+
+	event := &model.Event{Title: "Meetup", TimeZone: "Europe/Paris", StartTime: at(5, 18, paris), EndTime: at(5, 20, paris)}
+	assert.NoError(t, events.Create(ctx, event))
+	assert.Equal(t, time.UTC, event.StartTime.Location())
+	assert.Equal(t, time.Date(2024, 11, 5, 17, 0, 0, 0, time.UTC), *event.StartTime)
+
+	// The day of an all-day event is in its time zone, one day by default
+	event = &model.Event{Title: "Hackathon", AllDay: true, TimeZone: "Europe/Paris", StartTime: at(5, 0, paris)}
+	assert.NoError(t, events.Create(ctx, event))
+	assert.Equal(t, time.Date(2024, 11, 4, 23, 0, 0, 0, time.UTC), *event.StartTime)
+	assert.Equal(t, time.Date(2024, 11, 5, 23, 0, 0, 0, time.UTC), *event.EndTime)
+
+	event = &model.Event{Title: "Unscheduled"}
+	assert.NoError(t, events.Create(ctx, event))
+	assert.Equal(t, "UTC", event.TimeZone)
+	assert.Nil(t, event.StartTime)
+}
+
+func TestEventOccurrences(t *testing.T) {
+	ctx := context.Background()
+	events, _ := setupEventService()
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, paris)
+	}
+	starts := func(occurrences []model.Occurrence) []string {
+		times := []string{}
+		for _, o := range occurrences {
+			times = append(times, o.StartTime.In(paris).Format("Jan 2 15:04")+" "+o.Event.Title)
+		}
+		return times
+	}
+	from, to := at(10, 1, 0, 0), at(12, 31, 0, 0)
+
+	start, end := at(10, 21, 9, 30), at(10, 21, 9, 45)
+	for _, invalid := range []*model.Event{
+		{Title: "Standup", RRule: "FREQ=WEEKLY;BYDAY=TU", StartTime: &start, TimeZone: "Europe/Paris"},
+		{Title: "Standup", RRule: "FREQ=SECONDLY", StartTime: &start},
+		{Title: "Standup", RRule: "FREQ=WEEKLY"},
+		{Title: "Standup", ExDates: model.TimeList{start}, StartTime: &start},
+	} {
+		assert.ErrorIs(t, events.Create(ctx, invalid), ErrInvalid)
+	}
+
+	// Six Monday standups across the change to winter time
+	series := &model.Event{Title: "Standup", CreatedBy: "uid", RRule: "rrule:freq=weekly;count=6", StartTime: &start, EndTime: &end, TimeZone: "Europe/Paris"}
+	assert.NoError(t, events.Create(ctx, series))
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=6", series.RRule)
+	occurrences, err := events.Occurrences(ctx, series.ID, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Oct 21 09:30 Standup", "Oct 28 09:30 Standup", "Nov 4 09:30 Standup", "Nov 11 09:30 Standup", "Nov 18 09:30 Standup", "Nov 25 09:30 Standup"}, starts(occurrences))
+	assert.Equal(t, at(10, 28, 9, 45).UTC(), *occurrences[1].EndTime)
+	_, err = events.Occurrences(ctx, series.ID, to, from)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = events.Occurrences(ctx, series.ID, from, from.AddDate(2, 0, 0))
+	assert.ErrorIs(t, err, ErrInvalid)
+	far := time.Date(9998, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err = events.Occurrences(ctx, series.ID, far, far.AddDate(0, 1, 0))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// This one
+	assert.ErrorIs(t, events.DeleteOccurrence(ctx, series.ID, 0, at(11, 5, 9, 30), EditThis, "uid"), ErrNoOccurrence)
+	assert.NoError(t, events.DeleteOccurrence(ctx, series.ID, 0, at(11, 4, 9, 30), EditThis, "uid"))
+	moved, err := events.EditOccurrence(ctx, series.ID, 0, at(11, 11, 9, 30), EditThis, func(event *model.Event) error {
+		later := at(11, 11, 10, 0)
+		event.Title, event.StartTime, event.EndTime = "Late standup", &later, nil
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, series.ID, *moved.SeriesID)
+	assert.Equal(t, at(11, 11, 9, 30).UTC(), *moved.RecurrenceID)
+	_, err = events.EditOccurrence(ctx, series.ID, 0, at(11, 11, 9, 30), EditThis, func(event *model.Event) error {
+		event.Status = model.EventPublished
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+	occurrences, err = events.Occurrences(ctx, series.ID, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Oct 21 09:30 Standup", "Oct 28 09:30 Standup", "Nov 11 10:00 Late standup", "Nov 18 09:30 Standup", "Nov 25 09:30 Standup"}, starts(occurrences))
+	assert.Nil(t, occurrences[2].EndTime)
+	page, err := events.List(ctx, repository.EventFilter{}, repository.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+
+	// This one and the following ones, the series keeps its count
+	next, err := events.EditOccurrence(ctx, series.ID, 0, at(11, 18, 9, 30), EditFollowing, func(event *model.Event) error {
+		event.Title = "Daily"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=2", next.RRule)
+	series, err = events.Get(ctx, series.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;UNTIL=20241118T082959Z", series.RRule)
+	assert.Equal(t, model.TimeList{at(11, 4, 9, 30).UTC()}, series.ExDates)
+
+	single := &model.Event{Title: "Retro", CreatedBy: "uid", StartTime: &end}
+	assert.NoError(t, events.Create(ctx, single))
+	assert.NoError(t, events.Create(ctx, &model.Event{Title: "Undated", CreatedBy: "uid"}))
+	occurrences, err = events.ListOccurrences(ctx, repository.EventFilter{}, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Oct 21 09:30 Standup", "Oct 21 09:45 Retro", "Oct 28 09:30 Standup", "Nov 11 10:00 Late standup", "Nov 18 09:30 Daily", "Nov 25 09:30 Daily"}, starts(occurrences))
+	recurring := true
+	occurrences, err = events.ListOccurrences(ctx, repository.EventFilter{Recurring: &recurring}, at(11, 1, 0, 0), to)
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 3)
+
+	// The whole series, with its overrides
+	assert.NoError(t, events.DeleteOccurrence(ctx, series.ID, 0, at(10, 28, 9, 30), EditAll, "uid"))
+	_, err = events.Get(ctx, moved.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	occurrences, err = events.ListOccurrences(ctx, repository.EventFilter{}, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Oct 21 09:45 Retro", "Nov 18 09:30 Daily", "Nov 25 09:30 Daily"}, starts(occurrences))
+}
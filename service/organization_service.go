@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gotempl/model"
+	"gotempl/repository"
+)
+
+type OrganizationService struct {
+	repo repository.OrganizationRepository
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository) *OrganizationService {
+	return &OrganizationService{repo: repo}
+}
+
+// EnsureMembership records the organization and the user's membership as
+// reported by the identity provider, in one transaction. The name is only used
+// when it is not empty.
+func (s *OrganizationService) EnsureMembership(ctx context.Context, orgID, orgName, userUid, role string) error {
+	if orgID == "" || userUid == "" {
+		return errors.New("organization and user are required")
+	}
+
+	return s.repo.WithinTx(ctx, func(ctx context.Context) error {
+		if orgName == "" {
+			existing, err := s.repo.GetByID(ctx, orgID)
+			if err == nil {
+				orgName = existing.Name
+			} else {
+				orgName = orgID
+			}
+		}
+
+		if err := s.repo.Upsert(ctx, &model.Organization{ID: orgID, Name: orgName}); err != nil {
+			return err
+		}
+		return s.repo.UpsertMembership(ctx, &model.Membership{UserUid: userUid, OrgID: orgID, Role: role})
+	})
+}
+
+func (s *OrganizationService) GetAllOrganizations(ctx context.Context) ([]model.Organization, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *OrganizationService) GetOrganization(ctx context.Context, id string) (*model.Organization, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *OrganizationService) GetMembers(ctx context.Context, orgID string) ([]model.User, error) {
+	return s.repo.GetMembers(ctx, orgID)
+}
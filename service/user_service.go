@@ -1,61 +1,119 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"gotempl/model"
 	"gotempl/repository"
 
-	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
+// ErrUserDeleted is returned when synchronizing a user that is in the trash
+var ErrUserDeleted = errors.New("user is in the trash")
+
+// ErrSharedUser is returned when an organization changes a user who also
+// belongs to others
+var ErrSharedUser = fmt.Errorf("%w: the user also belongs to other organizations", ErrForbidden)
+
 type UserService struct {
-	repo     *repository.UserRepository
-	validate *validator.Validate
-}
+	*CRUDService[model.User, string]
 
-func NewUserService(repo *repository.UserRepository) *UserService {
-	return &UserService{
-		repo:     repo,
-		validate: validator.New(),
-	}
+	users repository.UserRepository
 }
 
-func (s *UserService) CreateUser(user *model.User) error {
-	if err := s.validate.Struct(user); err != nil {
-		return err
+func NewUserService(repo repository.UserRepository) *UserService {
+	s := &UserService{
+		CRUDService: NewCRUDService[model.User, string](repo, model.AuditEntityUser),
+		users:       repo,
 	}
-
-	if user.Uid == "" || user.Username == "" {
-		return errors.New("uid and username are required")
+	// The changes of members are audited in each of their organizations
+	s.Hooks.OrgsOf = func(ctx context.Context, user *model.User) ([]string, error) {
+		return repo.OrgIDs(ctx, user.Uid)
 	}
-
-	return s.repo.Create(user)
+	return s
 }
 
-func (s *UserService) GetUser(id string) (*model.User, error) {
-	return s.repo.GetByID(id)
+// ForOrg returns a service limited to the organization's members, see UserRepository.ForOrg
+func (s *UserService) ForOrg(orgID string) *UserService {
+	scoped := *s
+	scoped.users = s.users.ForOrg(orgID)
+	scoped.CRUDService = s.CRUDService.WithRepo(scoped.users)
+	return &scoped
 }
 
-func (s *UserService) GetAllUsers() ([]model.User, error) {
-	return s.repo.GetAll()
+// ExclusiveTo returns a service that refuses to change the users who also
+// belong to organizations other than orgID: their role and the trash are
+// global, the change would reach those organizations too
+func (s *UserService) ExclusiveTo(orgID string) *UserService {
+	guarded := *s.CRUDService
+	guarded.Hooks.Guard = func(ctx context.Context, user *model.User) error {
+		orgIDs, err := s.users.OrgIDs(ctx, user.Uid)
+		if err != nil {
+			return err
+		}
+		for _, other := range orgIDs {
+			if other != orgID {
+				return ErrSharedUser
+			}
+		}
+		return nil
+	}
+	exclusive := *s
+	exclusive.CRUDService = &guarded
+	return &exclusive
 }
 
-func (s *UserService) GetUserByID(id string) (*model.User, error) {
-	return s.repo.GetByID(id)
+// As returns a service whose changes are recorded in the audit log as made by actor
+func (s *UserService) As(actor Actor) *UserService {
+	acting := *s
+	acting.CRUDService = s.CRUDService.As(actor)
+	return &acting
 }
 
-func (s *UserService) UpdateUser(user *model.User) error {
-	if err := s.validate.Struct(user); err != nil {
-		return err
+// UpsertUser creates the user with the default role or keeps the username of an
+// existing one in sync. The role of existing users is never changed. When the
+// username is already taken by someone else the uid is used instead. Users in
+// the trash are left there and ErrUserDeleted is returned. The lookups and the
+// write are made in one transaction.
+func (s *UserService) UpsertUser(ctx context.Context, uid, username string) (*model.User, error) {
+	if uid == "" {
+		return nil, errors.New("uid is required")
+	}
+	if username == "" {
+		username = uid
 	}
-	return s.repo.Update(user)
-}
 
-func (s *UserService) DeleteUser(id string) error {
-	return s.repo.Delete(id)
-}
+	var user *model.User
+	err := s.WithinTx(ctx, func(ctx context.Context) error {
+		if other, err := s.users.GetByUsernameWithTrashed(ctx, username); err == nil && other.Uid != uid {
+			username = uid
+		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var err error
+		user, err = s.users.GetByIDWithTrashed(ctx, uid)
+		if err == nil && user.DeletedAt.Valid {
+			return ErrUserDeleted
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user = &model.User{Uid: uid, Username: username, Role: "user"}
+			return s.Create(ctx, user)
+		}
+		if err != nil {
+			return err
+		}
 
-// Additional method to match the handler
-func (s *UserService) GetAllUser() ([]model.User, error) {
-	return s.repo.GetAll()
+		if user.Username == username {
+			return nil
+		}
+		user.Username = username
+		return s.Update(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
 }
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"gotempl/model"
+	"gotempl/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUpsertUser(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserService(repository.NewMemoryUserRepository())
+
+	user, err := users.UpsertUser(ctx, "uid-1", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "user", user.Role)
+
+	// The role is kept and only the username follows the identity provider
+	user.Role = "admin"
+	assert.NoError(t, users.Update(ctx, user))
+	user, err = users.UpsertUser(ctx, "uid-1", "alice.smith")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice.smith", user.Username)
+	assert.Equal(t, "admin", user.Role)
+
+	// Taken usernames fall back to the uid
+	user, err = users.UpsertUser(ctx, "uid-2", "alice.smith")
+	assert.NoError(t, err)
+	assert.Equal(t, "uid-2", user.Username)
+
+	assert.NoError(t, users.Delete(ctx, "uid-2", 0, "admin-uid"))
+	_, err = users.UpsertUser(ctx, "uid-2", "bob")
+	assert.ErrorIs(t, err, ErrUserDeleted)
+
+	_, err = users.UpsertUser(ctx, "", "nobody")
+	assert.Error(t, err)
+
+	all, err := users.GetAll(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestEnsureMembership(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	orgs := NewOrganizationService(repository.NewMemoryOrganizationRepository(users))
+	assert.NoError(t, users.Create(ctx, &model.User{Uid: "uid-1", Username: "alice", Role: "user"}))
+
+	assert.NoError(t, orgs.EnsureMembership(ctx, "org_acme", "", "uid-1", "admin"))
+	org, err := orgs.GetOrganization(ctx, "org_acme")
+	assert.NoError(t, err)
+	assert.Equal(t, "org_acme", org.Name)
+
+	assert.NoError(t, orgs.EnsureMembership(ctx, "org_acme", "Acme", "uid-1", "member"))
+	assert.NoError(t, orgs.EnsureMembership(ctx, "org_acme", "", "uid-1", "member"))
+	org, err = orgs.GetOrganization(ctx, "org_acme")
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", org.Name)
+
+	members, err := orgs.GetMembers(ctx, "org_acme")
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "alice", members[0].Username)
+	}
+}
+
+func TestUsersForOrg(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository()
+	orgs := NewOrganizationService(repository.NewMemoryOrganizationRepository(repo))
+	users := NewUserService(repo)
+	assert.NoError(t, users.Create(ctx, &model.User{Uid: "a1", Username: "alice", Role: "user"}))
+	assert.NoError(t, users.Create(ctx, &model.User{Uid: "p1", Username: "pat", Role: "user"}))
+	assert.NoError(t, orgs.EnsureMembership(ctx, "org_a", "Acme", "a1", "org:admin"))
+
+	acme := users.ForOrg("org_a")
+	assert.NoError(t, acme.Create(ctx, &model.User{Uid: "a2", Username: "amy", Role: "user"}))
+	members, err := orgs.GetMembers(ctx, "org_a")
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	_, err = acme.Get(ctx, "p1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = users.ForOrg("").Get(ctx, "a1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	personal, err := users.ForOrg("").GetAll(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, personal, 1) {
+		assert.Equal(t, "p1", personal[0].Uid)
+	}
+}
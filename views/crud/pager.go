@@ -0,0 +1,18 @@
+package crud
+
+// Pagination describes where a listed page sits in the whole list
+type Pagination struct {
+	Shown int
+	Total int64
+	// FirstURL is set on pages after the first one
+	FirstURL string
+	// NextURL is set when more rows follow
+	NextURL string
+}
+
+// Search is the full-text search shown on a list page
+type Search struct {
+	Query string
+	// Snippets are the HTML excerpts of the results, by ID
+	Snippets map[uint64]string
+}
@@ -0,0 +1,93 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gotempl/model"
+	"gotempl/views/layout"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Cell is a column of the editable admin tables
+type Cell struct {
+	// JSON is the name of the field in the API, empty when it isn't exposed
+	JSON string
+	// Type tells the page how to send the value back: string, number, bool, time
+	// or list (comma separated)
+	Type     string
+	Value    string
+	ReadOnly bool
+}
+
+// cells lists the visible fields of a record, readOnly are the JSON names of
+// the fields the API ignores or sets itself. Times are in the viewer's time zone.
+func cells(ctx context.Context, record any, readOnly ...string) []Cell {
+	value := reflect.ValueOf(record)
+	var row []Cell
+	for _, field := range reflect.VisibleFields(value.Type()) {
+		cell := Cell{Type: "string"}
+		cell.JSON, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		if cell.JSON == "-" {
+			cell.JSON = ""
+		}
+		cell.ReadOnly = cell.JSON == "" || slices.Contains(readOnly, cell.JSON)
+
+		switch v := value.FieldByIndex(field.Index).Interface().(type) {
+		case time.Time:
+			cell.Type = "time"
+			if !v.IsZero() {
+				cell.Value = layout.FormatTime(ctx, v, time.RFC3339)
+			}
+		case *time.Time:
+			cell.Type = "time"
+			if v != nil {
+				cell.Value = layout.FormatTime(ctx, *v, time.RFC3339)
+			}
+		case gorm.DeletedAt:
+			cell.Type = "time"
+			if v.Valid {
+				cell.Value = layout.FormatTime(ctx, v.Time, time.RFC3339)
+			}
+		case model.StringList:
+			cell.Type = "list"
+			cell.Value = strings.Join(v, ", ")
+		case model.TimeList:
+			cell.Type = "list"
+			times := make([]string, len(v))
+			for i, t := range v {
+				times[i] = layout.FormatTime(ctx, t, time.RFC3339)
+			}
+			cell.Value = strings.Join(times, ", ")
+		case *uint64:
+			cell.Type = "number"
+			if v != nil {
+				cell.Value = fmt.Sprint(*v)
+			}
+		case bool:
+			cell.Type = "bool"
+			cell.Value = fmt.Sprint(v)
+		default:
+			switch field.Type.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64:
+				cell.Type = "number"
+			}
+			cell.Value = fmt.Sprintf("%v", v)
+		}
+		row = append(row, cell)
+	}
+	return row
+}
+
+// ifMatchHeaders is the hx-headers of the buttons changing a record
+func ifMatchHeaders(version uint64) string {
+	raw, _ := json.Marshal(map[string]string{"If-Match": model.ETag(version)})
+	return string(raw)
+}
@@ -0,0 +1,25 @@
+package layout
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type csrfTokenKey struct{}
+
+// WithCSRFToken stores the CSRF token for the templates rendered with ctx
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfTokenKey{}, token)
+}
+
+// CSRFToken returns the token set by the CSRF middleware, if any
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// htmxCSRFHeaders is the hx-headers value sending the token with every htmx request
+func htmxCSRFHeaders(ctx context.Context) string {
+	headers, _ := json.Marshal(map[string]string{"X-CSRF-Token": CSRFToken(ctx)})
+	return string(headers)
+}
@@ -0,0 +1,26 @@
+package layout
+
+import (
+	"context"
+	"time"
+)
+
+type locationKey struct{}
+
+// WithLocation stores the viewer's time zone for the templates rendered with ctx
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// Location returns the viewer's time zone, UTC when it isn't known
+func Location(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(locationKey{}).(*time.Location); ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// FormatTime formats t in the viewer's time zone
+func FormatTime(ctx context.Context, t time.Time, layout string) string {
+	return t.In(Location(ctx)).Format(layout)
+}
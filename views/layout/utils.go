@@ -5,6 +5,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// AuthProvider selects the sign-in widgets rendered by the top bar
+var AuthProvider = "clerk"
+
 type PageData struct {
 	Title   string
 	Content templ.Component